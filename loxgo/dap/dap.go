@@ -0,0 +1,507 @@
+// Package dap is a Debug Adapter Protocol server for Lox. It runs a script
+// on the tree-walking interpreter under a lox.Debugger, so that editors can
+// set breakpoints, step through the script and inspect its variables.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+)
+
+// threadID is the ID of the only thread, which runs the script.
+const threadID = 1
+
+// Server debugs one script for one client over a pair of streams, such as
+// stdin and stdout.
+type Server struct {
+	in *bufio.Reader
+	// writing guards out and seq, as the script's goroutine sends events.
+	writing sync.Mutex
+	out     io.Writer
+	seq     int
+
+	debugger *lox.Debugger
+	// launch is set by the launch request. The script starts once it is
+	// set and the client has sent its configuration, and done is closed
+	// when it ends.
+	launch     *LaunchArguments
+	configured bool
+	done       chan struct{}
+	// commands carries work to the script's goroutine while it is stopped:
+	// requests that inspect it, and finally how to resume it.
+	commands chan command
+
+	// mu guards the state shared with the script's goroutine.
+	mu          sync.Mutex
+	stopped     bool
+	entry       bool
+	terminating bool
+
+	// resuming is how to resume the script once the response to the
+	// request resuming it has been sent, so that it comes before the
+	// script's next events.
+	resuming lox.Resume
+
+	// refs are what the variable references of the current stop refer to.
+	// They belong to the script's goroutine.
+	refs []ref
+}
+
+type command struct {
+	inspect func(stop *lox.Stop)
+	resume  lox.Resume
+	done    chan struct{}
+}
+
+// ref is a variable reference: either a scope of a frame, or a value with
+// members.
+type ref struct {
+	frame int
+	scope int
+	value any
+	// isScope is set for references to a scope.
+	isScope bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{
+		in:       bufio.NewReader(in),
+		out:      out,
+		commands: make(chan command),
+	}
+	s.debugger = lox.NewDebugger(s.stop)
+	return s
+}
+
+// Run serves requests until the client disconnects or closes the input. The
+// script is stopped if it is still running.
+func (s *Server) Run() error {
+	defer s.terminate()
+
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if disconnect, err := s.handle(req); disconnect || err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) read() (*request, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("dap: bad Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	req := &request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("dap: bad message: %w", err)
+	}
+	return req, nil
+}
+
+// write sends a response or event, setting its sequence number.
+func (s *Server) write(msg any) error {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq, msg.Type = s.seq, "response"
+	case *event:
+		msg.Seq, msg.Type = s.seq, "event"
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) event(name string, body any) error {
+	return s.write(&event{Event: name, Body: body})
+}
+
+// handle answers a request. It reports whether the client disconnected.
+func (s *Server) handle(req *request) (bool, error) {
+	body, err := s.dispatch(req)
+
+	resp := &response{RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	if err := s.write(resp); err != nil {
+		return false, err
+	}
+	if s.resuming != "" {
+		s.commands <- command{resume: s.resuming}
+		s.resuming = ""
+	}
+
+	switch req.Command {
+	case "initialize":
+		return false, s.event("initialized", nil)
+	case "launch", "configurationDone":
+		s.start()
+	case "disconnect":
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *Server) dispatch(req *request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsSetVariable:              true,
+			SupportsTerminateRequest:         true,
+		}, nil
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		if args.Program == "" {
+			return nil, errors.New("launch needs a program")
+		}
+		s.launch = &args
+		return nil, nil
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		lines := []int{}
+		body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+			body.Breakpoints = append(body.Breakpoints, Breakpoint{Verified: true, Line: bp.Line})
+		}
+		s.debugger.SetBreakpoints(args.Source.Path, lines)
+		return body, nil
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.inspect(func(stop *lox.Stop) (any, error) {
+			return s.stackTrace(stop), nil
+		})
+	case "scopes":
+		var args ScopesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.inspect(func(stop *lox.Stop) (any, error) {
+			return s.scopes(stop, args.FrameID)
+		})
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.inspect(func(stop *lox.Stop) (any, error) {
+			return s.variables(stop, args.VariablesReference)
+		})
+	case "setVariable":
+		var args SetVariableArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.inspect(func(stop *lox.Stop) (any, error) {
+			return s.setVariable(stop, args)
+		})
+	case "evaluate":
+		var args EvaluateArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.inspect(func(stop *lox.Stop) (any, error) {
+			return s.evaluate(stop, args)
+		})
+	case "continue":
+		return ContinueResponseBody{AllThreadsContinued: true}, s.resume(lox.Resume_CONTINUE)
+	case "next":
+		return nil, s.resume(lox.Resume_STEP_OVER)
+	case "stepIn":
+		return nil, s.resume(lox.Resume_STEP_IN)
+	case "stepOut":
+		return nil, s.resume(lox.Resume_STEP_OUT)
+	case "pause":
+		s.debugger.Pause()
+		return nil, nil
+	case "terminate", "disconnect":
+		s.terminate()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request '%s'", req.Command)
+}
+
+// start runs the script once it has been launched and configured.
+func (s *Server) start() {
+	if s.launch == nil || !s.configured || s.done != nil {
+		return
+	}
+
+	opts := []lox.Option{
+		lox.WithStdout(&output{s, "stdout"}),
+		lox.WithStderr(&output{s, "stderr"}),
+	}
+	if !s.launch.NoDebug {
+		opts = append(opts, lox.WithDebugger(s.debugger))
+	}
+	if s.launch.StopOnEntry {
+		s.entry = true
+		s.debugger.Pause()
+	}
+	l := lox.New(opts...)
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		err := l.RunFile(s.launch.Program)
+		code := 0
+		var compileErr *lox.CompileError
+		var runtimeErr *lox.RuntimeError
+		switch {
+		case err == nil:
+		case errors.As(err, &compileErr):
+			code = 65
+		case errors.As(err, &runtimeErr):
+			code = 70
+		default:
+			// Errors outside the script, such as a missing file, aren't
+			// reported by Lox.
+			code = 1
+			s.event("output", OutputEventBody{Category: "stderr", Output: err.Error() + "\n"})
+		}
+		s.event("exited", ExitedEventBody{ExitCode: code})
+		s.event("terminated", nil)
+	}()
+}
+
+// terminate stops the script, if it is running, and waits for it to end.
+func (s *Server) terminate() {
+	if s.done == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.terminating = true
+	stopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+
+	if stopped {
+		s.commands <- command{resume: lox.Resume_STOP}
+	} else {
+		s.debugger.Pause()
+	}
+	<-s.done
+}
+
+// stop is called on the script's goroutine when it stops, and serves
+// commands until one resumes it.
+func (s *Server) stop(stop *lox.Stop) lox.Resume {
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return lox.Resume_STOP
+	}
+	reason := string(stop.Reason)
+	if s.entry {
+		reason, s.entry = "entry", false
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.refs = nil
+	s.event("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+
+	for {
+		cmd := <-s.commands
+		if cmd.inspect == nil {
+			return cmd.resume
+		}
+		cmd.inspect(stop)
+		close(cmd.done)
+	}
+}
+
+// inspect calls fn on the script's goroutine while it is stopped, and
+// returns what fn returns.
+func (s *Server) inspect(fn func(stop *lox.Stop) (any, error)) (result any, err error) {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+	if !stopped {
+		return nil, errors.New("the program is running")
+	}
+
+	done := make(chan struct{})
+	s.commands <- command{
+		inspect: func(stop *lox.Stop) {
+			result, err = fn(stop)
+		},
+		done: done,
+	}
+	<-done
+	return result, err
+}
+
+// resume resumes the stopped script, once the response has been sent.
+func (s *Server) resume(resume lox.Resume) error {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+	if !stopped {
+		return errors.New("the program is running")
+	}
+
+	s.resuming = resume
+	return nil
+}
+
+func (s *Server) stackTrace(stop *lox.Stop) StackTraceResponseBody {
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}}
+	for i, frame := range stop.Frames() {
+		sf := StackFrame{ID: i, Name: frame.String(), Line: frame.Line, Column: 1}
+		if frame.File != "" {
+			sf.Source = &Source{Name: filepath.Base(frame.File), Path: frame.File}
+		}
+		body.StackFrames = append(body.StackFrames, sf)
+	}
+	body.TotalFrames = len(body.StackFrames)
+	return body
+}
+
+func (s *Server) scopes(stop *lox.Stop, frame int) (ScopesResponseBody, error) {
+	scopes, err := stop.Scopes(frame)
+	if err != nil {
+		return ScopesResponseBody{}, err
+	}
+
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	for i, scope := range scopes {
+		body.Scopes = append(body.Scopes, Scope{
+			Name:               scope.Name,
+			VariablesReference: s.reference(ref{frame: frame, scope: i, isScope: true}),
+			Expensive:          scope.Name == "Globals",
+		})
+	}
+	return body, nil
+}
+
+func (s *Server) variables(stop *lox.Stop, reference int) (VariablesResponseBody, error) {
+	if reference < 1 || reference > len(s.refs) {
+		return VariablesResponseBody{}, fmt.Errorf("no variables with reference %d", reference)
+	}
+	r := s.refs[reference-1]
+
+	bindings := lox.Members(r.value)
+	if r.isScope {
+		scopes, err := stop.Scopes(r.frame)
+		if err != nil {
+			return VariablesResponseBody{}, err
+		}
+		bindings = scopes[r.scope].Variables
+	}
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+	for _, binding := range bindings {
+		body.Variables = append(body.Variables, Variable{
+			Name:               binding.Name,
+			Value:              lox.Inspect(binding.Value),
+			VariablesReference: s.valueReference(binding.Value),
+		})
+	}
+	return body, nil
+}
+
+func (s *Server) setVariable(stop *lox.Stop, args SetVariableArguments) (SetVariableResponseBody, error) {
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.refs) {
+		return SetVariableResponseBody{}, fmt.Errorf("no variables with reference %d", args.VariablesReference)
+	}
+	r := s.refs[args.VariablesReference-1]
+	if !r.isScope {
+		return SetVariableResponseBody{}, errors.New("only variables in scopes can be set")
+	}
+
+	value, err := stop.SetVariable(r.frame, r.scope, args.Name, args.Value)
+	if err != nil {
+		return SetVariableResponseBody{}, err
+	}
+	return SetVariableResponseBody{Value: lox.Inspect(value), VariablesReference: s.valueReference(value)}, nil
+}
+
+func (s *Server) evaluate(stop *lox.Stop, args EvaluateArguments) (EvaluateResponseBody, error) {
+	frame := 0
+	if args.FrameID != nil {
+		frame = *args.FrameID
+	}
+
+	value, err := stop.Evaluate(frame, args.Expression)
+	if err != nil {
+		return EvaluateResponseBody{}, err
+	}
+	return EvaluateResponseBody{Result: lox.Inspect(value), VariablesReference: s.valueReference(value)}, nil
+}
+
+// reference returns a new variable reference to r.
+func (s *Server) reference(r ref) int {
+	s.refs = append(s.refs, r)
+	return len(s.refs)
+}
+
+// valueReference returns a new variable reference to value if it has
+// members, or else 0.
+func (s *Server) valueReference(value any) int {
+	if len(lox.Members(value)) == 0 {
+		return 0
+	}
+	return s.reference(ref{value: value})
+}
+
+// output sends what the script writes to the client as output events.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.s.event("output", OutputEventBody{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,257 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// message is a response or event as the client reads it.
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client talks to a Server over pipes, as an editor would over stdio.
+type client struct {
+	t        *testing.T
+	in       io.WriteCloser
+	messages chan *message
+	seq      int
+	// events holds the events read while waiting for something else.
+	events []*message
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, in: clientOut, messages: make(chan *message, 100), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+	go func() {
+		defer close(c.messages)
+		r := bufio.NewReader(clientIn)
+		for {
+			header, err := textproto.NewReader(r).ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			body := make([]byte, length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			msg := &message{}
+			if err := json.Unmarshal(body, msg); err != nil {
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+	return c
+}
+
+// request sends a request and returns its response, decoding the body into
+// body.
+func (c *client) request(command string, arguments any, body any) *message {
+	c.seq++
+	b, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	require.Nil(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	require.Nil(c.t, err)
+
+	for msg := range c.messages {
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		require.Equal(c.t, c.seq, msg.RequestSeq)
+		if body != nil {
+			require.True(c.t, msg.Success, msg.Message)
+			require.Nil(c.t, json.Unmarshal(msg.Body, body))
+		}
+		return msg
+	}
+	c.t.Fatal("no response to " + command)
+	return nil
+}
+
+// event waits for the event called name, and returns its body.
+func (c *client) event(name string) json.RawMessage {
+	for i, msg := range c.events {
+		if msg.Event == name {
+			c.events = append(c.events[:i], c.events[i+1:]...)
+			return msg.Body
+		}
+	}
+	for msg := range c.messages {
+		if msg.Event == name {
+			return msg.Body
+		}
+		c.events = append(c.events, msg)
+	}
+	c.t.Fatal("no event " + name)
+	return nil
+}
+
+func (c *client) stopped() StoppedEventBody {
+	var body StoppedEventBody
+	require.Nil(c.t, json.Unmarshal(c.event("stopped"), &body))
+	return body
+}
+
+// output returns the output the script has printed.
+func (c *client) output() string {
+	var sb strings.Builder
+	for _, msg := range c.events {
+		var body OutputEventBody
+		if msg.Event == "output" && json.Unmarshal(msg.Body, &body) == nil && body.Category == "stdout" {
+			sb.WriteString(body.Output)
+		}
+	}
+	return sb.String()
+}
+
+func (c *client) frames() []StackFrame {
+	var trace StackTraceResponseBody
+	c.request("stackTrace", map[string]any{"threadId": threadID}, &trace)
+	return trace.StackFrames
+}
+
+func (c *client) variables(reference int) map[string]Variable {
+	var body VariablesResponseBody
+	c.request("variables", map[string]any{"variablesReference": reference}, &body)
+	vars := map[string]Variable{}
+	for _, v := range body.Variables {
+		vars[v.Name] = v
+	}
+	return vars
+}
+
+func TestServer(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "points.lox")
+	require.Nil(t, os.WriteFile(program, []byte(`class Point {
+  init(x, y) {
+    this.x = x;
+    this.y = y;
+  }
+}
+fun norm(p) {
+  var sum = p.x * p.x + p.y * p.y;
+  return sum;
+}
+var p = Point(3, 4);
+print norm(p);
+print "done";
+`), 0o644))
+
+	c := newClient(t)
+
+	var capabilities Capabilities
+	c.request("initialize", map[string]any{"adapterID": "loxgo"}, &capabilities)
+	require.True(t, capabilities.SupportsSetVariable)
+	c.event("initialized")
+
+	c.request("launch", map[string]any{"program": program, "stopOnEntry": true}, nil)
+	var breakpoints SetBreakpointsResponseBody
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 9}},
+	}, &breakpoints)
+	require.Equal(t, []Breakpoint{{Verified: true, Line: 9}}, breakpoints.Breakpoints)
+	c.request("configurationDone", nil, nil)
+
+	require.Equal(t, StoppedEventBody{Reason: "entry", ThreadID: threadID, AllThreadsStopped: true}, c.stopped())
+	require.Equal(t, []StackFrame{{ID: 0, Name: "script", Source: &Source{Name: "points.lox", Path: program}, Line: 1, Column: 1}}, c.frames())
+
+	c.request("continue", map[string]any{"threadId": threadID}, nil)
+	require.Equal(t, "breakpoint", c.stopped().Reason)
+	frames := c.frames()
+	require.Len(t, frames, 2)
+	require.Equal(t, "norm()", frames[0].Name)
+	require.Equal(t, 9, frames[0].Line)
+	require.Equal(t, 12, frames[1].Line)
+
+	var scopes ScopesResponseBody
+	c.request("scopes", map[string]any{"frameId": 0}, &scopes)
+	require.Len(t, scopes.Scopes, 2)
+	require.Equal(t, "Locals", scopes.Scopes[0].Name)
+	require.Equal(t, "Globals", scopes.Scopes[1].Name)
+
+	// Instances can be expanded into their fields.
+	locals := c.variables(scopes.Scopes[0].VariablesReference)
+	require.Equal(t, "25", locals["sum"].Value)
+	require.Equal(t, "Point instance", locals["p"].Value)
+	fields := c.variables(locals["p"].VariablesReference)
+	require.Equal(t, "3", fields["x"].Value)
+	require.Equal(t, "4", fields["y"].Value)
+
+	var set SetVariableResponseBody
+	c.request("setVariable", map[string]any{"variablesReference": scopes.Scopes[0].VariablesReference, "name": "sum", "value": "sum * 2"}, &set)
+	require.Equal(t, "50", set.Value)
+
+	var evaluated EvaluateResponseBody
+	c.request("evaluate", map[string]any{"expression": "p.x + sum", "frameId": 0}, &evaluated)
+	require.Equal(t, "53", evaluated.Result)
+	c.request("evaluate", map[string]any{"expression": "p.y * 10", "frameId": 1}, &evaluated)
+	require.Equal(t, "40", evaluated.Result)
+	failed := c.request("evaluate", map[string]any{"expression": "nope"}, nil)
+	require.False(t, failed.Success)
+	require.Equal(t, "Undefined variable 'nope'.", failed.Message)
+
+	c.request("stepOut", map[string]any{"threadId": threadID}, nil)
+	require.Equal(t, "step", c.stopped().Reason)
+	frames = c.frames()
+	require.Len(t, frames, 1)
+	require.Equal(t, 13, frames[0].Line)
+	require.Equal(t, "50\n", c.output())
+
+	c.request("next", map[string]any{"threadId": threadID}, nil)
+	var exited ExitedEventBody
+	require.Nil(t, json.Unmarshal(c.event("exited"), &exited))
+	require.Equal(t, 0, exited.ExitCode)
+	c.event("terminated")
+	require.Equal(t, "50\ndone\n", c.output())
+
+	running := c.request("next", map[string]any{"threadId": threadID}, nil)
+	require.False(t, running.Success)
+
+	c.request("disconnect", nil, nil)
+	require.Nil(t, <-c.done)
+}
+
+func TestServerDisconnectWhileStopped(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "loop.lox")
+	require.Nil(t, os.WriteFile(program, []byte("while (true) {\n  print 1;\n}\n"), 0o644))
+
+	c := newClient(t)
+	c.request("initialize", map[string]any{}, nil)
+	c.request("configurationDone", nil, nil)
+	c.request("launch", map[string]any{"program": program}, nil)
+
+	c.request("pause", map[string]any{"threadId": threadID}, nil)
+	require.Equal(t, "pause", c.stopped().Reason)
+
+	c.request("disconnect", nil, nil)
+	var exited ExitedEventBody
+	require.Nil(t, json.Unmarshal(c.event("exited"), &exited))
+	require.Equal(t, 70, exited.ExitCode)
+	require.Nil(t, <-c.done)
+}
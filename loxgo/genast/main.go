@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
-	"io"
+	"go/format"
 	"os"
 	"strings"
 )
 
-type ExprType struct {
+// NodeType describes a node of the syntax tree. Fields are written as in a
+// struct, "Name Type". Unexported fields hold what the parser and later
+// passes record on the node; they are copied by Clone but otherwise left
+// out.
+type NodeType struct {
 	Name   string
 	Fields []string
+	// Pos is the expression, in terms of the node e, for the position where
+	// the node starts.
+	Pos string
 }
 
 func main() {
@@ -21,204 +29,418 @@ func main() {
 
 	// TODO(adam): fix bad dupe names .. Var / Exp
 
-	if err := genAST(outputDir, "Expr", []ExprType{
+	if err := genAST(outputDir, "Expr", []NodeType{
 		{"Binary", []string{
-			"Left *Expr",
+			"Left Expr",
 			"Operator *Token",
-			"Right *Expr",
-		}},
+			"Right Expr",
+		}, "e.Left.Pos()"},
 		{"Grouping", []string{
-			"Expression *Expr",
-		}},
+			"Paren *Token",
+			"Expression Expr",
+		}, "e.Paren.pos()"},
 		{"Call", []string{
-			"Callee *Expr ",
-			"Paren *Token ",
-			"Arguments []*Expr",
-		}},
+			"Callee Expr",
+			"Paren *Token",
+			"Arguments []Expr",
+		}, "e.Callee.Pos()"},
 		{"Get", []string{
-			"Object *Expr",
+			"Object Expr",
 			"Name *Token",
-		}},
+		}, "e.Object.Pos()"},
 		{"Set", []string{
-			"Object *Expr",
+			"Object Expr",
 			"Name *Token",
-			"Value *Expr",
-		}},
+			"Value Expr",
+		}, "e.Object.Pos()"},
 		{"Literal", []string{
 			"Value any",
-		}},
+			"Token *Token",
+		}, "e.Token.pos()"},
 		{"Unary", []string{
 			"Operator *Token",
-			"Right *Expr",
-		}},
+			"Right Expr",
+		}, "e.Operator.pos()"},
 		{"This", []string{
 			"Keyword *Token",
-		}},
+			"ref slotRef",
+		}, "e.Keyword.pos()"},
 		{"Super", []string{
 			"Keyword *Token",
 			"Method *Token",
-		}},
+			"ref slotRef",
+		}, "e.Keyword.pos()"},
 		{"Logical", []string{
-			"Left *Expr",
+			"Left Expr",
 			"Operator *Token",
-			"Right *Expr",
-		}},
+			"Right Expr",
+		}, "e.Left.Pos()"},
 		{"Variable", []string{
 			"Name *Token",
-		}},
+			"ref slotRef",
+		}, "e.Name.pos()"},
 		{"Assign", []string{
 			"Name *Token",
-			"Value *Expr",
-		}},
+			"Value Expr",
+			"ref slotRef",
+		}, "e.Name.pos()"},
+		{"Interpolation", []string{
+			"Start *Token",
+			"Parts []Expr",
+		}, "e.Start.pos()"},
+		{"Lambda", []string{
+			"Function *Function",
+		}, "e.Function.Pos()"},
+		{"List", []string{
+			"Bracket *Token",
+			"Elements []Expr",
+		}, "e.Bracket.pos()"},
+		{"Map", []string{
+			"Brace *Token",
+			"Keys []Expr",
+			"Values []Expr",
+		}, "e.Brace.pos()"},
+		{"Index", []string{
+			"Object Expr",
+			"Bracket *Token",
+			"Index Expr",
+		}, "e.Object.Pos()"},
+		{"SetIndex", []string{
+			"Object Expr",
+			"Bracket *Token",
+			"Index Expr",
+			"Value Expr",
+		}, "e.Object.Pos()"},
 	}); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(64)
 	}
 
-	if err := genAST(outputDir, "Stmt", []ExprType{
+	if err := genAST(outputDir, "Stmt", []NodeType{
 		{"Expression", []string{
-			"Expression *Expr",
-		}},
+			"Expression Expr",
+		}, "e.Expression.Pos()"},
 		{"If", []string{
-			"Condition *Expr",
-			"Then *Stmt",
-			"Else *Stmt",
-		}},
+			"Keyword *Token",
+			"Condition Expr",
+			"Then Stmt",
+			"Else Stmt",
+		}, "e.Keyword.pos()"},
 		{"Function", []string{
 			"Name *Token",
 			"Params []*Token",
-			"Body []*Stmt",
-		}},
+			"Body []Stmt",
+			"end *Token",
+			"locals []string",
+		}, "e.Name.pos()"},
 		{"Return", []string{
 			"Keyword *Token",
-			"Value *Expr",
-		}},
+			"Value Expr",
+		}, "e.Keyword.pos()"},
 		{"Print", []string{
-			"Expression *Expr",
-		}},
+			"Keyword *Token",
+			"Expression Expr",
+		}, "e.Keyword.pos()"},
 		{"Var", []string{
 			"Name *Token",
-			"Initializer *Expr",
-		}},
+			"Initializer Expr",
+		}, "e.Name.pos()"},
 		{"While", []string{
-			"Condition *Expr",
-			"Body *Stmt",
-		}},
+			"Keyword *Token",
+			"Condition Expr",
+			"Body Stmt",
+			"Increment Expr",
+		}, "e.Keyword.pos()"},
+		{"Break", []string{
+			"Keyword *Token",
+		}, "e.Keyword.pos()"},
+		{"Continue", []string{
+			"Keyword *Token",
+		}, "e.Keyword.pos()"},
+		{"Import", []string{
+			"Keyword *Token",
+			"Path *Token",
+			"Alias *Token",
+			"Names []*Token",
+		}, "e.Keyword.pos()"},
+		{"Export", []string{
+			"Keyword *Token",
+			"Declaration Stmt",
+		}, "e.Keyword.pos()"},
+		{"Throw", []string{
+			"Keyword *Token",
+			"Value Expr",
+		}, "e.Keyword.pos()"},
+		{"Try", []string{
+			"Keyword *Token",
+			"Body []Stmt",
+			"CatchName *Token",
+			"Catch []Stmt",
+			"Finally []Stmt",
+			"bodyEnd *Token",
+			"catchEnd *Token",
+			"finallyEnd *Token",
+			"bodyLocals []string",
+			"catchLocals []string",
+			"finallyLocals []string",
+		}, "e.Keyword.pos()"},
 		{"Block", []string{
-			"Statements []*Stmt",
-		}},
+			"Brace *Token",
+			"Statements []Stmt",
+			"end *Token",
+			"locals []string",
+		}, "e.Brace.pos()"},
 		{"Class", []string{
 			"Name *Token",
 			"SuperClass *Variable",
-			"Methods []*Stmt",
-		}},
+			"Methods []*Function",
+		}, "e.Name.pos()"},
 	}); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(64)
 	}
 }
 
-func writeln(w io.Writer, s string) error {
-	_, err := w.Write([]byte(s + "\n"))
-	return err
+// field is a field of a node, split into its name and type.
+type field struct {
+	name string
+	typ  string
 }
 
-func genAST(outputDir string, baseName string, types []ExprType) error {
-	path := outputDir + "/" + strings.ToLower(baseName) + ".gen.go"
+func (f field) exported() bool {
+	return strings.ToUpper(f.name[:1]) == f.name[:1]
+}
 
-	_ = os.RemoveAll(path)
+// jsonName is the key of the field in the node's JSON object.
+func (f field) jsonName() string {
+	return strings.ToLower(f.name[:1]) + f.name[1:]
+}
 
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
-	if err != nil {
-		return err
+func fields(t NodeType) []field {
+	fs := []field{}
+	for _, f := range t.Fields {
+		name, typ, _ := strings.Cut(strings.TrimSpace(f), " ")
+		fs = append(fs, field{name: name, typ: strings.TrimSpace(typ)})
 	}
-	defer f.Close()
+	return fs
+}
 
-	if err = writeln(f, "// DO NOT EDIT - generated code!"); err != nil {
-		return err
-	}
-	if err = writeln(f, "package main"); err != nil {
-		return err
+// isNode reports whether typ is a node or a pointer to one.
+func isNode(typ string) bool {
+	switch typ {
+	case "Expr", "Stmt", "*Function", "*Variable":
+		return true
 	}
+	return false
+}
 
-	if err = writeln(f, "type "+baseName+" struct {"); err != nil {
-		return err
+// kinds names the kind of node each base type is.
+var kinds = map[string]string{"Expr": "expression", "Stmt": "statement"}
+
+func genAST(outputDir string, baseName string, types []NodeType) error {
+	var b bytes.Buffer
+	w := func(format string, args ...any) {
+		fmt.Fprintf(&b, format+"\n", args...)
 	}
+	marker := strings.ToLower(baseName) + "Node"
+
+	w("// DO NOT EDIT - generated code!")
+	w("package lox")
+	w("")
+	w("import \"encoding/json\"")
+	w("")
+	w("// %s is implemented by every %s node of the syntax tree.", baseName, kinds[baseName])
+	w("type %s interface {", baseName)
+	w("	Node")
+	w("	%s()", marker)
+	w("}")
+	w("")
 
 	for _, t := range types {
-		if err = writeln(f, fmt.Sprintf("	%s *%s", t.Name, t.Name)); err != nil {
-			return err
+		w("type %s struct {", t.Name)
+		for _, f := range t.Fields {
+			w("	%s", f)
 		}
+		w("}")
+		w("")
+		w("func (e *%s) Pos() Position { return %s }", t.Name, t.Pos)
+		w("func (*%s) %s() {}", t.Name, marker)
+		w("")
 	}
-	if err = writeln(f, "}"); err != nil {
+
+	genVisitor(w, baseName, types)
+	genClone(w, baseName, types)
+	genEqual(w, baseName, types)
+	genWalk(w, baseName, types)
+	genJSON(w, types)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
 		return err
 	}
+	return os.WriteFile(outputDir+"/"+strings.ToLower(baseName)+".gen.go", src, 0644)
+}
+
+func genVisitor(w func(string, ...any), baseName string, types []NodeType) {
+	name := strings.ToLower(baseName)
 
+	w("// Visitor%s has a method for each type of %s, returning an R.", baseName, kinds[baseName])
+	w("type Visitor%s[R any] interface {", baseName)
 	for _, t := range types {
-		if err = writeln(f, "type "+t.Name+" struct {"); err != nil {
-			return err
-		}
-		for _, field := range t.Fields {
-			if err = writeln(f, field); err != nil {
-				return err
-			}
-		}
-		if err = writeln(f, "}"); err != nil {
-			return err
-		}
+		w("	Visit%s(%s *%s) R", t.Name, name, t.Name)
 	}
+	w("}")
+	w("")
 
-	return genVisitor(f, baseName, types)
+	w("// accept%s calls the method of v for the type of %s.", baseName, name)
+	w("func accept%s[R any](%s %s, v Visitor%s[R]) R {", baseName, name, baseName, baseName)
+	w("	switch e := %s.(type) {", name)
+	for _, t := range types {
+		w("	case *%s:", t.Name)
+		w("		return v.Visit%s(e)", t.Name)
+	}
+	w("	}")
+	w("	panic(\"lox: unknown %s\")", name)
+	w("}")
+	w("")
 }
 
-func genVisitor(w io.Writer, baseName string, types []ExprType) error {
-	iname := "Visitor" + baseName
-
-	if err := writeln(w, `type `+iname+` interface {`); err != nil {
-		return err
+func genClone(w func(string, ...any), baseName string, types []NodeType) {
+	w("// Clone%s returns a deep copy of %s, as the Clone method of its type does.", baseName, strings.ToLower(baseName))
+	w("func Clone%s(%s %s) %s {", baseName, strings.ToLower(baseName), baseName, baseName)
+	w("	switch e := %s.(type) {", strings.ToLower(baseName))
+	for _, t := range types {
+		w("	case *%s:", t.Name)
+		w("		return e.Clone()")
 	}
+	w("	}")
+	w("	return nil")
+	w("}")
+	w("")
+
 	for _, t := range types {
-		if err := writeln(w, "	Visit"+t.Name+"(expr *"+t.Name+") any"); err != nil {
-			return err
+		w("// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.")
+		w("func (e *%s) Clone() *%s {", t.Name, t.Name)
+		w("	if e == nil {")
+		w("		return nil")
+		w("	}")
+		w("	c := *e")
+		for _, f := range fields(t) {
+			if !f.exported() {
+				continue
+			}
+			switch f.typ {
+			case "Expr", "Stmt":
+				w("	c.%s = Clone%s(e.%s)", f.name, f.typ, f.name)
+			case "*Function", "*Variable":
+				w("	c.%s = e.%s.Clone()", f.name, f.name)
+			case "[]Expr", "[]Stmt":
+				w("	c.%s = cloneList(e.%s, Clone%s)", f.name, f.name, f.typ[2:])
+			case "[]*Function":
+				w("	c.%s = cloneList(e.%s, (*Function).Clone)", f.name, f.name)
+			case "[]*Token":
+				w("	c.%s = cloneList(e.%s, sameToken)", f.name, f.name)
+			}
 		}
+		w("	return &c")
+		w("}")
+		w("")
 	}
-	if err := writeln(w, `}`); err != nil {
-		return err
-	}
+}
 
-	if err := writeln(w, "func (e *"+baseName+") accept(v "+iname+") any {"); err != nil {
-		return err
+func genEqual(w func(string, ...any), baseName string, types []NodeType) {
+	name := strings.ToLower(baseName)
+
+	w("func equal%s(%s %s, other Node) bool {", baseName, name, baseName)
+	w("	switch e := %s.(type) {", name)
+	for _, t := range types {
+		w("	case *%s:", t.Name)
+		w("		o, ok := other.(*%s)", t.Name)
+		w("		return ok && e.equal(o)")
 	}
+	w("	}")
+	w("	return false")
+	w("}")
+	w("")
+
 	for _, t := range types {
-		if err := writeln(w, "if e."+t.Name+" != nil {"); err != nil {
-			return err
-		}
-		if err := writeln(w, "	return e."+t.Name+".accept(v)"); err != nil {
-			return err
-		}
-		if err := writeln(w, "}"); err != nil {
-			return err
+		w("func (e *%s) equal(o *%s) bool {", t.Name, t.Name)
+		w("	if e == nil || o == nil {")
+		w("		return e == o")
+		w("	}")
+		conds := []string{}
+		for _, f := range fields(t) {
+			if !f.exported() {
+				continue
+			}
+			switch {
+			case isNode(f.typ):
+				conds = append(conds, fmt.Sprintf("Equal(e.%s, o.%s)", f.name, f.name))
+			case f.typ == "*Token":
+				conds = append(conds, fmt.Sprintf("equalToken(e.%s, o.%s)", f.name, f.name))
+			case f.typ == "[]*Token":
+				conds = append(conds, fmt.Sprintf("equalList(e.%s, o.%s, equalToken)", f.name, f.name))
+			case strings.HasPrefix(f.typ, "[]"):
+				conds = append(conds, fmt.Sprintf("equalList(e.%s, o.%s, equalNode[%s])", f.name, f.name, f.typ[2:]))
+			default:
+				conds = append(conds, fmt.Sprintf("e.%s == o.%s", f.name, f.name))
+			}
 		}
+		w("	return %s", strings.Join(conds, " &&\n\t\t"))
+		w("}")
+		w("")
 	}
-	if err := writeln(w, "	return nil"); err != nil {
-		return err
-	}
-	if err := writeln(w, "}"); err != nil {
-		return err
-	}
+}
 
+func genWalk(w func(string, ...any), baseName string, types []NodeType) {
+	name := strings.ToLower(baseName)
+
+	w("// walk%s walks the children of %s, in the order of their fields.", baseName, name)
+	w("func walk%s(v Visitor, %s %s) {", baseName, name, baseName)
+	w("	switch e := %s.(type) {", name)
 	for _, t := range types {
-		if err := writeln(w, "func (e *"+t.Name+") accept(visitor "+iname+") any {"); err != nil {
-			return err
+		walks := []string{}
+		for _, f := range fields(t) {
+			switch {
+			case !f.exported():
+			case isNode(f.typ):
+				walks = append(walks, fmt.Sprintf("if e.%s != nil {\nWalk(v, e.%s)\n}", f.name, f.name))
+			case strings.HasPrefix(f.typ, "[]") && f.typ != "[]*Token":
+				walks = append(walks, fmt.Sprintf("walkList(v, e.%s)", f.name))
+			}
 		}
-
-		if err := writeln(w, "	return visitor.Visit"+t.Name+"(e)"); err != nil {
-			return err
+		if len(walks) == 0 {
+			continue
+		}
+		w("	case *%s:", t.Name)
+		for _, walk := range walks {
+			w("		%s", walk)
 		}
+	}
+	w("	}")
+	w("}")
+	w("")
+}
 
-		if err := writeln(w, "}"); err != nil {
-			return err
+func genJSON(w func(string, ...any), types []NodeType) {
+	for _, t := range types {
+		w("func (e *%s) MarshalJSON() ([]byte, error) {", t.Name)
+		w("	return json.Marshal(nodeJSON(e, \"%s\", map[string]any{", t.Name)
+		for _, f := range fields(t) {
+			if !f.exported() {
+				continue
+			}
+			switch f.typ {
+			case "*Token":
+				w("		\"%s\": tokenJSON(e.%s),", f.jsonName(), f.name)
+			case "[]*Token":
+				w("		\"%s\": tokensJSON(e.%s),", f.jsonName(), f.name)
+			default:
+				w("		\"%s\": e.%s,", f.jsonName(), f.name)
+			}
 		}
+		w("	}))")
+		w("}")
+		w("")
 	}
-	return nil
 }
@@ -0,0 +1,265 @@
+package lox
+
+import (
+	"sort"
+	"strings"
+)
+
+// Analysis is what Analyze finds in a Lox source file without running it.
+// It backs editor tooling such as the loxgo language server.
+type Analysis struct {
+	// Diagnostics are the errors found by the scanner, parser and resolver.
+	Diagnostics []Diagnostic
+	// Symbols are the top-level declarations, with the methods of each class
+	// as its children.
+	Symbols []*Symbol
+	// Declarations are all the declared names, including locals and
+	// parameters, in source order.
+	Declarations []*Symbol
+	// References are the uses of variables, in the order the resolver found
+	// them.
+	References []Reference
+}
+
+// Span locates a token in the source. Line and Column count from 1, Column
+// in runes; Offset and Length are in bytes.
+type Span struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+// Contains reports whether offset is within the span or just after it, where
+// an editor's cursor sits after typing a name.
+func (s Span) Contains(offset int) bool {
+	return s.Offset <= offset && offset <= s.Offset+s.Length
+}
+
+type Diagnostic struct {
+	Span    Span
+	Message string
+}
+
+type SymbolKind string
+
+const (
+	SymbolKind_CLASS     SymbolKind = "class"
+	SymbolKind_METHOD    SymbolKind = "method"
+	SymbolKind_FUNCTION  SymbolKind = "function"
+	SymbolKind_VARIABLE  SymbolKind = "variable"
+	SymbolKind_PARAMETER SymbolKind = "parameter"
+	SymbolKind_KEYWORD   SymbolKind = "keyword"
+)
+
+// Symbol is a declared name.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Detail is the declaration as it would be written, such as
+	// "fun add(a, b)" or "class Square < Shape".
+	Detail string
+	// Span is where the name is declared. It is zero for the globals that
+	// every script starts with, and for keywords.
+	Span     Span
+	Children []*Symbol
+	// scope is the source a local can be named in, from its declaration to
+	// the end of its block or function. It is nil for globals, which can be
+	// named anywhere.
+	scope *Span
+}
+
+// Reference is a use of a variable.
+type Reference struct {
+	Span Span
+	// Symbol is the declaration the variable refers to, or nil if it isn't
+	// declared in the source, like the natives.
+	Symbol *Symbol
+}
+
+// Analyze scans, parses and resolves source, collecting its errors,
+// declarations and the declarations that its variables refer to.
+func Analyze(source string) *Analysis {
+	l := New()
+	a := &analyzer{
+		analysis: &Analysis{},
+		symbols:  map[*Token]*Symbol{},
+		globals:  map[string]*Token{},
+	}
+
+	tokens := NewScanner(l, source).scanTokens()
+	statements := NewParser(l, tokens).parse()
+
+	resolver := NewResolver(l, l.interpreter)
+	resolver.analysis = a
+	resolver.resolveStmts(statements)
+
+	for _, err := range l.errors {
+		a.analysis.Diagnostics = append(a.analysis.Diagnostics, Diagnostic{Span: a.span(err.token), Message: err.Message})
+	}
+
+	// Globals may be used before they are declared, so they are looked up
+	// once every declaration has been seen.
+	for _, use := range a.uses {
+		decl := use.decl
+		if decl == nil {
+			decl = a.globals[use.name.lexeme]
+		}
+		a.analysis.References = append(a.analysis.References, Reference{Span: a.span(use.name), Symbol: a.symbols[decl]})
+	}
+	return a.analysis
+}
+
+// SymbolAt returns the symbol declared or referred to by the name at offset,
+// or nil if there is none.
+func (a *Analysis) SymbolAt(offset int) *Symbol {
+	for _, symbol := range a.Declarations {
+		if symbol.Span.Contains(offset) {
+			return symbol
+		}
+	}
+	for _, reference := range a.References {
+		if reference.Span.Contains(offset) {
+			return reference.Symbol
+		}
+	}
+	return nil
+}
+
+// ReferencesTo returns where symbol is used.
+func (a *Analysis) ReferencesTo(symbol *Symbol) []Span {
+	spans := []Span{}
+	for _, reference := range a.References {
+		if reference.Symbol == symbol {
+			spans = append(spans, reference.Span)
+		}
+	}
+	return spans
+}
+
+// Completions returns the names that can be typed at offset: the locals in
+// scope there, innermost first, then the source's globals other than
+// methods, the globals that every script starts with and the keywords. Each name appears once.
+func (a *Analysis) Completions(offset int) []*Symbol {
+	seen := map[string]bool{}
+	completions := []*Symbol{}
+	add := func(symbol *Symbol) {
+		if !seen[symbol.Name] {
+			seen[symbol.Name] = true
+			completions = append(completions, symbol)
+		}
+	}
+
+	// A local declared later in the source is in the same or an inner
+	// scope, so it shadows the ones before it.
+	for i := len(a.Declarations) - 1; i >= 0; i-- {
+		if scope := a.Declarations[i].scope; scope != nil && scope.Contains(offset) {
+			add(a.Declarations[i])
+		}
+	}
+	for _, symbol := range a.Declarations {
+		// Methods are reached through instances, not by name.
+		if symbol.scope == nil && symbol.Kind != SymbolKind_METHOD {
+			add(symbol)
+		}
+	}
+
+	names := []string{}
+	for name := range natives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(&Symbol{Name: name, Kind: SymbolKind_FUNCTION, Detail: "native fun " + name + "()"})
+	}
+
+	names = names[:0]
+	for keyword := range keywords {
+		names = append(names, keyword)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(&Symbol{Name: name, Kind: SymbolKind_KEYWORD, Detail: name})
+	}
+	return completions
+}
+
+// analyzer collects an Analysis as the resolver reports declarations and
+// uses.
+type analyzer struct {
+	analysis *Analysis
+	// symbols are the symbols by the token that declares them, and globals
+	// the first top-level declaration of each name.
+	symbols map[*Token]*Symbol
+	globals map[string]*Token
+	uses    []use
+}
+
+// use is a variable named by name, declared by decl, or by a global if decl
+// is nil.
+type use struct {
+	name *Token
+	decl *Token
+}
+
+func (a *analyzer) span(token *Token) Span {
+	return sourceSpan(token)
+}
+
+// sourceSpan locates token in the source it was scanned from.
+func sourceSpan(token *Token) Span {
+	return Span{
+		Line:   firstLine(token),
+		Column: token.column,
+		Offset: token.offset,
+		Length: len(token.lexeme),
+	}
+}
+
+func (a *analyzer) global(name *Token) {
+	if _, ok := a.globals[name.lexeme]; !ok {
+		a.globals[name.lexeme] = name
+	}
+}
+
+func (a *analyzer) use(name, decl *Token) {
+	// `this` and `super` are resolved like variables but aren't declared.
+	if name.t == TokenType_THIS || name.t == TokenType_SUPER {
+		return
+	}
+	a.uses = append(a.uses, use{name: name, decl: decl})
+}
+
+// symbol records the declaration of name. Methods become children of their
+// class, and other top-level declarations are listed in Symbols. A local's
+// scope ends with end, the last token of its block or function.
+func (a *analyzer) symbol(name *Token, kind SymbolKind, detail string, parent *Symbol, topLevel bool, end *Token) *Symbol {
+	symbol := &Symbol{Name: name.lexeme, Kind: kind, Detail: detail, Span: a.span(name)}
+	if !topLevel && end != nil {
+		// The cursor may sit just after the last token, unless it closes
+		// the block.
+		length := end.offset - name.offset
+		if end.t != TokenType_RIGHT_BRACE {
+			length += len(end.lexeme)
+		}
+		symbol.scope = &Span{Offset: name.offset, Length: length}
+	}
+	a.symbols[name] = symbol
+	a.analysis.Declarations = append(a.analysis.Declarations, symbol)
+
+	if parent != nil {
+		parent.Children = append(parent.Children, symbol)
+	} else if topLevel {
+		a.analysis.Symbols = append(a.analysis.Symbols, symbol)
+	}
+	return symbol
+}
+
+// signature formats the name and parameters of fn, as in "add(a, b)".
+func signature(fn *Function) string {
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = param.lexeme
+	}
+	return fn.Name.lexeme + "(" + strings.Join(params, ", ") + ")"
+}
@@ -0,0 +1,258 @@
+package lox
+
+// Node is a node of the syntax tree: an Expr or a Stmt. The node types, and
+// their Clone, Equal, Walk and JSON support, are generated by genast.
+type Node interface {
+	// Pos is where the node starts in the source.
+	Pos() Position
+}
+
+// Position is a place in the source. Line and Column count from 1, and
+// Offset is in bytes from the start.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// pos is where the token starts.
+func (t *Token) pos() Position {
+	return Position{
+		Line:   firstLine(t),
+		Column: t.column,
+		Offset: t.offset,
+	}
+}
+
+// Equal reports whether a and b are the same tree. Positions, and what the
+// resolver has recorded on the nodes, are ignored, and tokens are compared by
+// their type and lexeme.
+func Equal(a, b Node) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case Expr:
+		return equalExpr(a, b)
+	case Stmt:
+		return equalStmt(a, b)
+	}
+	return false
+}
+
+// A Visitor's Visit method is called by Walk for each node. If it returns a
+// non-nil visitor w, Walk visits each of the children of node with w, then
+// calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth-first, as go/ast.Walk does.
+// Children left nil, by a syntax error or because they are optional, are
+// skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case Expr:
+		walkExpr(v, n)
+	case Stmt:
+		walkStmt(v, n)
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// InspectAST traverses the tree rooted at node depth-first, as
+// go/ast.Inspect does, calling f for each node and then f(nil) once its
+// children are done. If f returns false, the children of the node are
+// skipped.
+func InspectAST(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+func walkList[N Node](v Visitor, list []N) {
+	for _, node := range list {
+		if Node(node) != nil {
+			Walk(v, node)
+		}
+	}
+}
+
+// cloneList copies list with clone, keeping a nil list nil.
+func cloneList[T any](list []T, clone func(T) T) []T {
+	if list == nil {
+		return nil
+	}
+	ret := make([]T, len(list))
+	for i, x := range list {
+		ret[i] = clone(x)
+	}
+	return ret
+}
+
+func sameToken(token *Token) *Token {
+	return token
+}
+
+func equalList[T any](a, b []T, equal func(T, T) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalNode[N Node](a, b N) bool {
+	return Equal(a, b)
+}
+
+func equalToken(a, b *Token) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.t == b.t && a.lexeme == b.lexeme
+}
+
+// nodeJSON gives the fields of a node the type of the node and where it
+// starts. The variables the resolver has resolved to a local also get the
+// scope depth and slot it found.
+func nodeJSON(node Node, t string, fields map[string]any) map[string]any {
+	pos := node.Pos()
+	fields["type"] = t
+	fields["line"] = pos.Line
+	fields["column"] = pos.Column
+
+	var ref slotRef
+	switch n := node.(type) {
+	case *Variable:
+		ref = n.ref
+	case *Assign:
+		ref = n.ref
+	case *This:
+		ref = n.ref
+	case *Super:
+		ref = n.ref
+	}
+	if ref.resolved && ref.depth >= 0 {
+		fields["depth"] = ref.depth
+		fields["slot"] = ref.slot
+	}
+	return fields
+}
+
+func tokenJSON(token *Token) any {
+	if token == nil {
+		return nil
+	}
+	return token.lexeme
+}
+
+func tokensJSON(tokens []*Token) []string {
+	ret := make([]string, len(tokens))
+	for i, token := range tokens {
+		ret[i] = token.lexeme
+	}
+	return ret
+}
+
+// stmtToken returns a token that locates the statement in the source, used
+// to report errors raised while executing it. It returns nil for an empty
+// block.
+func stmtToken(stmt Stmt) *Token {
+	switch s := stmt.(type) {
+	case *Expression:
+		return exprToken(s.Expression)
+	case *If:
+		return exprToken(s.Condition)
+	case *Function:
+		return s.Name
+	case *Return:
+		return s.Keyword
+	case *Print:
+		return exprToken(s.Expression)
+	case *Var:
+		return s.Name
+	case *While:
+		return exprToken(s.Condition)
+	case *Break:
+		return s.Keyword
+	case *Continue:
+		return s.Keyword
+	case *Import:
+		return s.Keyword
+	case *Export:
+		return s.Keyword
+	case *Throw:
+		return s.Keyword
+	case *Try:
+		return s.Keyword
+	case *Block:
+		for _, stmt := range s.Statements {
+			if token := stmtToken(stmt); token != nil {
+				return token
+			}
+		}
+	case *Class:
+		return s.Name
+	}
+	return nil
+}
+
+// exprToken returns a token that locates the expression in the source, used
+// to report errors raised while evaluating it.
+func exprToken(expr Expr) *Token {
+	switch e := expr.(type) {
+	case *Binary:
+		return e.Operator
+	case *Grouping:
+		return exprToken(e.Expression)
+	case *Call:
+		return e.Paren
+	case *Get:
+		return e.Name
+	case *Set:
+		return e.Name
+	case *Literal:
+		return e.Token
+	case *Unary:
+		return e.Operator
+	case *This:
+		return e.Keyword
+	case *Super:
+		return e.Keyword
+	case *Logical:
+		return e.Operator
+	case *Variable:
+		return e.Name
+	case *Assign:
+		return e.Name
+	case *Interpolation:
+		return e.Start
+	case *Lambda:
+		return e.Function.Name
+	case *List:
+		return e.Bracket
+	case *Map:
+		return e.Brace
+	case *Index:
+		return e.Bracket
+	case *SetIndex:
+		return e.Bracket
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package lox
+
+import "fmt"
+
+type Callable interface {
+	Call(itrp *Interpreter, arguments []any) any
+	Arity() int
+	String() string
+}
+
+// Builtin is a value with methods implemented in Go, such as lists and maps.
+type Builtin interface {
+	Method(name *Token) *NativeFunction
+}
+
+// Indexable is a value that supports `value[key]` and `value[key] = v`.
+type Indexable interface {
+	Get(token *Token, key any) any
+	Set(token *Token, key any, value any)
+}
+
+var (
+	_ Builtin   = (*LoxList)(nil)
+	_ Builtin   = (*LoxMap)(nil)
+	_ Indexable = (*LoxList)(nil)
+	_ Indexable = (*LoxMap)(nil)
+)
+
+var _ Callable = (*NativeFunction)(nil)
+
+// NativeFunction is a Callable implemented in Go. It is shared by the
+// interpreter and the VM, so fn must not depend on either; it is passed the
+// Lox running the call.
+type NativeFunction struct {
+	arity int
+	// variadic functions accept arity or more arguments.
+	variadic bool
+	fn       func(l *Lox, arguments []any) any
+}
+
+func NewNativeFunction(arity int, fn func(arguments []any) any) *NativeFunction {
+	return &NativeFunction{arity: arity, fn: func(_ *Lox, arguments []any) any {
+		return fn(arguments)
+	}}
+}
+
+func (n *NativeFunction) Call(itrp *Interpreter, arguments []any) any {
+	// fn raises errors without a token; report them at the call.
+	defer func() {
+		if r := recover(); r != nil {
+			if re, ok := r.(*RuntimeError); ok && re.token == nil {
+				re.token = itrp.callSite
+			}
+			panic(r)
+		}
+	}()
+
+	return n.fn(itrp.lox, arguments)
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// arityMessage is the error for calling a function that takes arity
+// arguments with argCount of them, or "" if the call is allowed.
+func arityMessage(arity int, variadic bool, argCount int) string {
+	if variadic {
+		if argCount < arity {
+			return fmt.Sprintf("Expected at least %d arguments but got %d.", arity, argCount)
+		}
+		return ""
+	}
+	if argCount != arity {
+		return fmt.Sprintf("Expected %d arguments but got %d.", arity, argCount)
+	}
+	return ""
+}
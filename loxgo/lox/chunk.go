@@ -0,0 +1,224 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+)
+
+type OpCode byte
+
+const (
+	OpCode_CONSTANT OpCode = iota
+	OpCode_NIL
+	OpCode_TRUE
+	OpCode_FALSE
+	OpCode_POP
+	OpCode_GET_LOCAL
+	OpCode_SET_LOCAL
+	OpCode_GET_GLOBAL
+	OpCode_DEFINE_GLOBAL
+	OpCode_SET_GLOBAL
+	OpCode_GET_UPVALUE
+	OpCode_SET_UPVALUE
+	OpCode_GET_PROPERTY
+	OpCode_SET_PROPERTY
+	OpCode_GET_SUPER
+	OpCode_EQUAL
+	OpCode_GREATER
+	OpCode_GREATER_EQUAL
+	OpCode_LESS
+	OpCode_LESS_EQUAL
+	OpCode_ADD
+	OpCode_SUBTRACT
+	OpCode_MULTIPLY
+	OpCode_DIVIDE
+	OpCode_NOT
+	OpCode_NEGATE
+	OpCode_PRINT
+	OpCode_JUMP
+	OpCode_JUMP_IF_FALSE
+	OpCode_LOOP
+	OpCode_CALL
+	OpCode_INVOKE
+	OpCode_SUPER_INVOKE
+	OpCode_CLOSURE
+	OpCode_CLOSE_UPVALUE
+	OpCode_RETURN
+	OpCode_CLASS
+	OpCode_INHERIT
+	OpCode_METHOD
+	OpCode_BUILD_LIST
+	OpCode_BUILD_MAP
+	OpCode_THROW
+	OpCode_PUSH_CATCH
+	OpCode_PUSH_FINALLY
+	OpCode_POP_HANDLER
+	OpCode_GET_INDEX
+	OpCode_SET_INDEX
+	OpCode_IMPORT
+	OpCode_IMPORT_NAME
+	OpCode_INTERPOLATE
+)
+
+var opCodeNames = [...]string{
+	OpCode_CONSTANT:      "OP_CONSTANT",
+	OpCode_NIL:           "OP_NIL",
+	OpCode_TRUE:          "OP_TRUE",
+	OpCode_FALSE:         "OP_FALSE",
+	OpCode_POP:           "OP_POP",
+	OpCode_GET_LOCAL:     "OP_GET_LOCAL",
+	OpCode_SET_LOCAL:     "OP_SET_LOCAL",
+	OpCode_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OpCode_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OpCode_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OpCode_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OpCode_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OpCode_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OpCode_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OpCode_GET_SUPER:     "OP_GET_SUPER",
+	OpCode_EQUAL:         "OP_EQUAL",
+	OpCode_GREATER:       "OP_GREATER",
+	OpCode_GREATER_EQUAL: "OP_GREATER_EQUAL",
+	OpCode_LESS:          "OP_LESS",
+	OpCode_LESS_EQUAL:    "OP_LESS_EQUAL",
+	OpCode_ADD:           "OP_ADD",
+	OpCode_SUBTRACT:      "OP_SUBTRACT",
+	OpCode_MULTIPLY:      "OP_MULTIPLY",
+	OpCode_DIVIDE:        "OP_DIVIDE",
+	OpCode_NOT:           "OP_NOT",
+	OpCode_NEGATE:        "OP_NEGATE",
+	OpCode_PRINT:         "OP_PRINT",
+	OpCode_JUMP:          "OP_JUMP",
+	OpCode_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OpCode_LOOP:          "OP_LOOP",
+	OpCode_CALL:          "OP_CALL",
+	OpCode_INVOKE:        "OP_INVOKE",
+	OpCode_SUPER_INVOKE:  "OP_SUPER_INVOKE",
+	OpCode_CLOSURE:       "OP_CLOSURE",
+	OpCode_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OpCode_RETURN:        "OP_RETURN",
+	OpCode_CLASS:         "OP_CLASS",
+	OpCode_INHERIT:       "OP_INHERIT",
+	OpCode_METHOD:        "OP_METHOD",
+	OpCode_BUILD_LIST:    "OP_BUILD_LIST",
+	OpCode_BUILD_MAP:     "OP_BUILD_MAP",
+	OpCode_THROW:         "OP_THROW",
+	OpCode_PUSH_CATCH:    "OP_PUSH_CATCH",
+	OpCode_PUSH_FINALLY:  "OP_PUSH_FINALLY",
+	OpCode_POP_HANDLER:   "OP_POP_HANDLER",
+	OpCode_GET_INDEX:     "OP_GET_INDEX",
+	OpCode_SET_INDEX:     "OP_SET_INDEX",
+	OpCode_IMPORT:        "OP_IMPORT",
+	OpCode_IMPORT_NAME:   "OP_IMPORT_NAME",
+	OpCode_INTERPOLATE:   "OP_INTERPOLATE",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opCodeNames) {
+		return opCodeNames[op]
+	}
+	return fmt.Sprintf("OP_UNKNOWN(%d)", byte(op))
+}
+
+// Chunk is a sequence of bytecode with a parallel table of source lines and
+// the constants referenced by the code. Constant and global operands are two
+// bytes wide; local, upvalue and argument count operands are one byte.
+type Chunk struct {
+	code      []byte
+	lines     []int
+	constants []any
+}
+
+func NewChunk() *Chunk {
+	return &Chunk{
+		code:      []byte{},
+		lines:     []int{},
+		constants: []any{},
+	}
+}
+
+func (c *Chunk) write(b byte, line int) {
+	c.code = append(c.code, b)
+	c.lines = append(c.lines, line)
+}
+
+func (c *Chunk) addConstant(v any) int {
+	c.constants = append(c.constants, v)
+	return len(c.constants) - 1
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.code[offset])<<8 | int(c.code[offset+1])
+}
+
+func (c *Chunk) disassemble(w io.Writer, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+
+	for offset := 0; offset < len(c.code); {
+		offset = c.disassembleInstruction(w, offset)
+	}
+}
+
+func (c *Chunk) disassembleInstruction(w io.Writer, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	if offset > 0 && c.lines[offset] == c.lines[offset-1] {
+		fmt.Fprintf(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", c.lines[offset])
+	}
+
+	op := OpCode(c.code[offset])
+	switch op {
+	case OpCode_CONSTANT, OpCode_GET_GLOBAL, OpCode_DEFINE_GLOBAL, OpCode_SET_GLOBAL,
+		OpCode_GET_PROPERTY, OpCode_SET_PROPERTY, OpCode_GET_SUPER,
+		OpCode_CLASS, OpCode_METHOD, OpCode_IMPORT, OpCode_IMPORT_NAME:
+		constant := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d '%s'\n", op, constant, stringify(c.constants[constant]))
+		return offset + 3
+	case OpCode_GET_LOCAL, OpCode_SET_LOCAL, OpCode_GET_UPVALUE, OpCode_SET_UPVALUE, OpCode_CALL:
+		fmt.Fprintf(w, "%-16s %4d\n", op, c.code[offset+1])
+		return offset + 2
+	case OpCode_BUILD_LIST, OpCode_BUILD_MAP, OpCode_INTERPOLATE:
+		fmt.Fprintf(w, "%-16s %4d\n", op, c.readShort(offset+1))
+		return offset + 3
+	case OpCode_JUMP, OpCode_JUMP_IF_FALSE, OpCode_PUSH_CATCH, OpCode_PUSH_FINALLY:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OpCode_LOOP:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OpCode_INVOKE, OpCode_SUPER_INVOKE:
+		constant := c.readShort(offset + 1)
+		argCount := c.code[offset+3]
+		fmt.Fprintf(w, "%-16s (%d args) %4d '%s'\n", op, argCount, constant, stringify(c.constants[constant]))
+		return offset + 4
+	case OpCode_CLOSURE:
+		constant := c.readShort(offset + 1)
+		fn := c.constants[constant].(*ObjFunction)
+		fmt.Fprintf(w, "%-16s %4d %s\n", op, constant, fn)
+		offset += 3
+		for i := 0; i < fn.upvalueCount; i++ {
+			isLocal := c.code[offset]
+			index := c.code[offset+1]
+			fmt.Fprintf(w, "%04d    |                     %s %d\n", offset, tern(isLocal == 1, "local", "upvalue"), index)
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(w, "%s\n", op)
+		return offset + 1
+	}
+}
+
+// disassembleFunction prints the chunk of function followed by the chunks of
+// every function nested in its constants.
+func disassembleFunction(w io.Writer, function *ObjFunction) {
+	function.chunk.disassemble(w, function.String())
+	for _, constant := range function.chunk.constants {
+		if fn, ok := constant.(*ObjFunction); ok {
+			disassembleFunction(w, fn)
+		}
+	}
+}
@@ -0,0 +1,871 @@
+package lox
+
+import "math"
+
+var (
+	_ (VisitorExpr[any]) = (*Compiler)(nil)
+	_ (VisitorStmt[any]) = (*Compiler)(nil)
+)
+
+const maxLocals = math.MaxUint8 + 1
+
+type compilerLocal struct {
+	name       string
+	depth      int
+	isCaptured bool
+}
+
+type compilerLoop struct {
+	enclosing  *compilerLoop
+	scopeDepth int
+	// number of try statements already open when the loop started
+	tryDepth  int
+	breaks    []int
+	continues []int
+}
+
+// compilerTry describes a try statement whose body or catch block is being
+// compiled. Jumping out of it has to pop its handlers and run its finally
+// block first.
+type compilerTry struct {
+	handlers int
+	finally  []Stmt
+}
+
+type compilerUpvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// Compiler walks the resolved AST and emits bytecode for the VM. There is one
+// Compiler per function being compiled; nested functions push a new Compiler
+// whose enclosing field points back to the outer one.
+type Compiler struct {
+	lox        *Lox
+	enclosing  *Compiler
+	function   *ObjFunction
+	fnType     FunctionType
+	locals     []compilerLocal
+	upvalues   []compilerUpvalue
+	scopeDepth int
+	loop       *compilerLoop
+	tries      []*compilerTry
+	token      *Token
+	// tooManyLocals is set once the function has run out of local slots, so
+	// that the error is reported only once.
+	tooManyLocals bool
+}
+
+func NewCompiler(lox *Lox) *Compiler {
+	function := NewObjFunction("")
+	if len(lox.importing) > 0 {
+		function.file = lox.importing[0]
+	}
+	return newCompiler(lox, nil, function, FunctionType_NONE)
+}
+
+func newCompiler(lox *Lox, enclosing *Compiler, function *ObjFunction, fnType FunctionType) *Compiler {
+	c := &Compiler{
+		lox:       lox,
+		enclosing: enclosing,
+		function:  function,
+		fnType:    fnType,
+		locals:    []compilerLocal{},
+		upvalues:  []compilerUpvalue{},
+		token:     &Token{line: 1},
+	}
+	if enclosing != nil {
+		c.token = enclosing.token
+		function.file = enclosing.function.file
+	}
+
+	// Slot zero holds the function being called, or the receiver for methods.
+	slotZero := ""
+	if fnType == FunctionType_METHOD || fnType == FunctionType_INITIALIZER {
+		slotZero = "this"
+	}
+	c.locals = append(c.locals, compilerLocal{name: slotZero, depth: 0})
+	return c
+}
+
+func (c *Compiler) compile(stmts []Stmt) *ObjFunction {
+	for _, stmt := range stmts {
+		c.compileStmt(stmt)
+	}
+	return c.endCompiler()
+}
+
+// compileExpression compiles a script that returns the value of expr.
+func (c *Compiler) compileExpression(expr Expr) *ObjFunction {
+	c.compileExpr(expr)
+	c.emitOp(OpCode_RETURN)
+	c.function.upvalueCount = len(c.upvalues)
+	return c.function
+}
+
+func (c *Compiler) compileStmt(stmt Stmt) {
+	acceptStmt[any](stmt, c)
+}
+
+func (c *Compiler) compileExpr(expr Expr) {
+	acceptExpr[any](expr, c)
+}
+
+func (c *Compiler) endCompiler() *ObjFunction {
+	c.emitReturn()
+	c.function.upvalueCount = len(c.upvalues)
+	return c.function
+}
+
+func (c *Compiler) at(token *Token) {
+	if token != nil {
+		c.token = token
+	}
+}
+
+func (c *Compiler) error(message string) {
+	c.lox.error(c.token, message)
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return c.function.chunk
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.chunk().write(b, c.token.line)
+}
+
+func (c *Compiler) emitOp(op OpCode) {
+	c.emitByte(byte(op))
+}
+
+func (c *Compiler) emitOpByte(op OpCode, b byte) {
+	c.emitOp(op)
+	c.emitByte(b)
+}
+
+func (c *Compiler) emitShort(v int) {
+	c.emitByte(byte(v >> 8 & 0xff))
+	c.emitByte(byte(v & 0xff))
+}
+
+func (c *Compiler) emitOpShort(op OpCode, v int) {
+	c.emitOp(op)
+	c.emitShort(v)
+}
+
+func (c *Compiler) emitReturn() {
+	c.emitImplicitReturnValue()
+	c.emitOp(OpCode_RETURN)
+}
+
+func (c *Compiler) emitImplicitReturnValue() {
+	if c.fnType == FunctionType_INITIALIZER {
+		c.emitOpByte(OpCode_GET_LOCAL, 0)
+	} else {
+		c.emitOp(OpCode_NIL)
+	}
+}
+
+func (c *Compiler) makeConstant(v any) int {
+	constant := c.chunk().addConstant(v)
+	if constant > math.MaxUint16 {
+		c.error("Too many constants in one chunk.")
+		return 0
+	}
+	return constant
+}
+
+func (c *Compiler) emitConstant(v any) {
+	c.emitOpShort(OpCode_CONSTANT, c.makeConstant(v))
+}
+
+func (c *Compiler) identifierConstant(name *Token) int {
+	return c.makeConstant(name.lexeme)
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitOp(op)
+	c.emitShort(0xffff)
+	return len(c.chunk().code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	// -2 to adjust for the bytecode for the jump offset itself.
+	jump := len(c.chunk().code) - offset - 2
+	if jump > math.MaxUint16 {
+		c.error("Too much code to jump over.")
+	}
+
+	c.chunk().code[offset] = byte(jump >> 8 & 0xff)
+	c.chunk().code[offset+1] = byte(jump & 0xff)
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(OpCode_LOOP)
+
+	offset := len(c.chunk().code) - loopStart + 2
+	if offset > math.MaxUint16 {
+		c.error("Loop body too large.")
+	}
+	c.emitShort(offset)
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		if c.locals[len(c.locals)-1].isCaptured {
+			c.emitOp(OpCode_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(OpCode_POP)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *Compiler) addLocal(name string) {
+	if len(c.locals) == maxLocals {
+		if !c.tooManyLocals {
+			c.error("Too many local variables in function.")
+			c.tooManyLocals = true
+		}
+		return
+	}
+	c.locals = append(c.locals, compilerLocal{name: name, depth: c.scopeDepth})
+}
+
+func (c *Compiler) resolveLocal(name string) int {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index byte, isLocal bool) int {
+	for i, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(c.upvalues) == maxLocals {
+		c.error("Too many closure variables in function.")
+		return 0
+	}
+
+	c.upvalues = append(c.upvalues, compilerUpvalue{index: index, isLocal: isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+
+	local := c.enclosing.resolveLocal(name)
+	if local != -1 {
+		c.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(byte(local), true)
+	}
+
+	upvalue := c.enclosing.resolveUpvalue(name)
+	if upvalue != -1 {
+		return c.addUpvalue(byte(upvalue), false)
+	}
+
+	return -1
+}
+
+func (c *Compiler) namedVariable(name *Token, assign Expr) {
+	c.at(name)
+
+	var getOp, setOp OpCode
+	var arg int
+	if arg = c.resolveLocal(name.lexeme); arg != -1 {
+		getOp, setOp = OpCode_GET_LOCAL, OpCode_SET_LOCAL
+	} else if arg = c.resolveUpvalue(name.lexeme); arg != -1 {
+		getOp, setOp = OpCode_GET_UPVALUE, OpCode_SET_UPVALUE
+	} else {
+		arg = c.identifierConstant(name)
+		getOp, setOp = OpCode_GET_GLOBAL, OpCode_SET_GLOBAL
+	}
+
+	op := getOp
+	if assign != nil {
+		c.compileExpr(assign)
+		c.at(name)
+		op = setOp
+	}
+
+	if op == OpCode_GET_GLOBAL || op == OpCode_SET_GLOBAL {
+		c.emitOpShort(op, arg)
+	} else {
+		c.emitOpByte(op, byte(arg))
+	}
+}
+
+// declareVariable makes the value on top of the stack a named variable: a new
+// local in the current scope, or a global at the top level.
+func (c *Compiler) declareVariable(name *Token) {
+	c.at(name)
+	if c.scopeDepth > 0 {
+		c.addLocal(name.lexeme)
+		return
+	}
+	c.emitOpShort(OpCode_DEFINE_GLOBAL, c.identifierConstant(name))
+}
+
+func (c *Compiler) compileFunction(fn *Function, fnType FunctionType) {
+	c.at(fn.Name)
+	compiler := newCompiler(c.lox, c, NewObjFunction(fn.Name.lexeme), fnType)
+	compiler.beginScope()
+
+	for _, param := range fn.Params {
+		compiler.at(param)
+		compiler.function.arity++
+		compiler.addLocal(param.lexeme)
+	}
+	for _, stmt := range fn.Body {
+		compiler.compileStmt(stmt)
+	}
+
+	function := compiler.endCompiler()
+	c.token = compiler.token
+
+	c.emitOpShort(OpCode_CLOSURE, c.makeConstant(function))
+	for _, upvalue := range compiler.upvalues {
+		c.emitByte(tern[byte](upvalue.isLocal, 1, 0))
+		c.emitByte(upvalue.index)
+	}
+}
+
+func (c *Compiler) VisitBinary(expr *Binary) any {
+	c.compileExpr(expr.Left)
+	c.compileExpr(expr.Right)
+	c.at(expr.Operator)
+
+	switch expr.Operator.t {
+	case TokenType_BANG_EQUAL:
+		c.emitOp(OpCode_EQUAL)
+		c.emitOp(OpCode_NOT)
+	case TokenType_EQUAL_EQUAL:
+		c.emitOp(OpCode_EQUAL)
+	case TokenType_GREATER:
+		c.emitOp(OpCode_GREATER)
+	case TokenType_GREATER_EQUAL:
+		c.emitOp(OpCode_GREATER_EQUAL)
+	case TokenType_LESS:
+		c.emitOp(OpCode_LESS)
+	case TokenType_LESS_EQUAL:
+		c.emitOp(OpCode_LESS_EQUAL)
+	case TokenType_PLUS:
+		c.emitOp(OpCode_ADD)
+	case TokenType_MINUS:
+		c.emitOp(OpCode_SUBTRACT)
+	case TokenType_STAR:
+		c.emitOp(OpCode_MULTIPLY)
+	case TokenType_SLASH:
+		c.emitOp(OpCode_DIVIDE)
+	}
+	return nil
+}
+func (c *Compiler) VisitGrouping(expr *Grouping) any {
+	c.compileExpr(expr.Expression)
+	return nil
+}
+func (c *Compiler) VisitCall(expr *Call) any {
+	if len(expr.Arguments) > math.MaxUint8 {
+		c.at(expr.Paren)
+		c.error("Can't have more than 255 arguments.")
+	}
+
+	// Calls on a property or super method skip creating a bound method.
+	if get, ok := expr.Callee.(*Get); ok {
+		c.compileExpr(get.Object)
+		for _, arg := range expr.Arguments {
+			c.compileExpr(arg)
+		}
+		c.at(get.Name)
+		c.emitOpShort(OpCode_INVOKE, c.identifierConstant(get.Name))
+		c.emitByte(byte(len(expr.Arguments)))
+		return nil
+	}
+	if super, ok := expr.Callee.(*Super); ok {
+		c.namedVariable(&Token{t: TokenType_THIS, lexeme: "this", line: super.Keyword.line}, nil)
+		for _, arg := range expr.Arguments {
+			c.compileExpr(arg)
+		}
+		c.namedVariable(super.Keyword, nil)
+		c.at(super.Method)
+		c.emitOpShort(OpCode_SUPER_INVOKE, c.identifierConstant(super.Method))
+		c.emitByte(byte(len(expr.Arguments)))
+		return nil
+	}
+
+	c.compileExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		c.compileExpr(arg)
+	}
+	c.at(expr.Paren)
+	c.emitOpByte(OpCode_CALL, byte(len(expr.Arguments)))
+	return nil
+}
+func (c *Compiler) VisitGet(expr *Get) any {
+	c.compileExpr(expr.Object)
+	c.at(expr.Name)
+	c.emitOpShort(OpCode_GET_PROPERTY, c.identifierConstant(expr.Name))
+	return nil
+}
+func (c *Compiler) VisitSet(expr *Set) any {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Value)
+	c.at(expr.Name)
+	c.emitOpShort(OpCode_SET_PROPERTY, c.identifierConstant(expr.Name))
+	return nil
+}
+func (c *Compiler) VisitLiteral(expr *Literal) any {
+	c.at(expr.Token)
+	switch expr.Value {
+	case nil:
+		c.emitOp(OpCode_NIL)
+	case true:
+		c.emitOp(OpCode_TRUE)
+	case false:
+		c.emitOp(OpCode_FALSE)
+	default:
+		c.emitConstant(expr.Value)
+	}
+	return nil
+}
+func (c *Compiler) VisitUnary(expr *Unary) any {
+	c.compileExpr(expr.Right)
+	c.at(expr.Operator)
+
+	switch expr.Operator.t {
+	case TokenType_BANG:
+		c.emitOp(OpCode_NOT)
+	case TokenType_MINUS:
+		c.emitOp(OpCode_NEGATE)
+	}
+	return nil
+}
+func (c *Compiler) VisitThis(expr *This) any {
+	c.namedVariable(expr.Keyword, nil)
+	return nil
+}
+func (c *Compiler) VisitSuper(expr *Super) any {
+	c.namedVariable(&Token{t: TokenType_THIS, lexeme: "this", line: expr.Keyword.line}, nil)
+	c.namedVariable(expr.Keyword, nil)
+	c.at(expr.Method)
+	c.emitOpShort(OpCode_GET_SUPER, c.identifierConstant(expr.Method))
+	return nil
+}
+func (c *Compiler) VisitLogical(expr *Logical) any {
+	c.compileExpr(expr.Left)
+	c.at(expr.Operator)
+
+	if expr.Operator.t == TokenType_OR {
+		elseJump := c.emitJump(OpCode_JUMP_IF_FALSE)
+		endJump := c.emitJump(OpCode_JUMP)
+
+		c.patchJump(elseJump)
+		c.emitOp(OpCode_POP)
+
+		c.compileExpr(expr.Right)
+		c.patchJump(endJump)
+		return nil
+	}
+
+	endJump := c.emitJump(OpCode_JUMP_IF_FALSE)
+	c.emitOp(OpCode_POP)
+	c.compileExpr(expr.Right)
+	c.patchJump(endJump)
+	return nil
+}
+func (c *Compiler) VisitVariable(expr *Variable) any {
+	c.namedVariable(expr.Name, nil)
+	return nil
+}
+func (c *Compiler) VisitAssign(expr *Assign) any {
+	c.namedVariable(expr.Name, expr.Value)
+	return nil
+}
+func (c *Compiler) VisitInterpolation(expr *Interpolation) any {
+	for _, part := range expr.Parts {
+		c.compileExpr(part)
+	}
+	c.at(expr.Start)
+	c.emitOpShort(OpCode_INTERPOLATE, len(expr.Parts))
+	return nil
+}
+
+func (c *Compiler) VisitLambda(expr *Lambda) any {
+	c.compileFunction(expr.Function, FunctionType_FUNCTION)
+	return nil
+}
+
+func (c *Compiler) VisitList(expr *List) any {
+	for _, element := range expr.Elements {
+		c.compileExpr(element)
+	}
+	c.at(expr.Bracket)
+	if len(expr.Elements) > math.MaxUint16 {
+		c.error("Too many elements in list literal.")
+	}
+	c.emitOpShort(OpCode_BUILD_LIST, len(expr.Elements))
+	return nil
+}
+func (c *Compiler) VisitMap(expr *Map) any {
+	for i := range expr.Keys {
+		c.compileExpr(expr.Keys[i])
+		c.compileExpr(expr.Values[i])
+	}
+	c.at(expr.Brace)
+	if len(expr.Keys) > math.MaxUint16 {
+		c.error("Too many entries in map literal.")
+	}
+	c.emitOpShort(OpCode_BUILD_MAP, len(expr.Keys))
+	return nil
+}
+func (c *Compiler) VisitIndex(expr *Index) any {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.at(expr.Bracket)
+	c.emitOp(OpCode_GET_INDEX)
+	return nil
+}
+func (c *Compiler) VisitSetIndex(expr *SetIndex) any {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.compileExpr(expr.Value)
+	c.at(expr.Bracket)
+	c.emitOp(OpCode_SET_INDEX)
+	return nil
+}
+
+func (c *Compiler) VisitExpression(stmt *Expression) any {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(OpCode_POP)
+	return nil
+}
+func (c *Compiler) VisitIf(stmt *If) any {
+	c.compileExpr(stmt.Condition)
+
+	thenJump := c.emitJump(OpCode_JUMP_IF_FALSE)
+	c.emitOp(OpCode_POP)
+	c.compileStmt(stmt.Then)
+
+	elseJump := c.emitJump(OpCode_JUMP)
+
+	c.patchJump(thenJump)
+	c.emitOp(OpCode_POP)
+
+	if stmt.Else != nil {
+		c.compileStmt(stmt.Else)
+	}
+	c.patchJump(elseJump)
+	return nil
+}
+func (c *Compiler) VisitFunction(stmt *Function) any {
+	// A local function is marked as declared before its body is compiled so
+	// that it can refer to itself recursively.
+	if c.scopeDepth > 0 {
+		c.at(stmt.Name)
+		c.addLocal(stmt.Name.lexeme)
+		c.compileFunction(stmt, FunctionType_FUNCTION)
+		return nil
+	}
+
+	c.compileFunction(stmt, FunctionType_FUNCTION)
+	c.declareVariable(stmt.Name)
+	return nil
+}
+func (c *Compiler) VisitReturn(stmt *Return) any {
+	c.at(stmt.Keyword)
+
+	if len(c.tries) == 0 {
+		if stmt.Value == nil {
+			c.emitReturn()
+			return nil
+		}
+
+		c.compileExpr(stmt.Value)
+		c.emitOp(OpCode_RETURN)
+		return nil
+	}
+
+	if stmt.Value == nil {
+		c.emitImplicitReturnValue()
+	} else {
+		c.compileExpr(stmt.Value)
+	}
+
+	// Keep the return value in a hidden local while finally blocks run.
+	c.beginScope()
+	c.addLocal("")
+	slot := len(c.locals) - 1
+	c.exitTries(0)
+	c.emitOpByte(OpCode_GET_LOCAL, byte(slot))
+	c.emitOp(OpCode_RETURN)
+	c.scopeDepth--
+	c.locals = c.locals[:slot]
+	return nil
+}
+func (c *Compiler) VisitPrint(stmt *Print) any {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(OpCode_PRINT)
+	return nil
+}
+func (c *Compiler) VisitVar(stmt *Var) any {
+	c.at(stmt.Name)
+	if stmt.Initializer != nil {
+		c.compileExpr(stmt.Initializer)
+	} else {
+		c.emitOp(OpCode_NIL)
+	}
+
+	c.declareVariable(stmt.Name)
+	return nil
+}
+func (c *Compiler) VisitWhile(stmt *While) any {
+	loop := &compilerLoop{enclosing: c.loop, scopeDepth: c.scopeDepth, tryDepth: len(c.tries)}
+	c.loop = loop
+
+	loopStart := len(c.chunk().code)
+	c.compileExpr(stmt.Condition)
+
+	exitJump := c.emitJump(OpCode_JUMP_IF_FALSE)
+	c.emitOp(OpCode_POP)
+	c.compileStmt(stmt.Body)
+
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	if stmt.Increment != nil {
+		c.compileExpr(stmt.Increment)
+		c.emitOp(OpCode_POP)
+	}
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	c.emitOp(OpCode_POP)
+
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+	c.loop = loop.enclosing
+	return nil
+}
+
+// discardLoopLocals pops the locals declared inside the current loop without
+// forgetting them, ahead of a jump out of the loop body.
+func (c *Compiler) discardLoopLocals() {
+	for i := len(c.locals) - 1; i >= 0 && c.locals[i].depth > c.loop.scopeDepth; i-- {
+		if c.locals[i].isCaptured {
+			c.emitOp(OpCode_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(OpCode_POP)
+		}
+	}
+}
+func (c *Compiler) VisitBreak(stmt *Break) any {
+	c.at(stmt.Keyword)
+	c.exitTries(c.loop.tryDepth)
+	c.discardLoopLocals()
+	c.loop.breaks = append(c.loop.breaks, c.emitJump(OpCode_JUMP))
+	return nil
+}
+func (c *Compiler) VisitContinue(stmt *Continue) any {
+	c.at(stmt.Keyword)
+	c.exitTries(c.loop.tryDepth)
+	c.discardLoopLocals()
+	c.loop.continues = append(c.loop.continues, c.emitJump(OpCode_JUMP))
+	return nil
+}
+func (c *Compiler) VisitBlock(stmt *Block) any {
+	c.block(stmt.Statements)
+	return nil
+}
+
+func (c *Compiler) block(stmts []Stmt) {
+	c.beginScope()
+	for _, s := range stmts {
+		c.compileStmt(s)
+	}
+	c.endScope()
+}
+
+// VisitImport compiles
+//
+//	IMPORT path              module on the stack
+//	DEFINE_GLOBAL alias      (with alias)
+//	IMPORT_NAME name         (for each name)
+//	DEFINE_GLOBAL name
+//	POP                      (without alias)
+//
+// The resolver only allows imports at top level, so names are globals.
+func (c *Compiler) VisitImport(stmt *Import) any {
+	c.at(stmt.Keyword)
+	c.emitOpShort(OpCode_IMPORT, c.makeConstant(stmt.Path.literal))
+	if stmt.Alias != nil {
+		c.emitOpShort(OpCode_DEFINE_GLOBAL, c.identifierConstant(stmt.Alias))
+		return nil
+	}
+
+	for _, name := range stmt.Names {
+		c.at(name)
+		c.emitOpShort(OpCode_IMPORT_NAME, c.identifierConstant(name))
+		c.emitOpShort(OpCode_DEFINE_GLOBAL, c.identifierConstant(name))
+	}
+	c.emitOp(OpCode_POP)
+	return nil
+}
+func (c *Compiler) VisitExport(stmt *Export) any {
+	c.compileStmt(stmt.Declaration)
+	return nil
+}
+func (c *Compiler) VisitThrow(stmt *Throw) any {
+	c.compileExpr(stmt.Value)
+	c.at(stmt.Keyword)
+	c.emitOp(OpCode_THROW)
+	return nil
+}
+
+// VisitTry compiles
+//
+//	PUSH_FINALLY -> finallyHandler   (with finally)
+//	PUSH_CATCH -> catchHandler       (with catch)
+//	  body
+//	POP_HANDLER                      (with catch)
+//	JUMP -> afterCatch               (with catch)
+//	catchHandler:                    error value on the stack
+//	  catch body
+//	afterCatch:
+//	POP_HANDLER                      (with finally)
+//	finally body                     (with finally)
+//	JUMP -> end                      (with finally)
+//	finallyHandler:                  error on the stack
+//	  finally body
+//	  THROW
+//	end:
+//
+// Both handlers are pushed before the body so that they record the same
+// stack depth, and errors in the catch body still run the finally block.
+func (c *Compiler) VisitTry(stmt *Try) any {
+	c.at(stmt.Keyword)
+
+	try := &compilerTry{finally: stmt.Finally}
+	finallyHandler := -1
+	if stmt.Finally != nil {
+		finallyHandler = c.emitJump(OpCode_PUSH_FINALLY)
+		try.handlers++
+	}
+
+	if stmt.Catch == nil {
+		c.tries = append(c.tries, try)
+		c.block(stmt.Body)
+		c.tries = c.tries[:len(c.tries)-1]
+	} else {
+		catchHandler := c.emitJump(OpCode_PUSH_CATCH)
+		try.handlers++
+
+		c.tries = append(c.tries, try)
+		c.block(stmt.Body)
+		c.emitOp(OpCode_POP_HANDLER)
+		try.handlers--
+		afterCatch := c.emitJump(OpCode_JUMP)
+
+		c.patchJump(catchHandler)
+		c.beginScope()
+		c.at(stmt.CatchName)
+		c.addLocal(stmt.CatchName.lexeme)
+		for _, s := range stmt.Catch {
+			c.compileStmt(s)
+		}
+		c.endScope()
+		c.tries = c.tries[:len(c.tries)-1]
+
+		c.patchJump(afterCatch)
+	}
+
+	if stmt.Finally == nil {
+		return nil
+	}
+
+	c.emitOp(OpCode_POP_HANDLER)
+	c.block(stmt.Finally)
+	end := c.emitJump(OpCode_JUMP)
+
+	c.patchJump(finallyHandler)
+	c.beginScope()
+	c.addLocal("")
+	c.block(stmt.Finally)
+	c.emitOpByte(OpCode_GET_LOCAL, byte(len(c.locals)-1))
+	c.emitOp(OpCode_THROW)
+	c.endScope()
+
+	c.patchJump(end)
+	return nil
+}
+
+// exitTries emits what leaving the open try statements down to depth requires
+// before a jump or return: popping their handlers and running their finally
+// blocks, innermost first.
+func (c *Compiler) exitTries(depth int) {
+	tries := c.tries
+	defer func() {
+		c.tries = tries
+	}()
+
+	for i := len(tries) - 1; i >= depth; i-- {
+		for j := 0; j < tries[i].handlers; j++ {
+			c.emitOp(OpCode_POP_HANDLER)
+		}
+		if tries[i].finally != nil {
+			c.tries = tries[:i]
+			c.block(tries[i].finally)
+		}
+	}
+}
+func (c *Compiler) VisitClass(stmt *Class) any {
+	c.at(stmt.Name)
+	nameConstant := c.identifierConstant(stmt.Name)
+
+	c.emitOpShort(OpCode_CLASS, nameConstant)
+	c.declareVariable(stmt.Name)
+
+	if stmt.SuperClass != nil {
+		c.namedVariable(stmt.SuperClass.Name, nil)
+
+		c.beginScope()
+		c.addLocal("super")
+
+		c.namedVariable(stmt.Name, nil)
+		c.emitOp(OpCode_INHERIT)
+	}
+
+	c.namedVariable(stmt.Name, nil)
+	for _, fn := range stmt.Methods {
+		fnType := FunctionType_METHOD
+		if fn.Name.lexeme == "init" {
+			fnType = FunctionType_INITIALIZER
+		}
+
+		c.compileFunction(fn, fnType)
+		c.at(fn.Name)
+		c.emitOpShort(OpCode_METHOD, c.identifierConstant(fn.Name))
+	}
+	c.emitOp(OpCode_POP)
+
+	if stmt.SuperClass != nil {
+		c.endScope()
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package lox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const debugHelp = `The program stops before its first line. Commands are:
+
+  break [file:]line    stop at a line; without one, list the breakpoints
+  clear [file:]line    remove a breakpoint
+  continue             run until the next breakpoint
+  step                 run to the next line, stepping into calls
+  next                 run to the next line, stepping over calls
+  out                  run until the current function returns
+  backtrace            list the frames of the call stack
+  frame <n>            select a frame; up and down select the next one out or in
+  vars                 list the variables the frame can see, by scope
+  print <expr>         evaluate an expression in the frame
+  set <name> = <expr>  assign to a variable in the frame
+  list                 show the source around the frame's line
+  help                 show this help
+  quit                 stop the program
+
+Most commands can be shortened to their first letter, and backtrace to bt.
+An empty line repeats the previous command.
+`
+
+// DebugConsole is a command-line front end for a Debugger. Each time the
+// program stops, it reads commands from a user until one of them resumes
+// the program. If the input ends, the program runs on without stopping.
+type DebugConsole struct {
+	debugger *Debugger
+	in       *bufio.Reader
+	out      io.Writer
+	// frame is the frame commands inspect, counting from the innermost, and
+	// previous the last command entered.
+	frame    int
+	previous string
+	// sources caches the lines of the files listed so far.
+	sources  map[string][]string
+	detached bool
+}
+
+// NewDebugConsole creates a console that stops the program before its first
+// line. Attach its debugger with WithDebugger(console.Debugger()).
+func NewDebugConsole(in io.Reader, out io.Writer) *DebugConsole {
+	c := &DebugConsole{
+		in:      bufio.NewReader(in),
+		out:     out,
+		sources: map[string][]string{},
+	}
+	c.debugger = NewDebugger(c.stopped)
+	c.debugger.Pause()
+	return c
+}
+
+func (c *DebugConsole) Debugger() *Debugger {
+	return c.debugger
+}
+
+func (c *DebugConsole) stopped(stop *Stop) Resume {
+	if c.detached {
+		return Resume_CONTINUE
+	}
+
+	c.frame = 0
+	frame := stop.Frames()[0]
+	switch stop.Reason {
+	case StopReason_BREAKPOINT:
+		fmt.Fprintf(c.out, "Breakpoint in %s at %s\n", frame, location(frame.File, frame.Line))
+	default:
+		fmt.Fprintf(c.out, "Stopped in %s at %s\n", frame, location(frame.File, frame.Line))
+	}
+	c.listLines(frame.File, frame.Line, 0)
+
+	for {
+		fmt.Fprint(c.out, "(debug) ")
+		line, err := c.in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(c.out)
+			c.detached = true
+			return Resume_CONTINUE
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = c.previous
+		}
+		c.previous = line
+		if resume, ok := c.command(stop, line); ok {
+			return resume
+		}
+	}
+}
+
+// command runs a command. It reports whether the command resumes the
+// program, and how.
+func (c *DebugConsole) command(stop *Stop, line string) (Resume, bool) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	frames := stop.Frames()
+	frame := frames[c.frame]
+
+	switch name {
+	case "":
+	case "c", "continue":
+		return Resume_CONTINUE, true
+	case "s", "step":
+		return Resume_STEP_IN, true
+	case "n", "next":
+		return Resume_STEP_OVER, true
+	case "o", "out":
+		return Resume_STEP_OUT, true
+	case "q", "quit":
+		return Resume_STOP, true
+	case "h", "help":
+		fmt.Fprint(c.out, debugHelp)
+	case "b", "break":
+		if arg == "" {
+			c.listBreakpoints()
+			break
+		}
+		if file, line, ok := c.parseLine(frame.File, arg); ok {
+			c.debugger.SetBreakpoint(file, line)
+			fmt.Fprintf(c.out, "Breakpoint at %s\n", location(breakpointFile(file), line))
+		}
+	case "clear":
+		if file, line, ok := c.parseLine(frame.File, arg); ok {
+			if !c.debugger.ClearBreakpoint(file, line) {
+				fmt.Fprintf(c.out, "No breakpoint at %s\n", location(breakpointFile(file), line))
+			}
+		}
+	case "bt", "backtrace":
+		for i, f := range frames {
+			marker := " "
+			if i == c.frame {
+				marker = "*"
+			}
+			fmt.Fprintf(c.out, "%s #%d %s at %s\n", marker, i, f, location(f.File, f.Line))
+		}
+	case "frame", "up", "down":
+		selected := c.frame
+		switch name {
+		case "up":
+			selected++
+		case "down":
+			selected--
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Fprintln(c.out, "usage: frame <n>")
+				return "", false
+			}
+			selected = n
+		}
+		if selected < 0 || selected >= len(frames) {
+			fmt.Fprintf(c.out, "No frame %d\n", selected)
+			break
+		}
+		c.frame = selected
+		f := frames[selected]
+		fmt.Fprintf(c.out, "#%d %s at %s\n", selected, f, location(f.File, f.Line))
+	case "v", "vars":
+		scopes, _ := stop.Scopes(c.frame)
+		for _, scope := range scopes {
+			fmt.Fprintf(c.out, "%s:\n", scope.Name)
+			for _, v := range scope.Variables {
+				fmt.Fprintf(c.out, "  %s = %s\n", v.Name, Inspect(v.Value))
+			}
+		}
+	case "p", "print":
+		c.evaluate(stop, arg, "print <expr>")
+	case "set":
+		c.evaluate(stop, arg, "set <name> = <expr>")
+	case "l", "list":
+		c.listLines(frame.File, frame.Line, 5)
+	default:
+		fmt.Fprintf(c.out, "Unknown command '%s'. Enter help for a list.\n", name)
+	}
+	return "", false
+}
+
+func (c *DebugConsole) evaluate(stop *Stop, source string, usage string) {
+	if source == "" {
+		fmt.Fprintln(c.out, "usage: "+usage)
+		return
+	}
+	value, err := stop.Evaluate(c.frame, source)
+	if err != nil {
+		fmt.Fprintln(c.out, err.Error())
+		return
+	}
+	fmt.Fprintln(c.out, Inspect(value))
+}
+
+// parseLine parses a breakpoint location, "[file:]line", where file defaults
+// to the file of the selected frame.
+func (c *DebugConsole) parseLine(file string, arg string) (string, int, bool) {
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, arg = arg[:i], arg[i+1:]
+	}
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		fmt.Fprintln(c.out, "usage: break [file:]line")
+		return "", 0, false
+	}
+	return file, line, true
+}
+
+func (c *DebugConsole) listBreakpoints() {
+	breakpoints := c.debugger.Breakpoints()
+	files := make([]string, 0, len(breakpoints))
+	for file := range breakpoints {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		for _, line := range breakpoints[file] {
+			fmt.Fprintf(c.out, "  %s\n", location(file, line))
+		}
+	}
+}
+
+// listLines prints line of file, marked, and up to around lines either side
+// of it.
+func (c *DebugConsole) listLines(file string, line int, around int) {
+	if file == "" {
+		return
+	}
+	lines, ok := c.sources[file]
+	if !ok {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return
+		}
+		lines = strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		c.sources[file] = lines
+	}
+
+	for n := line - around; n <= line+around; n++ {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		marker := "  "
+		if n == line {
+			marker = "=>"
+		}
+		fmt.Fprintf(c.out, "%s %4d  %s\n", marker, n, lines[n-1])
+	}
+}
+
+// location formats a line of a file for the user.
+func location(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", displayPath(file), line)
+}
@@ -0,0 +1,446 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Resume is how a paused program carries on.
+type Resume string
+
+const (
+	// Resume_CONTINUE runs until the next breakpoint.
+	Resume_CONTINUE Resume = "CONTINUE"
+	// Resume_STEP_IN stops at the next line, in whichever function it is.
+	Resume_STEP_IN Resume = "STEP_IN"
+	// Resume_STEP_OVER stops at the next line of the current function or
+	// one that called it, running any calls on the current line.
+	Resume_STEP_OVER Resume = "STEP_OVER"
+	// Resume_STEP_OUT stops once the current function has returned.
+	Resume_STEP_OUT Resume = "STEP_OUT"
+	// Resume_STOP ends the program with a runtime error wrapping ErrStopped,
+	// which Lox code can't catch.
+	Resume_STOP Resume = "STOP"
+)
+
+// StopReason is why a program paused.
+type StopReason string
+
+const (
+	StopReason_BREAKPOINT StopReason = "breakpoint"
+	StopReason_STEP       StopReason = "step"
+	StopReason_PAUSE      StopReason = "pause"
+)
+
+// Debugger pauses a program running on the tree-walking interpreter at
+// breakpoints, after steps, or when asked to, and lets its host inspect the
+// paused program. Attach it to a Lox with WithDebugger.
+//
+// Programs pause before a statement that starts a new line, or that loops
+// back to the start of a line. The debugger
+// calls stopped on the goroutine running the program, which stays paused
+// until stopped returns how to resume it. SetBreakpoint, ClearBreakpoint,
+// SetBreakpoints and Pause may be called from other goroutines.
+type Debugger struct {
+	stopped func(stop *Stop) Resume
+
+	mu sync.Mutex
+	// breakpoints are the lines to stop at by file, and pause is set to stop
+	// at the next line.
+	breakpoints map[string]map[int]bool
+	pause       bool
+
+	// The rest belong to the goroutine running the program. resume is how
+	// it was last resumed, from a call depth of depth.
+	resume Resume
+	depth  int
+	// last is the last statement, at a call depth of lastDepth in file, so
+	// that a program stops only once per line unless it loops back.
+	last      *Token
+	lastDepth int
+	lastFile  string
+	// evaluating is set while a paused program evaluates an expression for
+	// the host, which must not stop.
+	evaluating bool
+}
+
+func NewDebugger(stopped func(stop *Stop) Resume) *Debugger {
+	return &Debugger{
+		stopped:     stopped,
+		breakpoints: map[string]map[int]bool{},
+		resume:      Resume_CONTINUE,
+	}
+}
+
+// SetBreakpoint stops programs before they run line of file. file is the
+// path of a script or module, or empty for source passed to Run.
+func (d *Debugger) SetBreakpoint(file string, line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file = breakpointFile(file)
+	if d.breakpoints[file] == nil {
+		d.breakpoints[file] = map[int]bool{}
+	}
+	d.breakpoints[file][line] = true
+}
+
+// ClearBreakpoint removes the breakpoint at line of file. It reports
+// whether there was one.
+func (d *Debugger) ClearBreakpoint(file string, line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file = breakpointFile(file)
+	if !d.breakpoints[file][line] {
+		return false
+	}
+	delete(d.breakpoints[file], line)
+	return true
+}
+
+// SetBreakpoints replaces the breakpoints in file with lines.
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file = breakpointFile(file)
+	d.breakpoints[file] = map[int]bool{}
+	for _, line := range lines {
+		d.breakpoints[file][line] = true
+	}
+}
+
+// Breakpoints returns the lines with breakpoints by file, in order.
+func (d *Debugger) Breakpoints() map[string][]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	breakpoints := map[string][]int{}
+	for file, lines := range d.breakpoints {
+		for line := range lines {
+			breakpoints[file] = append(breakpoints[file], line)
+		}
+		sort.Ints(breakpoints[file])
+	}
+	return breakpoints
+}
+
+// Pause stops the program at the next line it runs.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pause = true
+}
+
+// breakpointFile makes the path of a breakpoint absolute, as the paths of
+// the modules being run are.
+func breakpointFile(file string) string {
+	if file == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
+
+// hook is called by the interpreter before it executes stmt, and stops the
+// program there if it should.
+func (d *Debugger) hook(itrp *Interpreter, stmt Stmt) {
+	// Blocks start on the line of the statement that holds them, or of the
+	// first statement in them.
+	if _, ok := stmt.(*Block); d.evaluating || ok {
+		return
+	}
+	token := stmtToken(stmt)
+	if token == nil {
+		return
+	}
+
+	file, depth := itrp.file(itrp.globals), len(itrp.calls)
+	last, sameLine := d.last, d.last != nil && file == d.lastFile && depth == d.lastDepth && token.line == d.last.line
+	d.last, d.lastDepth, d.lastFile = token, depth, file
+	if sameLine && token.offset > last.offset {
+		return
+	}
+
+	reason, ok := d.reason(file, token.line, depth)
+	if !ok {
+		return
+	}
+
+	d.resume = d.stopped(&Stop{Reason: reason, File: file, Line: token.line, itrp: itrp, debugger: d})
+	d.depth = depth
+	if d.resume == Resume_STOP {
+		panic(limitError(token, ErrStopped))
+	}
+}
+
+// reason returns why the program should stop at line of file, if it should.
+func (d *Debugger) reason(file string, line int, depth int) (StopReason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pause {
+		d.pause = false
+		return StopReason_PAUSE, true
+	}
+	if d.breakpoints[file][line] {
+		return StopReason_BREAKPOINT, true
+	}
+
+	switch d.resume {
+	case Resume_STEP_IN:
+		return StopReason_STEP, true
+	case Resume_STEP_OVER:
+		return StopReason_STEP, depth <= d.depth
+	case Resume_STEP_OUT:
+		return StopReason_STEP, depth < d.depth
+	}
+	return "", false
+}
+
+// Stop is a paused program. It is valid until the debugger's stopped
+// function returns.
+type Stop struct {
+	Reason StopReason
+	// File and Line are where the program stopped, before running the line.
+	// File is empty for source passed to Run.
+	File string
+	Line int
+
+	itrp     *Interpreter
+	debugger *Debugger
+}
+
+// Frame is a function that is running, or the top level of the script or
+// of a module being imported.
+type Frame struct {
+	// Function is the name of the function, or "" for a top level.
+	Function string
+	File     string
+	// Line is the line the frame had reached.
+	Line int
+
+	env     *Environment
+	globals *Environment
+}
+
+func (f Frame) String() string {
+	return StackFrame{function: f.Function}.String()
+}
+
+// Scope is an environment in which a frame looks up variables.
+type Scope struct {
+	// Name is "Locals" for the innermost scope and "Globals" for the
+	// outermost. Between them are the scopes of enclosing blocks and
+	// closures, named "Enclosing", the scope that binds `this` in methods and
+	// the one that binds `super` in subclass methods.
+	Name      string
+	Variables []Binding
+
+	env *Environment
+}
+
+// Binding is a name bound to a value: a variable, a field, or an element or
+// entry of a list or map.
+type Binding struct {
+	Name  string
+	Value any
+}
+
+// Frames returns the call stack, innermost frame first.
+func (s *Stop) Frames() []Frame {
+	itrp := s.itrp
+
+	frames := []Frame{}
+	file, line := s.File, s.Line
+	env, globals := itrp.env, itrp.globals
+	for i := len(itrp.calls) - 1; i >= 0; i-- {
+		frames = append(frames, Frame{Function: itrp.calls[i].function, File: file, Line: line, env: env, globals: globals})
+
+		env, globals = itrp.calls[i].env, itrp.calls[i].globals
+		file, line = itrp.file(globals), itrp.calls[i].line
+	}
+	return append(frames, Frame{Function: "", File: file, Line: line, env: env, globals: globals})
+}
+
+// Scopes returns the scopes of frame, which counts from the innermost
+// frame, innermost scope first.
+func (s *Stop) Scopes(frame int) ([]Scope, error) {
+	f, err := s.frame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []Scope{}
+	for env := f.env; env != nil; env = env.enclosing {
+		name := "Enclosing"
+		values := env.bindings()
+		_, this := values["this"]
+		_, super := values["super"]
+		switch {
+		case env.enclosing == nil:
+			name = "Globals"
+		case env == f.env:
+			name = "Locals"
+		case this && len(values) == 1:
+			name = "this"
+		case super && len(values) == 1:
+			name = "super"
+		}
+		scopes = append(scopes, Scope{Name: name, Variables: variables(values), env: env})
+	}
+	return scopes, nil
+}
+
+// SetVariable assigns the value of the expression source, evaluated in
+// frame, to the variable called name in the scope at index scope of the
+// frame's scopes. It returns the value.
+func (s *Stop) SetVariable(frame int, scope int, name string, source string) (any, error) {
+	scopes, err := s.Scopes(frame)
+	if err != nil {
+		return nil, err
+	}
+	if scope < 0 || scope >= len(scopes) {
+		return nil, fmt.Errorf("no scope %d", scope)
+	}
+	env := scopes[scope].env
+	slot, ok := env.slot(name)
+	if !ok {
+		return nil, fmt.Errorf("no variable '%s' in %s", name, scopes[scope].Name)
+	}
+
+	value, err := s.Evaluate(frame, source)
+	if err != nil {
+		return nil, err
+	}
+	env.values[slot] = value
+	return value, nil
+}
+
+// Evaluate evaluates the expression source in frame, as if it appeared at
+// the line the frame has reached. Assignments change the program's
+// variables. Errors are returned without being reported.
+func (s *Stop) Evaluate(frame int, source string) (value any, err error) {
+	f, err := s.frame(frame)
+	if err != nil {
+		return nil, err
+	}
+	itrp, l := s.itrp, s.itrp.lox
+
+	// The program's own errors and reporting are left as they were.
+	stderr, diagnostics, errors, hadError := l.stderr, l.diagnostics, l.errors, l.hadError
+	l.stderr, l.diagnostics = io.Discard, nil
+	defer func() {
+		l.stderr, l.diagnostics, l.errors, l.hadError = stderr, diagnostics, errors, hadError
+	}()
+
+	expr, err := l.parseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the expression in the frame's scopes, so that it reads the
+	// same variables the frame would.
+	resolver := NewResolver(l, itrp)
+	for env := f.env; env.enclosing != nil; env = env.enclosing {
+		local := newScope()
+		for slot, name := range env.names[:len(env.values)] {
+			local.add(name)
+			local.defined[slot] = true
+		}
+		resolver.scopes = append([]*scope{local}, resolver.scopes...)
+
+		if _, ok := local.slots["super"]; ok {
+			resolver.currentClass = ClassType_SUBCLASS
+		} else if _, ok := local.slots["this"]; ok && resolver.currentClass == ClassType_NONE {
+			resolver.currentClass = ClassType_CLASS
+		}
+	}
+	resolver.resolveExpr(expr)
+	if l.hadError {
+		return nil, l.compileError()
+	}
+
+	env, globals, calls, callSite := itrp.env, itrp.globals, len(itrp.calls), itrp.callSite
+	s.debugger.evaluating = true
+	defer func() {
+		itrp.env, itrp.globals, itrp.calls, itrp.callSite = env, globals, itrp.calls[:calls], callSite
+		s.debugger.evaluating = false
+
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				err = l.internalError(r)
+				return
+			}
+			// A limit reached while evaluating stops the program too.
+			if re.fatal {
+				panic(re)
+			}
+			err = re
+		}
+	}()
+
+	itrp.env, itrp.globals = f.env, f.globals
+	return itrp.evaluate(expr), nil
+}
+
+func (s *Stop) frame(frame int) (Frame, error) {
+	frames := s.Frames()
+	if frame < 0 || frame >= len(frames) {
+		return Frame{}, fmt.Errorf("no frame %d", frame)
+	}
+	return frames[frame], nil
+}
+
+// Members returns the fields of an instance, the elements of a list or the
+// entries of a map, for a debugger to show inside the value. Other values
+// have none.
+func Members(value any) []Binding {
+	switch v := value.(type) {
+	case *LoxInstance:
+		return variables(v.fields)
+	case *LoxList:
+		members := make([]Binding, len(v.elements))
+		for i, element := range v.elements {
+			members[i] = Binding{Name: strconv.Itoa(i), Value: element}
+		}
+		return members
+	case *LoxMap:
+		members := make([]Binding, len(v.entries))
+		for i, entry := range v.entries {
+			members[i] = Binding{Name: Inspect(entry.key), Value: entry.value}
+		}
+		return members
+	}
+	return nil
+}
+
+// Inspect formats value as a debugger shows it: as `print` does, but with
+// strings quoted.
+func Inspect(value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return stringify(value)
+}
+
+// variables lists values by name, in order.
+func variables(values map[string]any) []Binding {
+	vars := make([]Binding, 0, len(values))
+	for name, value := range values {
+		vars = append(vars, Binding{Name: name, Value: value})
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name < vars[j].Name
+	})
+	return vars
+}
@@ -0,0 +1,15 @@
+// Package lox implements the Lox language from "Crafting Interpreters" with
+// both a tree-walking interpreter and a bytecode VM, for embedding in Go
+// programs.
+//
+//	l := lox.New(lox.WithStdout(&out), lox.WithGlobals(map[string]any{
+//		"version": "1.0",
+//	}))
+//	if err := l.Run(`var x = 40; print version;`); err != nil {
+//		// *lox.CompileError or *lox.RuntimeError
+//	}
+//	v, err := l.Eval("x + 2") // 42.0
+//
+// Lox values are Go values: nil, bool, float64 and string, plus pointer types
+// from this package for functions, classes, instances, lists and maps.
+package lox
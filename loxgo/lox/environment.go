@@ -0,0 +1,159 @@
+package lox
+
+// Environment holds the variables of a scope in slots, numbered by the
+// resolver. A local scope's slots are defined in order, so values grows up to
+// the size of the scope as its declarations run. A global scope is indexed by
+// the slots of globals, which every global scope of an interpreter shares.
+type Environment struct {
+	values    []any
+	enclosing *Environment
+	// names names the slots of a local scope, for the debugger.
+	names   []string
+	globals *globalNames
+}
+
+// slotRef is where the resolver found a variable: in the given slot of the
+// scope depth scopes out from its use, or of the global scope if depth is -1.
+// resolved is set once the resolver has found it.
+type slotRef struct {
+	depth    int
+	slot     int
+	resolved bool
+}
+
+// globalNames gives each global name a slot.
+type globalNames struct {
+	slots map[string]int
+	names []string
+}
+
+func newGlobalNames() *globalNames {
+	return &globalNames{slots: map[string]int{}}
+}
+
+// slot returns the slot of name, giving it the next one if it has none.
+func (g *globalNames) slot(name string) int {
+	slot, ok := g.slots[name]
+	if !ok {
+		slot = len(g.names)
+		g.slots[name] = slot
+		g.names = append(g.names, name)
+	}
+	return slot
+}
+
+// undefined fills the slots of the names a global scope hasn't defined.
+var undefined any = &struct{ undefined bool }{}
+
+func newGlobalEnvironment(globals *globalNames) *Environment {
+	return &Environment{
+		values:  []any{},
+		globals: globals,
+	}
+}
+
+// NewEnvironmentFrom creates a local scope enclosed by env, with room for
+// the variables named by names.
+func NewEnvironmentFrom(env *Environment, names []string) *Environment {
+	return &Environment{
+		values:    make([]any, 0, len(names)),
+		enclosing: env,
+		names:     names,
+	}
+}
+
+// define defines the next slot of a local scope, or the slot of name in a
+// global one.
+func (e *Environment) define(name string, v any) {
+	if e.globals == nil {
+		e.values = append(e.values, v)
+		return
+	}
+
+	slot := e.globals.slot(name)
+	for len(e.values) <= slot {
+		e.values = append(e.values, undefined)
+	}
+	e.values[slot] = v
+}
+
+// slot returns the slot of the variable called name if the scope has defined
+// it.
+func (e *Environment) slot(name string) (int, bool) {
+	if e.globals != nil {
+		slot, ok := e.globals.slots[name]
+		if !ok || slot >= len(e.values) || e.values[slot] == undefined {
+			return 0, false
+		}
+		return slot, true
+	}
+
+	for slot, n := range e.names[:len(e.values)] {
+		if n == name {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// bindings returns the variables the scope has defined, by name.
+func (e *Environment) bindings() map[string]any {
+	bindings := map[string]any{}
+	for slot, v := range e.values {
+		if e.globals == nil {
+			bindings[e.names[slot]] = v
+		} else if v != undefined {
+			bindings[e.globals.names[slot]] = v
+		}
+	}
+	return bindings
+}
+
+// get reads a variable by name.
+func (e *Environment) get(name *Token) any {
+	slot, ok := e.slot(name.lexeme)
+	if !ok {
+		panic(NewRuntimeError(name, "Undefined variable '"+name.lexeme+"'."))
+	}
+	return e.values[slot]
+}
+
+// getGlobal reads the global called name, which has the given slot.
+func (e *Environment) getGlobal(name *Token, slot int) any {
+	if slot >= len(e.values) || e.values[slot] == undefined {
+		panic(NewRuntimeError(name, "Undefined variable '"+name.lexeme+"'."))
+	}
+	return e.values[slot]
+}
+
+// assignGlobal assigns to the global called name, which has the given slot.
+func (e *Environment) assignGlobal(name *Token, slot int, v any) {
+	if slot >= len(e.values) || e.values[slot] == undefined {
+		panic(NewRuntimeError(name, "Undefined variable '"+name.lexeme+"'."))
+	}
+	e.values[slot] = v
+}
+
+func (e *Environment) getAt(distance int, slot int) any {
+	return e.ancestor(distance).values[slot]
+}
+func (e *Environment) ancestor(distance int) *Environment {
+	var ret *Environment = e
+	for i := 0; i < distance; i++ {
+		ret = ret.enclosing
+	}
+	return ret
+}
+
+// root is the outermost enclosing environment, the global scope of a module.
+func (e *Environment) root() *Environment {
+	ret := e
+	for ret.enclosing != nil {
+		ret = ret.enclosing
+	}
+	return ret
+}
+
+func (e *Environment) assignAt(distance int, slot int, value any) {
+	e.ancestor(distance).values[slot] = value
+}
@@ -0,0 +1,225 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrStepLimit is the cause of the runtime error raised when a run
+	// executes more steps than WithMaxSteps allows.
+	ErrStepLimit = errors.New("lox: step limit exceeded")
+	// ErrStackOverflow is the cause of the runtime error raised when Lox
+	// calls nest deeper than WithMaxCallDepth allows.
+	ErrStackOverflow = errors.New("lox: stack overflow")
+	// ErrStopped is the cause of the runtime error raised when a debugger
+	// stops the program it has paused.
+	ErrStopped = errors.New("lox: stopped by the debugger")
+)
+
+// RuntimeError is a Lox runtime error raised at token, either by the runtime
+// or by a `throw` statement. trace is filled in as the error leaves the
+// innermost Lox function, while the call stack that raised it is still intact.
+type RuntimeError struct {
+	token   *Token
+	message string
+	trace   []StackFrame
+	// value is the thrown Lox value; thrown is false for errors raised by
+	// the runtime, which are turned into Error instances when caught.
+	value  any
+	thrown bool
+	// cause is the limit that stopped the run, if any. fatal errors can't
+	// be caught by Lox code and skip `finally` blocks, so that a script
+	// can't keep running once it has been stopped.
+	cause error
+	fatal bool
+}
+
+func NewRuntimeError(token *Token, message string) *RuntimeError {
+	return &RuntimeError{token: token, message: message}
+}
+
+func NewThrow(token *Token, value any) *RuntimeError {
+	return &RuntimeError{token: token, message: thrownMessage(value), value: value, thrown: true}
+}
+
+// limitError is the error raised when a run reaches a limit: cause is one of
+// ErrStepLimit, ErrStackOverflow and ErrStopped or the error of a cancelled
+// context. Only a stack overflow can be caught.
+func limitError(token *Token, cause error) *RuntimeError {
+	var message string
+	switch cause {
+	case ErrStackOverflow:
+		message = "Stack overflow."
+	case ErrStepLimit:
+		message = "Step limit exceeded."
+	case ErrStopped:
+		message = "Stopped by the debugger."
+	default:
+		message = "Run cancelled: " + cause.Error() + "."
+	}
+	return &RuntimeError{token: token, message: message, cause: cause, fatal: cause != ErrStackOverflow}
+}
+
+// The classes of the values that runtime errors become when caught. They
+// have `message` and `line` fields.
+var (
+	errorClass    = NewLoxClass("Error", nil, map[string]*LoxFunction{})
+	objErrorClass = NewObjClass("Error")
+)
+
+// thrownMessage is the message reported when value is thrown and never
+// caught. Rethrowing a caught runtime error keeps its original message.
+func thrownMessage(value any) string {
+	switch v := value.(type) {
+	case *LoxInstance:
+		if v.class == errorClass {
+			return stringify(v.fields["message"])
+		}
+	case *ObjInstance:
+		if v.class == objErrorClass {
+			return stringify(v.fields["message"])
+		}
+	}
+	return stringify(value)
+}
+
+// line is the line the error was raised at.
+func (e *RuntimeError) line() int {
+	if len(e.trace) > 0 {
+		return e.trace[0].line
+	}
+	if e.token != nil {
+		return e.token.line
+	}
+	return 0
+}
+
+func (e *RuntimeError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the limit that stopped the run, such as ErrStepLimit or
+// context.DeadlineExceeded, or nil.
+func (e *RuntimeError) Unwrap() error {
+	return e.cause
+}
+
+// Message is the error message without the line or call stack.
+func (e *RuntimeError) Message() string {
+	return e.message
+}
+
+// Line is the line the error was raised at.
+func (e *RuntimeError) Line() int {
+	return e.line()
+}
+
+// Trace is the Lox call stack when the error was raised, innermost frame
+// first.
+func (e *RuntimeError) Trace() []StackFrame {
+	return e.trace
+}
+
+// Value is the Lox value passed to `throw`, or nil for errors raised by the
+// runtime.
+func (e *RuntimeError) Value() any {
+	return e.value
+}
+
+// Traceback formats the message and call stack the way the loxgo command
+// reports them. When the stack runs through more than one file, as it does
+// across imports, each frame names its file. A run of identical frames, as
+// a runaway recursion leaves, is printed once with the number of times it
+// repeats.
+func (e *RuntimeError) Traceback() string {
+	files := false
+	for _, frame := range e.trace {
+		files = files || frame.file != e.trace[0].file
+	}
+
+	var sb strings.Builder
+	sb.WriteString(e.message + "\n")
+	for i := 0; i < len(e.trace); {
+		frame := e.trace[i]
+		where := frame.String()
+		if files {
+			where += " (" + displayPath(frame.file) + ")"
+		}
+		fmt.Fprintf(&sb, "[line %d] in %s\n", frame.line, where)
+
+		repeats := 0
+		for i++; i < len(e.trace) && e.trace[i] == frame; i++ {
+			repeats++
+		}
+		switch {
+		case repeats == 1:
+			fmt.Fprintf(&sb, "[line %d] in %s\n", frame.line, where)
+		case repeats > 1:
+			fmt.Fprintf(&sb, "[previous line repeated %d more times]\n", repeats)
+		}
+	}
+	return sb.String()
+}
+
+// SyntaxError is an error found before a program runs, while scanning,
+// parsing, resolving or compiling it.
+type SyntaxError struct {
+	Line int
+	// Where is the offending token, such as " at 'x'", or empty.
+	Where   string
+	Message string
+	// token is the offending token, which locates the error for Analyze.
+	token *Token
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.Line, e.Where, e.Message)
+}
+
+// CompileError is returned when a program has syntax errors. Nothing in the
+// program has run.
+type CompileError struct {
+	Errors []*SyntaxError
+}
+
+func (e *CompileError) Error() string {
+	ss := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		ss[i] = err.Error()
+	}
+	return strings.Join(ss, "\n")
+}
+
+// StackFrame is one entry of a Lox call stack: the function that was running,
+// the file it was declared in and the line it had reached. The top-level
+// script has an empty function name.
+type StackFrame struct {
+	function string
+	file     string
+	line     int
+}
+
+// Function is the name of the function, or "" for the top level.
+func (f StackFrame) Function() string {
+	return f.function
+}
+
+// File is the path of the script or module the frame's code is in, or "" for
+// source run by Run.
+func (f StackFrame) File() string {
+	return f.file
+}
+
+// Line is the line the frame had reached.
+func (f StackFrame) Line() int {
+	return f.line
+}
+
+func (f StackFrame) String() string {
+	if f.function == "" {
+		return "script"
+	}
+	return f.function + "()"
+}
@@ -0,0 +1,871 @@
+// DO NOT EDIT - generated code!
+package lox
+
+import "encoding/json"
+
+// Expr is implemented by every expression node of the syntax tree.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+type Binary struct {
+	Left     Expr
+	Operator *Token
+	Right    Expr
+}
+
+func (e *Binary) Pos() Position { return e.Left.Pos() }
+func (*Binary) exprNode()       {}
+
+type Grouping struct {
+	Paren      *Token
+	Expression Expr
+}
+
+func (e *Grouping) Pos() Position { return e.Paren.pos() }
+func (*Grouping) exprNode()       {}
+
+type Call struct {
+	Callee    Expr
+	Paren     *Token
+	Arguments []Expr
+}
+
+func (e *Call) Pos() Position { return e.Callee.Pos() }
+func (*Call) exprNode()       {}
+
+type Get struct {
+	Object Expr
+	Name   *Token
+}
+
+func (e *Get) Pos() Position { return e.Object.Pos() }
+func (*Get) exprNode()       {}
+
+type Set struct {
+	Object Expr
+	Name   *Token
+	Value  Expr
+}
+
+func (e *Set) Pos() Position { return e.Object.Pos() }
+func (*Set) exprNode()       {}
+
+type Literal struct {
+	Value any
+	Token *Token
+}
+
+func (e *Literal) Pos() Position { return e.Token.pos() }
+func (*Literal) exprNode()       {}
+
+type Unary struct {
+	Operator *Token
+	Right    Expr
+}
+
+func (e *Unary) Pos() Position { return e.Operator.pos() }
+func (*Unary) exprNode()       {}
+
+type This struct {
+	Keyword *Token
+	ref     slotRef
+}
+
+func (e *This) Pos() Position { return e.Keyword.pos() }
+func (*This) exprNode()       {}
+
+type Super struct {
+	Keyword *Token
+	Method  *Token
+	ref     slotRef
+}
+
+func (e *Super) Pos() Position { return e.Keyword.pos() }
+func (*Super) exprNode()       {}
+
+type Logical struct {
+	Left     Expr
+	Operator *Token
+	Right    Expr
+}
+
+func (e *Logical) Pos() Position { return e.Left.Pos() }
+func (*Logical) exprNode()       {}
+
+type Variable struct {
+	Name *Token
+	ref  slotRef
+}
+
+func (e *Variable) Pos() Position { return e.Name.pos() }
+func (*Variable) exprNode()       {}
+
+type Assign struct {
+	Name  *Token
+	Value Expr
+	ref   slotRef
+}
+
+func (e *Assign) Pos() Position { return e.Name.pos() }
+func (*Assign) exprNode()       {}
+
+type Interpolation struct {
+	Start *Token
+	Parts []Expr
+}
+
+func (e *Interpolation) Pos() Position { return e.Start.pos() }
+func (*Interpolation) exprNode()       {}
+
+type Lambda struct {
+	Function *Function
+}
+
+func (e *Lambda) Pos() Position { return e.Function.Pos() }
+func (*Lambda) exprNode()       {}
+
+type List struct {
+	Bracket  *Token
+	Elements []Expr
+}
+
+func (e *List) Pos() Position { return e.Bracket.pos() }
+func (*List) exprNode()       {}
+
+type Map struct {
+	Brace  *Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (e *Map) Pos() Position { return e.Brace.pos() }
+func (*Map) exprNode()       {}
+
+type Index struct {
+	Object  Expr
+	Bracket *Token
+	Index   Expr
+}
+
+func (e *Index) Pos() Position { return e.Object.Pos() }
+func (*Index) exprNode()       {}
+
+type SetIndex struct {
+	Object  Expr
+	Bracket *Token
+	Index   Expr
+	Value   Expr
+}
+
+func (e *SetIndex) Pos() Position { return e.Object.Pos() }
+func (*SetIndex) exprNode()       {}
+
+// VisitorExpr has a method for each type of expression, returning an R.
+type VisitorExpr[R any] interface {
+	VisitBinary(expr *Binary) R
+	VisitGrouping(expr *Grouping) R
+	VisitCall(expr *Call) R
+	VisitGet(expr *Get) R
+	VisitSet(expr *Set) R
+	VisitLiteral(expr *Literal) R
+	VisitUnary(expr *Unary) R
+	VisitThis(expr *This) R
+	VisitSuper(expr *Super) R
+	VisitLogical(expr *Logical) R
+	VisitVariable(expr *Variable) R
+	VisitAssign(expr *Assign) R
+	VisitInterpolation(expr *Interpolation) R
+	VisitLambda(expr *Lambda) R
+	VisitList(expr *List) R
+	VisitMap(expr *Map) R
+	VisitIndex(expr *Index) R
+	VisitSetIndex(expr *SetIndex) R
+}
+
+// acceptExpr calls the method of v for the type of expr.
+func acceptExpr[R any](expr Expr, v VisitorExpr[R]) R {
+	switch e := expr.(type) {
+	case *Binary:
+		return v.VisitBinary(e)
+	case *Grouping:
+		return v.VisitGrouping(e)
+	case *Call:
+		return v.VisitCall(e)
+	case *Get:
+		return v.VisitGet(e)
+	case *Set:
+		return v.VisitSet(e)
+	case *Literal:
+		return v.VisitLiteral(e)
+	case *Unary:
+		return v.VisitUnary(e)
+	case *This:
+		return v.VisitThis(e)
+	case *Super:
+		return v.VisitSuper(e)
+	case *Logical:
+		return v.VisitLogical(e)
+	case *Variable:
+		return v.VisitVariable(e)
+	case *Assign:
+		return v.VisitAssign(e)
+	case *Interpolation:
+		return v.VisitInterpolation(e)
+	case *Lambda:
+		return v.VisitLambda(e)
+	case *List:
+		return v.VisitList(e)
+	case *Map:
+		return v.VisitMap(e)
+	case *Index:
+		return v.VisitIndex(e)
+	case *SetIndex:
+		return v.VisitSetIndex(e)
+	}
+	panic("lox: unknown expr")
+}
+
+// CloneExpr returns a deep copy of expr, as the Clone method of its type does.
+func CloneExpr(expr Expr) Expr {
+	switch e := expr.(type) {
+	case *Binary:
+		return e.Clone()
+	case *Grouping:
+		return e.Clone()
+	case *Call:
+		return e.Clone()
+	case *Get:
+		return e.Clone()
+	case *Set:
+		return e.Clone()
+	case *Literal:
+		return e.Clone()
+	case *Unary:
+		return e.Clone()
+	case *This:
+		return e.Clone()
+	case *Super:
+		return e.Clone()
+	case *Logical:
+		return e.Clone()
+	case *Variable:
+		return e.Clone()
+	case *Assign:
+		return e.Clone()
+	case *Interpolation:
+		return e.Clone()
+	case *Lambda:
+		return e.Clone()
+	case *List:
+		return e.Clone()
+	case *Map:
+		return e.Clone()
+	case *Index:
+		return e.Clone()
+	case *SetIndex:
+		return e.Clone()
+	}
+	return nil
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Binary) Clone() *Binary {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Left = CloneExpr(e.Left)
+	c.Right = CloneExpr(e.Right)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Grouping) Clone() *Grouping {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Expression = CloneExpr(e.Expression)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Call) Clone() *Call {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Callee = CloneExpr(e.Callee)
+	c.Arguments = cloneList(e.Arguments, CloneExpr)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Get) Clone() *Get {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Object = CloneExpr(e.Object)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Set) Clone() *Set {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Object = CloneExpr(e.Object)
+	c.Value = CloneExpr(e.Value)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Literal) Clone() *Literal {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Unary) Clone() *Unary {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Right = CloneExpr(e.Right)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *This) Clone() *This {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Super) Clone() *Super {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Logical) Clone() *Logical {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Left = CloneExpr(e.Left)
+	c.Right = CloneExpr(e.Right)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Variable) Clone() *Variable {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Assign) Clone() *Assign {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Value = CloneExpr(e.Value)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Interpolation) Clone() *Interpolation {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Parts = cloneList(e.Parts, CloneExpr)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Lambda) Clone() *Lambda {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Function = e.Function.Clone()
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *List) Clone() *List {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Elements = cloneList(e.Elements, CloneExpr)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Map) Clone() *Map {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Keys = cloneList(e.Keys, CloneExpr)
+	c.Values = cloneList(e.Values, CloneExpr)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Index) Clone() *Index {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Object = CloneExpr(e.Object)
+	c.Index = CloneExpr(e.Index)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *SetIndex) Clone() *SetIndex {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Object = CloneExpr(e.Object)
+	c.Index = CloneExpr(e.Index)
+	c.Value = CloneExpr(e.Value)
+	return &c
+}
+
+func equalExpr(expr Expr, other Node) bool {
+	switch e := expr.(type) {
+	case *Binary:
+		o, ok := other.(*Binary)
+		return ok && e.equal(o)
+	case *Grouping:
+		o, ok := other.(*Grouping)
+		return ok && e.equal(o)
+	case *Call:
+		o, ok := other.(*Call)
+		return ok && e.equal(o)
+	case *Get:
+		o, ok := other.(*Get)
+		return ok && e.equal(o)
+	case *Set:
+		o, ok := other.(*Set)
+		return ok && e.equal(o)
+	case *Literal:
+		o, ok := other.(*Literal)
+		return ok && e.equal(o)
+	case *Unary:
+		o, ok := other.(*Unary)
+		return ok && e.equal(o)
+	case *This:
+		o, ok := other.(*This)
+		return ok && e.equal(o)
+	case *Super:
+		o, ok := other.(*Super)
+		return ok && e.equal(o)
+	case *Logical:
+		o, ok := other.(*Logical)
+		return ok && e.equal(o)
+	case *Variable:
+		o, ok := other.(*Variable)
+		return ok && e.equal(o)
+	case *Assign:
+		o, ok := other.(*Assign)
+		return ok && e.equal(o)
+	case *Interpolation:
+		o, ok := other.(*Interpolation)
+		return ok && e.equal(o)
+	case *Lambda:
+		o, ok := other.(*Lambda)
+		return ok && e.equal(o)
+	case *List:
+		o, ok := other.(*List)
+		return ok && e.equal(o)
+	case *Map:
+		o, ok := other.(*Map)
+		return ok && e.equal(o)
+	case *Index:
+		o, ok := other.(*Index)
+		return ok && e.equal(o)
+	case *SetIndex:
+		o, ok := other.(*SetIndex)
+		return ok && e.equal(o)
+	}
+	return false
+}
+
+func (e *Binary) equal(o *Binary) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Left, o.Left) &&
+		equalToken(e.Operator, o.Operator) &&
+		Equal(e.Right, o.Right)
+}
+
+func (e *Grouping) equal(o *Grouping) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Paren, o.Paren) &&
+		Equal(e.Expression, o.Expression)
+}
+
+func (e *Call) equal(o *Call) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Callee, o.Callee) &&
+		equalToken(e.Paren, o.Paren) &&
+		equalList(e.Arguments, o.Arguments, equalNode[Expr])
+}
+
+func (e *Get) equal(o *Get) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Object, o.Object) &&
+		equalToken(e.Name, o.Name)
+}
+
+func (e *Set) equal(o *Set) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Object, o.Object) &&
+		equalToken(e.Name, o.Name) &&
+		Equal(e.Value, o.Value)
+}
+
+func (e *Literal) equal(o *Literal) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return e.Value == o.Value &&
+		equalToken(e.Token, o.Token)
+}
+
+func (e *Unary) equal(o *Unary) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Operator, o.Operator) &&
+		Equal(e.Right, o.Right)
+}
+
+func (e *This) equal(o *This) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword)
+}
+
+func (e *Super) equal(o *Super) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		equalToken(e.Method, o.Method)
+}
+
+func (e *Logical) equal(o *Logical) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Left, o.Left) &&
+		equalToken(e.Operator, o.Operator) &&
+		Equal(e.Right, o.Right)
+}
+
+func (e *Variable) equal(o *Variable) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Name, o.Name)
+}
+
+func (e *Assign) equal(o *Assign) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Name, o.Name) &&
+		Equal(e.Value, o.Value)
+}
+
+func (e *Interpolation) equal(o *Interpolation) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Start, o.Start) &&
+		equalList(e.Parts, o.Parts, equalNode[Expr])
+}
+
+func (e *Lambda) equal(o *Lambda) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Function, o.Function)
+}
+
+func (e *List) equal(o *List) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Bracket, o.Bracket) &&
+		equalList(e.Elements, o.Elements, equalNode[Expr])
+}
+
+func (e *Map) equal(o *Map) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Brace, o.Brace) &&
+		equalList(e.Keys, o.Keys, equalNode[Expr]) &&
+		equalList(e.Values, o.Values, equalNode[Expr])
+}
+
+func (e *Index) equal(o *Index) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Object, o.Object) &&
+		equalToken(e.Bracket, o.Bracket) &&
+		Equal(e.Index, o.Index)
+}
+
+func (e *SetIndex) equal(o *SetIndex) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Object, o.Object) &&
+		equalToken(e.Bracket, o.Bracket) &&
+		Equal(e.Index, o.Index) &&
+		Equal(e.Value, o.Value)
+}
+
+// walkExpr walks the children of expr, in the order of their fields.
+func walkExpr(v Visitor, expr Expr) {
+	switch e := expr.(type) {
+	case *Binary:
+		if e.Left != nil {
+			Walk(v, e.Left)
+		}
+		if e.Right != nil {
+			Walk(v, e.Right)
+		}
+	case *Grouping:
+		if e.Expression != nil {
+			Walk(v, e.Expression)
+		}
+	case *Call:
+		if e.Callee != nil {
+			Walk(v, e.Callee)
+		}
+		walkList(v, e.Arguments)
+	case *Get:
+		if e.Object != nil {
+			Walk(v, e.Object)
+		}
+	case *Set:
+		if e.Object != nil {
+			Walk(v, e.Object)
+		}
+		if e.Value != nil {
+			Walk(v, e.Value)
+		}
+	case *Unary:
+		if e.Right != nil {
+			Walk(v, e.Right)
+		}
+	case *Logical:
+		if e.Left != nil {
+			Walk(v, e.Left)
+		}
+		if e.Right != nil {
+			Walk(v, e.Right)
+		}
+	case *Assign:
+		if e.Value != nil {
+			Walk(v, e.Value)
+		}
+	case *Interpolation:
+		walkList(v, e.Parts)
+	case *Lambda:
+		if e.Function != nil {
+			Walk(v, e.Function)
+		}
+	case *List:
+		walkList(v, e.Elements)
+	case *Map:
+		walkList(v, e.Keys)
+		walkList(v, e.Values)
+	case *Index:
+		if e.Object != nil {
+			Walk(v, e.Object)
+		}
+		if e.Index != nil {
+			Walk(v, e.Index)
+		}
+	case *SetIndex:
+		if e.Object != nil {
+			Walk(v, e.Object)
+		}
+		if e.Index != nil {
+			Walk(v, e.Index)
+		}
+		if e.Value != nil {
+			Walk(v, e.Value)
+		}
+	}
+}
+
+func (e *Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Binary", map[string]any{
+		"left":     e.Left,
+		"operator": tokenJSON(e.Operator),
+		"right":    e.Right,
+	}))
+}
+
+func (e *Grouping) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Grouping", map[string]any{
+		"paren":      tokenJSON(e.Paren),
+		"expression": e.Expression,
+	}))
+}
+
+func (e *Call) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Call", map[string]any{
+		"callee":    e.Callee,
+		"paren":     tokenJSON(e.Paren),
+		"arguments": e.Arguments,
+	}))
+}
+
+func (e *Get) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Get", map[string]any{
+		"object": e.Object,
+		"name":   tokenJSON(e.Name),
+	}))
+}
+
+func (e *Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Set", map[string]any{
+		"object": e.Object,
+		"name":   tokenJSON(e.Name),
+		"value":  e.Value,
+	}))
+}
+
+func (e *Literal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Literal", map[string]any{
+		"value": e.Value,
+		"token": tokenJSON(e.Token),
+	}))
+}
+
+func (e *Unary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Unary", map[string]any{
+		"operator": tokenJSON(e.Operator),
+		"right":    e.Right,
+	}))
+}
+
+func (e *This) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "This", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+	}))
+}
+
+func (e *Super) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Super", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+		"method":  tokenJSON(e.Method),
+	}))
+}
+
+func (e *Logical) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Logical", map[string]any{
+		"left":     e.Left,
+		"operator": tokenJSON(e.Operator),
+		"right":    e.Right,
+	}))
+}
+
+func (e *Variable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Variable", map[string]any{
+		"name": tokenJSON(e.Name),
+	}))
+}
+
+func (e *Assign) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Assign", map[string]any{
+		"name":  tokenJSON(e.Name),
+		"value": e.Value,
+	}))
+}
+
+func (e *Interpolation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Interpolation", map[string]any{
+		"start": tokenJSON(e.Start),
+		"parts": e.Parts,
+	}))
+}
+
+func (e *Lambda) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Lambda", map[string]any{
+		"function": e.Function,
+	}))
+}
+
+func (e *List) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "List", map[string]any{
+		"bracket":  tokenJSON(e.Bracket),
+		"elements": e.Elements,
+	}))
+}
+
+func (e *Map) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Map", map[string]any{
+		"brace":  tokenJSON(e.Brace),
+		"keys":   e.Keys,
+		"values": e.Values,
+	}))
+}
+
+func (e *Index) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Index", map[string]any{
+		"object":  e.Object,
+		"bracket": tokenJSON(e.Bracket),
+		"index":   e.Index,
+	}))
+}
+
+func (e *SetIndex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "SetIndex", map[string]any{
+		"object":  e.Object,
+		"bracket": tokenJSON(e.Bracket),
+		"index":   e.Index,
+		"value":   e.Value,
+	}))
+}
@@ -0,0 +1,626 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format formats source in the one style loxgo uses: two-space indentation,
+// opening braces on the line they belong to and spaces around binary
+// operators. Comments and single blank lines between statements are kept,
+// and list and map literals broken across lines in the source stay that
+// way, one element per line. Formatting formatted source changes nothing.
+//
+// Source with syntax errors isn't formatted; they are returned as a
+// *CompileError.
+func Format(source string) (string, error) {
+	l := New()
+	tokens := NewScanner(l, source).scanTokens()
+	statements := NewParser(l, tokens).parse()
+	if l.hadError {
+		return "", l.compileError()
+	}
+
+	f := &formatter{tokens: tokens, lineStart: true, commented: -1}
+	return f.format(statements)
+}
+
+// formatter prints a parsed program. It walks the AST and the tokens it was
+// parsed from together: each token the AST stands for is consumed from
+// tokens in order as it is printed, which places the comments attached to
+// it, and tells apart syntax that parses to the same tree, such as a `for`
+// loop and the `while` loop it is desugared to.
+type formatter struct {
+	tokens  []*Token
+	current int
+	out     strings.Builder
+	indent  int
+	// lineStart is set until something is written on the current line.
+	lineStart bool
+	// trailing holds the comments to write at the end of the current line.
+	trailing []string
+	// last is the last token consumed, and commented the index of the token
+	// whose leading comments have been written ahead of it.
+	last      *Token
+	commented int
+}
+
+// formatError is raised when the tokens don't match the tree, which is a bug
+// in the formatter.
+type formatError struct {
+	message string
+}
+
+func (f *formatter) format(statements []Stmt) (ret string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fe, ok := r.(*formatError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("format: %s", fe.message)
+		}
+	}()
+
+	for i, stmt := range statements {
+		f.startLine(i > 0)
+		f.stmt(stmt)
+	}
+	if eof := f.peek(); len(eof.leading) > 0 {
+		f.startLine(len(statements) > 0)
+	}
+	f.newline()
+	return f.out.String(), nil
+}
+
+func (f *formatter) stmt(stmt Stmt) {
+	switch stmt := stmt.(type) {
+	case *Expression:
+		f.expr(stmt.Expression)
+		f.token(TokenType_SEMICOLON)
+	case *Print:
+		f.token(TokenType_PRINT)
+		f.space()
+		f.expr(stmt.Expression)
+		f.token(TokenType_SEMICOLON)
+	case *Var:
+		f.varDeclaration(stmt)
+	case *Function:
+		f.token(TokenType_FUN)
+		f.space()
+		f.function(stmt)
+	case *Return:
+		f.token(TokenType_RETURN)
+		if stmt.Value != nil {
+			f.space()
+			f.expr(stmt.Value)
+		}
+		f.token(TokenType_SEMICOLON)
+	case *If:
+		f.ifStatement(stmt)
+	case *While:
+		if f.check(TokenType_FOR) {
+			f.forStatement(nil, stmt)
+			break
+		}
+		f.token(TokenType_WHILE)
+		f.space()
+		f.token(TokenType_LEFT_PAREN)
+		f.expr(stmt.Condition)
+		f.token(TokenType_RIGHT_PAREN)
+		f.body(stmt.Body)
+	case *Block:
+		// A `for` loop with an initializer is desugared to a block holding
+		// the initializer and a while loop.
+		if f.check(TokenType_FOR) {
+			f.forStatement(stmt.Statements[0], stmt.Statements[1].(*While))
+			break
+		}
+		f.block(stmt.Statements)
+	case *Break:
+		f.token(TokenType_BREAK)
+		f.token(TokenType_SEMICOLON)
+	case *Continue:
+		f.token(TokenType_CONTINUE)
+		f.token(TokenType_SEMICOLON)
+	case *Import:
+		f.importDeclaration(stmt)
+	case *Export:
+		f.token(TokenType_EXPORT)
+		f.space()
+		f.stmt(stmt.Declaration)
+	case *Throw:
+		f.token(TokenType_THROW)
+		f.space()
+		f.expr(stmt.Value)
+		f.token(TokenType_SEMICOLON)
+	case *Try:
+		f.tryStatement(stmt)
+	case *Class:
+		f.classDeclaration(stmt)
+	}
+}
+
+func (f *formatter) varDeclaration(stmt *Var) {
+	f.token(TokenType_VAR)
+	f.space()
+	f.token(TokenType_IDENTIFIER)
+	if stmt.Initializer != nil {
+		f.space()
+		f.token(TokenType_EQUAL)
+		f.space()
+		f.expr(stmt.Initializer)
+	}
+	f.token(TokenType_SEMICOLON)
+}
+
+// function prints a function or method from its name on.
+func (f *formatter) function(stmt *Function) {
+	f.token(TokenType_IDENTIFIER)
+	f.parameters(stmt.Params)
+	f.space()
+	f.block(stmt.Body)
+}
+
+func (f *formatter) parameters(params []*Token) {
+	f.token(TokenType_LEFT_PAREN)
+	for i := range params {
+		if i > 0 {
+			f.token(TokenType_COMMA)
+			f.space()
+		}
+		f.token(TokenType_IDENTIFIER)
+	}
+	f.token(TokenType_RIGHT_PAREN)
+}
+
+func (f *formatter) ifStatement(stmt *If) {
+	f.token(TokenType_IF)
+	f.space()
+	f.token(TokenType_LEFT_PAREN)
+	f.expr(stmt.Condition)
+	f.token(TokenType_RIGHT_PAREN)
+	f.body(stmt.Then)
+	if stmt.Else == nil {
+		return
+	}
+
+	if f.last.t == TokenType_RIGHT_BRACE && len(f.peek().leading) == 0 {
+		f.space()
+	} else {
+		f.startLine(false)
+	}
+	f.token(TokenType_ELSE)
+	if elseIf, ok := stmt.Else.(*If); ok {
+		f.space()
+		f.ifStatement(elseIf)
+		return
+	}
+	f.body(stmt.Else)
+}
+
+// forStatement prints the `for` loop that was desugared to initializer and
+// loop, its original syntax.
+func (f *formatter) forStatement(initializer Stmt, loop *While) {
+	f.token(TokenType_FOR)
+	f.space()
+	f.token(TokenType_LEFT_PAREN)
+	if initializer != nil {
+		f.stmt(initializer)
+	} else {
+		f.token(TokenType_SEMICOLON)
+	}
+	// A loop without a condition was given a `true` literal.
+	if !f.check(TokenType_SEMICOLON) {
+		f.space()
+		f.expr(loop.Condition)
+	}
+	f.token(TokenType_SEMICOLON)
+	if loop.Increment != nil {
+		f.space()
+		f.expr(loop.Increment)
+	}
+	f.token(TokenType_RIGHT_PAREN)
+	f.body(loop.Body)
+}
+
+// body prints the body of an if, while or for statement. A block starts on
+// the same line; another statement follows on it.
+func (f *formatter) body(stmt Stmt) {
+	f.space()
+	if block, ok := stmt.(*Block); ok && f.check(TokenType_LEFT_BRACE) {
+		f.block(block.Statements)
+		return
+	}
+	f.stmt(stmt)
+}
+
+func (f *formatter) importDeclaration(stmt *Import) {
+	f.token(TokenType_IMPORT)
+	f.space()
+	if len(stmt.Names) > 0 {
+		f.token(TokenType_LEFT_BRACE)
+		f.space()
+		for i := range stmt.Names {
+			if i > 0 {
+				f.token(TokenType_COMMA)
+				f.space()
+			}
+			f.token(TokenType_IDENTIFIER)
+		}
+		f.space()
+		f.token(TokenType_RIGHT_BRACE)
+		f.space()
+		f.token(TokenType_IDENTIFIER) // from
+		f.space()
+	}
+	f.token(TokenType_STRING)
+	if stmt.Alias != nil {
+		f.space()
+		f.token(TokenType_IDENTIFIER) // as
+		f.space()
+		f.token(TokenType_IDENTIFIER)
+	}
+	f.token(TokenType_SEMICOLON)
+}
+
+func (f *formatter) tryStatement(stmt *Try) {
+	f.token(TokenType_TRY)
+	f.space()
+	f.block(stmt.Body)
+	if f.check(TokenType_CATCH) {
+		f.space()
+		f.token(TokenType_CATCH)
+		f.space()
+		f.token(TokenType_LEFT_PAREN)
+		f.token(TokenType_IDENTIFIER)
+		f.token(TokenType_RIGHT_PAREN)
+		f.space()
+		f.block(stmt.Catch)
+	}
+	if f.check(TokenType_FINALLY) {
+		f.space()
+		f.token(TokenType_FINALLY)
+		f.space()
+		f.block(stmt.Finally)
+	}
+}
+
+func (f *formatter) classDeclaration(stmt *Class) {
+	f.token(TokenType_CLASS)
+	f.space()
+	f.token(TokenType_IDENTIFIER)
+	if stmt.SuperClass != nil {
+		f.space()
+		f.token(TokenType_LESS)
+		f.space()
+		f.token(TokenType_IDENTIFIER)
+	}
+	f.space()
+	f.braced(TokenType_LEFT_BRACE, TokenType_RIGHT_BRACE, len(stmt.Methods), func(i int) {
+		f.function(stmt.Methods[i])
+	})
+}
+
+func (f *formatter) block(statements []Stmt) {
+	f.braced(TokenType_LEFT_BRACE, TokenType_RIGHT_BRACE, len(statements), func(i int) {
+		f.stmt(statements[i])
+	})
+}
+
+// braced prints n items between open and close, each on its own line and
+// indented. Without items or comments, it prints the brackets together.
+func (f *formatter) braced(open TokenType, close TokenType, n int, item func(i int)) {
+	f.token(open)
+	if n == 0 && f.last.trailing == nil && len(f.peek().leading) == 0 {
+		f.token(close)
+		return
+	}
+
+	f.indent++
+	for i := 0; i < n; i++ {
+		f.startLine(i > 0)
+		item(i)
+	}
+	// Comments before the closing bracket belong inside.
+	if len(f.peek().leading) > 0 {
+		f.startLine(n > 0)
+	}
+	f.indent--
+	f.newline()
+	f.token(close)
+}
+
+func (f *formatter) expr(expr Expr) {
+	switch expr := expr.(type) {
+	case *Binary:
+		f.expr(expr.Left)
+		f.space()
+		f.token(expr.Operator.t)
+		f.space()
+		f.expr(expr.Right)
+	case *Logical:
+		f.expr(expr.Left)
+		f.space()
+		f.token(expr.Operator.t)
+		f.space()
+		f.expr(expr.Right)
+	case *Grouping:
+		f.token(TokenType_LEFT_PAREN)
+		f.expr(expr.Expression)
+		f.token(TokenType_RIGHT_PAREN)
+	case *Call:
+		f.expr(expr.Callee)
+		f.token(TokenType_LEFT_PAREN)
+		for i, argument := range expr.Arguments {
+			if i > 0 {
+				f.token(TokenType_COMMA)
+				f.space()
+			}
+			f.expr(argument)
+		}
+		f.token(TokenType_RIGHT_PAREN)
+	case *Get:
+		f.expr(expr.Object)
+		f.token(TokenType_DOT)
+		f.token(TokenType_IDENTIFIER)
+	case *Set:
+		f.expr(expr.Object)
+		f.token(TokenType_DOT)
+		f.token(TokenType_IDENTIFIER)
+		f.assignment(expr.Value)
+	case *Literal:
+		f.token(expr.Token.t)
+	case *Unary:
+		f.token(expr.Operator.t)
+		f.expr(expr.Right)
+	case *This:
+		f.token(TokenType_THIS)
+	case *Super:
+		f.token(TokenType_SUPER)
+		f.token(TokenType_DOT)
+		f.token(TokenType_IDENTIFIER)
+	case *Variable:
+		f.token(TokenType_IDENTIFIER)
+	case *Assign:
+		f.token(TokenType_IDENTIFIER)
+		f.assignment(expr.Value)
+	case *Interpolation:
+		// Parts alternate between the pieces of the string, which are
+		// printed as they were written, and the interpolated expressions.
+		for i, part := range expr.Parts {
+			if i%2 == 0 {
+				f.token(part.(*Literal).Token.t)
+			} else {
+				f.expr(part)
+			}
+		}
+	case *Lambda:
+		f.lambda(expr.Function)
+	case *List:
+		f.elements(TokenType_LEFT_BRACKET, TokenType_RIGHT_BRACKET, len(expr.Elements), func(i int) {
+			f.expr(expr.Elements[i])
+		})
+	case *Map:
+		f.elements(TokenType_LEFT_BRACE, TokenType_RIGHT_BRACE, len(expr.Keys), func(i int) {
+			f.expr(expr.Keys[i])
+			f.token(TokenType_COLON)
+			f.space()
+			f.expr(expr.Values[i])
+		})
+	case *Index:
+		f.expr(expr.Object)
+		f.token(TokenType_LEFT_BRACKET)
+		f.expr(expr.Index)
+		f.token(TokenType_RIGHT_BRACKET)
+	case *SetIndex:
+		f.expr(expr.Object)
+		f.token(TokenType_LEFT_BRACKET)
+		f.expr(expr.Index)
+		f.token(TokenType_RIGHT_BRACKET)
+		f.assignment(expr.Value)
+	}
+}
+
+func (f *formatter) assignment(value Expr) {
+	f.space()
+	f.token(TokenType_EQUAL)
+	f.space()
+	f.expr(value)
+}
+
+// lambda prints an anonymous function. The short form keeps its
+// parentheses only around zero or several parameters: `x => x + 1`,
+// `(a, b) => a + b`.
+func (f *formatter) lambda(function *Function) {
+	if f.check(TokenType_FUN) {
+		f.token(TokenType_FUN)
+		f.space()
+		f.parameters(function.Params)
+		f.space()
+		f.block(function.Body)
+		return
+	}
+
+	parens := len(function.Params) != 1
+	hadParens := f.check(TokenType_LEFT_PAREN)
+	if hadParens {
+		f.skip(TokenType_LEFT_PAREN)
+	}
+	if parens {
+		f.write("(")
+	}
+	for i := range function.Params {
+		if i > 0 {
+			f.token(TokenType_COMMA)
+			f.space()
+		}
+		f.token(TokenType_IDENTIFIER)
+	}
+	if hadParens {
+		f.skip(TokenType_RIGHT_PAREN)
+	}
+	if parens {
+		f.write(")")
+	}
+	f.space()
+	f.token(TokenType_ARROW)
+	f.space()
+	f.expr(function.Body[0].(*Return).Value)
+}
+
+// elements prints the n elements of a list or map literal. They are printed
+// on one line, unless the first one was on a line of its own in the source.
+func (f *formatter) elements(open TokenType, close TokenType, n int, element func(i int)) {
+	bracket := f.peek()
+	if n == 0 || f.tokens[f.current+1].line > bracket.line || bracket.trailing != nil {
+		f.braced(open, close, n, func(i int) {
+			element(i)
+			if f.check(TokenType_COMMA) {
+				f.token(TokenType_COMMA)
+			} else {
+				f.write(",")
+			}
+		})
+		return
+	}
+
+	f.token(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			f.token(TokenType_COMMA)
+			f.space()
+		}
+		element(i)
+	}
+	// Drop a trailing comma.
+	if f.check(TokenType_COMMA) {
+		f.skip(TokenType_COMMA)
+	}
+	f.token(close)
+}
+
+func (f *formatter) peek() *Token {
+	return f.tokens[f.current]
+}
+
+func (f *formatter) check(t TokenType) bool {
+	return f.peek().t == t
+}
+
+// token prints the next token, which must be of type t, with its comments.
+func (f *formatter) token(t TokenType) {
+	f.write(f.consume(t).lexeme)
+}
+
+// skip consumes the next token, which must be of type t, without printing
+// it. Its comments are still printed.
+func (f *formatter) skip(t TokenType) {
+	f.consume(t)
+}
+
+func (f *formatter) consume(t TokenType) *Token {
+	token := f.peek()
+	if token.t != t {
+		panic(&formatError{fmt.Sprintf("expected %s at line %d but found %s", t, token.line, token.t)})
+	}
+	f.leading(false)
+	if token.trailing != nil {
+		f.trailing = append(f.trailing, token.trailing.lexeme)
+	}
+	f.current++
+	f.last = token
+	return token
+}
+
+// startLine starts a line for the next statement or element, preceded by
+// its comments. A blank line before it in the source is kept if blank is
+// set; blank lines between its comments are always kept.
+func (f *formatter) startLine(blank bool) {
+	f.newline()
+	f.leading(blank)
+}
+
+// leading prints the comments before the next token. At the start of a
+// line, each goes on a line of its own. Elsewhere a comment can't be
+// followed by more code, so it is moved to the end of the line.
+func (f *formatter) leading(blank bool) {
+	token := f.peek()
+	if f.commented == f.current {
+		return
+	}
+	f.commented = f.current
+
+	if !f.lineStart {
+		for _, comment := range token.leading {
+			f.trailing = append(f.trailing, comment.lexeme)
+		}
+		return
+	}
+
+	previous := 0
+	if f.last != nil {
+		previous = f.last.line
+	}
+	for _, comment := range token.leading {
+		if blank && previous > 0 && comment.line > previous+1 {
+			f.blankLine()
+		}
+		f.write(comment.lexeme)
+		f.newline()
+		previous = comment.line
+		blank = true
+	}
+	if blank && previous > 0 && firstLine(token) > previous+1 && !closing(token) {
+		f.blankLine()
+	}
+}
+
+// closing reports whether token ends a bracketed list of items, which isn't
+// separated from the last one by a blank line.
+func closing(token *Token) bool {
+	switch token.t {
+	case TokenType_RIGHT_BRACE, TokenType_RIGHT_BRACKET, TokenType_EOF:
+		return true
+	}
+	return false
+}
+
+// firstLine returns the line token starts on. A token's line is the one it
+// ends on, which is further down for strings with newlines in them.
+func firstLine(token *Token) int {
+	return token.line - strings.Count(token.lexeme, "\n")
+}
+
+func (f *formatter) write(s string) {
+	if f.lineStart {
+		f.out.WriteString(strings.Repeat("  ", f.indent))
+		f.lineStart = false
+	}
+	f.out.WriteString(s)
+}
+
+func (f *formatter) space() {
+	f.write(" ")
+}
+
+// newline ends the current line, if anything has been written on it, with
+// the comments that trail it.
+func (f *formatter) newline() {
+	if f.lineStart {
+		return
+	}
+	if len(f.trailing) > 0 {
+		f.out.WriteString(" " + strings.Join(f.trailing, " "))
+		f.trailing = nil
+	}
+	f.out.WriteByte('\n')
+	f.lineStart = true
+}
+
+func (f *formatter) blankLine() {
+	f.newline()
+	if s := f.out.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		f.out.WriteByte('\n')
+	}
+}
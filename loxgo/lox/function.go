@@ -0,0 +1,60 @@
+package lox
+
+var _ Callable = (*LoxFunction)(nil)
+
+type LoxFunction struct {
+	decl    *Function
+	closure *Environment
+	// globals is the global scope of the module the function was declared
+	// in, where its unresolved names are looked up.
+	globals       *Environment
+	isInitializer bool
+}
+
+func NewLoxFunction(decl *Function, closure *Environment, isInitializer bool) *LoxFunction {
+	return &LoxFunction{
+		decl:          decl,
+		closure:       closure,
+		globals:       closure.root(),
+		isInitializer: isInitializer,
+	}
+}
+
+func (f *LoxFunction) Call(itrp *Interpreter, arguments []any) any {
+	env := NewEnvironmentFrom(f.closure, f.decl.locals)
+	env.values = append(env.values, arguments...)
+
+	// An error escaping the body leaves the call on the stack, for the
+	// traceback; the interpreter unwinds where the error is caught.
+	itrp.pushCall(f.decl.Name.lexeme)
+	globals := itrp.globals
+	itrp.globals = f.globals
+	completion := itrp.executeBlock(f.decl.Body, env)
+	itrp.globals = globals
+	itrp.popCall()
+
+	// An initializer returns `this`, whether it ends or returns early.
+	if f.isInitializer {
+		return f.closure.getAt(0, 0)
+	}
+	if completion == Completion_RETURN {
+		ret := itrp.returned
+		itrp.returned = nil
+		return ret
+	}
+	return nil
+}
+
+func (c *LoxFunction) Arity() int {
+	return len(c.decl.Params)
+}
+
+func (c *LoxFunction) String() string {
+	return "<fn " + c.decl.Name.lexeme + ">"
+}
+
+func (c *LoxFunction) bind(instance *LoxInstance) *LoxFunction {
+	env := NewEnvironmentFrom(c.closure, thisLocals)
+	env.define("this", instance)
+	return NewLoxFunction(c.decl, env, c.isInitializer)
+}
@@ -0,0 +1,826 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var _ = (VisitorExpr[any])(&Interpreter{})
+var _ = (VisitorStmt[Completion])(&Interpreter{})
+
+type Interpreter struct {
+	lox     *Lox
+	env     *Environment
+	globals *Environment
+	// globalNames gives the globals of every module their slots.
+	globalNames *globalNames
+	// calls holds the Lox functions currently executing, each with the line
+	// it was called from.
+	calls []call
+	// files maps the global scope of each imported module to its path, for
+	// the debugger.
+	files map[*Environment]string
+	// returned is the value of the return statement being completed.
+	returned any
+	// callSite is the closing paren of the call being made, read by Callable
+	// implementations that push a stack frame.
+	callSite *Token
+}
+
+func NewInterpreter(lox *Lox) *Interpreter {
+	itrp := &Interpreter{
+		lox:         lox,
+		globalNames: newGlobalNames(),
+		files:       map[*Environment]string{},
+	}
+	itrp.globals = itrp.newGlobals()
+	itrp.env = itrp.globals
+	return itrp
+}
+
+// newGlobals creates the global scope of a module, holding the natives and
+// the globals defined by the host.
+func (itrp *Interpreter) newGlobals() *Environment {
+	globals := newGlobalEnvironment(itrp.globalNames)
+
+	for name, value := range itrp.lox.globals {
+		globals.define(name, value)
+	}
+
+	return globals
+}
+
+func (itrp *Interpreter) interpret(stmts []Stmt) (err error) {
+	defer itrp.recoverError(&err, itrp.mark())
+
+	for _, stmt := range stmts {
+		itrp.execute(stmt)
+	}
+	return err
+}
+
+// eval evaluates a single expression in the global scope.
+func (itrp *Interpreter) eval(expr Expr) (value any, err error) {
+	defer itrp.recoverError(&err, itrp.mark())
+
+	return itrp.evaluate(expr), nil
+}
+
+// recoverError turns a panic raised while running Lox code into *err and
+// unwinds the interpreter to m so that it can run more code. It must be
+// deferred.
+func (itrp *Interpreter) recoverError(err *error, m mark) {
+	if r := recover(); r != nil {
+		re, ok := r.(*RuntimeError)
+		if !ok {
+			*err = itrp.lox.internalError(r)
+		} else {
+			*err = re
+		}
+		itrp.unwind(m, re)
+	}
+}
+
+// mark is a state of the interpreter to unwind to when an error raised by
+// Lox code is caught. Calls, blocks and imports leave their state as it is
+// when an error escapes them, so that the traceback is taken from the call
+// stack where the error was raised.
+type mark struct {
+	env, globals *Environment
+	calls        int
+	importing    int
+}
+
+func (itrp *Interpreter) mark() mark {
+	return mark{env: itrp.env, globals: itrp.globals, calls: len(itrp.calls), importing: len(itrp.lox.importing)}
+}
+
+// unwind gives err, if it is a runtime error without one, the traceback of
+// where it was raised, and returns the interpreter to m.
+func (itrp *Interpreter) unwind(m mark, err *RuntimeError) {
+	if err != nil && err.trace == nil {
+		err.trace = itrp.traceback(err.token)
+	}
+	itrp.env, itrp.globals = m.env, m.globals
+	itrp.calls = itrp.calls[:m.calls]
+	itrp.lox.importing = itrp.lox.importing[:m.importing]
+}
+
+// traceback describes the current call stack for an error raised at token,
+// innermost frame first.
+func (itrp *Interpreter) traceback(token *Token) []StackFrame {
+	line := 0
+	if token != nil {
+		line = token.line
+	}
+
+	trace := []StackFrame{}
+	file := itrp.file(itrp.globals)
+	for i := len(itrp.calls) - 1; i >= 0; i-- {
+		trace = append(trace, StackFrame{function: itrp.calls[i].function, file: file, line: line})
+		file, line = itrp.file(itrp.calls[i].globals), itrp.calls[i].line
+	}
+	return append(trace, StackFrame{function: "", file: file, line: line})
+}
+
+// call is a frame of the interpreter's call stack. env and globals are the
+// caller's scopes, which the debugger shows for the frames below the
+// innermost.
+type call struct {
+	StackFrame
+	env     *Environment
+	globals *Environment
+}
+
+func (itrp *Interpreter) pushCall(function string) {
+	// The script counts towards the depth, as its frame does on the VM.
+	if len(itrp.calls)+1 >= itrp.lox.maxCallDepth {
+		panic(limitError(itrp.callSite, ErrStackOverflow))
+	}
+
+	line := 0
+	if itrp.callSite != nil {
+		line = itrp.callSite.line
+	}
+	itrp.calls = append(itrp.calls, call{
+		StackFrame: StackFrame{function: function, line: line},
+		env:        itrp.env,
+		globals:    itrp.globals,
+	})
+}
+
+func (itrp *Interpreter) popCall() {
+	itrp.calls = itrp.calls[:len(itrp.calls)-1]
+}
+
+func (itrp *Interpreter) execute(stmt Stmt) Completion {
+	if err := itrp.lox.step(); err != nil {
+		err.token = stmtToken(stmt)
+		panic(err)
+	}
+	if d := itrp.lox.debugger; d != nil {
+		d.hook(itrp, stmt)
+	}
+	return acceptStmt[Completion](stmt, itrp)
+}
+
+// file is the path of the module whose global scope is globals, or else of
+// the script run by RunFile. It is empty for source run by Run.
+func (itrp *Interpreter) file(globals *Environment) string {
+	if path, ok := itrp.files[globals]; ok {
+		return path
+	}
+	if len(itrp.lox.importing) > 0 {
+		return itrp.lox.importing[0]
+	}
+	return ""
+}
+
+func (itrp *Interpreter) evaluate(expr Expr) any {
+	if err := itrp.lox.step(); err != nil {
+		err.token = exprToken(expr)
+		panic(err)
+	}
+	return acceptExpr[any](expr, itrp)
+}
+
+func (itrp *Interpreter) VisitVariable(expr *Variable) any {
+	return itrp.lookUpVariable(expr.Name, expr.ref)
+}
+func (itrp *Interpreter) VisitThis(expr *This) any {
+	return itrp.lookUpVariable(expr.Keyword, expr.ref)
+}
+
+func (itrp *Interpreter) lookUpVariable(name *Token, ref slotRef) any {
+	if ref.depth < 0 {
+		return itrp.globals.getGlobal(name, ref.slot)
+	}
+	return itrp.env.getAt(ref.depth, ref.slot)
+}
+
+func (itrp *Interpreter) VisitCall(expr *Call) any {
+	callee := itrp.evaluate(expr.Callee)
+
+	arguments := []any{}
+	for _, argument := range expr.Arguments {
+		arguments = append(arguments, itrp.evaluate(argument))
+	}
+
+	fn, message := callable(callee, len(arguments))
+	if message != "" {
+		panic(NewRuntimeError(expr.Paren, message))
+	}
+
+	itrp.callSite = expr.Paren
+	return fn.Call(itrp, arguments)
+}
+
+// callable returns callee as a Callable that accepts argCount arguments, or
+// the error message for calling it.
+func callable(callee any, argCount int) (Callable, string) {
+	fn, ok := callee.(Callable)
+	if !ok {
+		return nil, "Can only call functions and classes."
+	}
+
+	variadic := false
+	if native, ok := fn.(*NativeFunction); ok {
+		variadic = native.variadic
+	}
+	return fn, arityMessage(fn.Arity(), variadic, argCount)
+}
+
+// call calls callee on behalf of the host, which may itself have been called
+// from Lox through a native. The interpreter is left as it was found.
+func (itrp *Interpreter) call(callee any, arguments []any) (result any, err error) {
+	defer itrp.recoverError(&err, itrp.mark())
+
+	fn, message := callable(callee, len(arguments))
+	if message != "" {
+		return nil, NewRuntimeError(nil, message)
+	}
+
+	itrp.callSite = nil
+	return fn.Call(itrp, arguments), nil
+}
+
+func (itrp *Interpreter) VisitLogical(expr *Logical) any {
+	left := itrp.evaluate(expr.Left)
+
+	if expr.Operator.t == TokenType_OR {
+		if isTruthy(left) {
+			return left
+		}
+	} else {
+		if !isTruthy(left) {
+			return left
+		}
+	}
+	return itrp.evaluate(expr.Right)
+}
+func (itrp *Interpreter) VisitSet(expr *Set) any {
+	object := itrp.evaluate(expr.Object)
+
+	loxi, ok := object.(*LoxInstance)
+	if !ok {
+		panic(NewRuntimeError(expr.Name, "Only instances have fields."))
+	}
+
+	value := itrp.evaluate(expr.Value)
+	loxi.Set(expr.Name, value)
+	return value
+}
+func (itrp *Interpreter) VisitSuper(expr *Super) any {
+	// `this` is bound in the scope inside the one that binds `super`.
+	distance := expr.ref.depth
+	superclass := (itrp.env.getAt(distance, 0)).(*LoxClass)
+	object := itrp.env.getAt(distance-1, 0).(*LoxInstance)
+	method := superclass.findMethod(expr.Method.lexeme)
+
+	if method == nil {
+		panic(NewRuntimeError(expr.Method, "Undefined property '"+expr.Method.lexeme+"'."))
+	}
+
+	return method.bind(object)
+}
+
+func (itrp *Interpreter) VisitAssign(expr *Assign) any {
+	value := itrp.evaluate(expr.Value)
+	if expr.ref.depth < 0 {
+		itrp.globals.assignGlobal(expr.Name, expr.ref.slot, value)
+	} else {
+		itrp.env.assignAt(expr.ref.depth, expr.ref.slot, value)
+	}
+
+	return value
+}
+func (itrp *Interpreter) VisitLiteral(expr *Literal) any {
+	return expr.Value
+}
+func (itrp *Interpreter) VisitGrouping(expr *Grouping) any {
+	return itrp.evaluate(expr.Expression)
+}
+
+func (itrp *Interpreter) VisitUnary(expr *Unary) any {
+	right := itrp.evaluate(expr.Right)
+
+	switch expr.Operator.t {
+	case TokenType_BANG:
+		return !isTruthy(right)
+	case TokenType_MINUS:
+		rf, ok := right.(float64)
+		if !ok {
+			panic(NewRuntimeError(expr.Operator, "Operand must be a number."))
+		}
+		return -1.0 * rf
+	}
+
+	panic(NewRuntimeError(expr.Operator, "Unknown unary operator."))
+}
+
+func (itrp *Interpreter) VisitGet(expr *Get) any {
+	object := itrp.evaluate(expr.Object)
+
+	li, ok := object.(*LoxInstance)
+	if ok {
+		return li.Get(expr.Name)
+	}
+
+	builtin, ok := asBuiltin(object)
+	if ok {
+		return builtin.Method(expr.Name)
+	}
+
+	module, ok := object.(*LoxModule)
+	if ok {
+		return module.Get(expr.Name)
+	}
+
+	panic(NewRuntimeError(expr.Name, "Only instances have properties."))
+}
+
+// VisitInterpolation joins the parts of an interpolated string, converting
+// each the same way as print.
+func (itrp *Interpreter) VisitInterpolation(expr *Interpolation) any {
+	var sb strings.Builder
+	for _, part := range expr.Parts {
+		sb.WriteString(stringify(itrp.evaluate(part)))
+	}
+	return sb.String()
+}
+
+func (itrp *Interpreter) VisitLambda(expr *Lambda) any {
+	return NewLoxFunction(expr.Function, itrp.env, false)
+}
+
+func (itrp *Interpreter) VisitList(expr *List) any {
+	elements := make([]any, len(expr.Elements))
+	for i, element := range expr.Elements {
+		elements[i] = itrp.evaluate(element)
+	}
+	return NewLoxList(elements)
+}
+
+func (itrp *Interpreter) VisitMap(expr *Map) any {
+	m := NewLoxMap()
+	for i := range expr.Keys {
+		key := itrp.evaluate(expr.Keys[i])
+		value := itrp.evaluate(expr.Values[i])
+		m.Set(expr.Brace, key, value)
+	}
+	return m
+}
+
+func (itrp *Interpreter) VisitIndex(expr *Index) any {
+	object := itrp.evaluate(expr.Object)
+	index := itrp.evaluate(expr.Index)
+
+	indexable, ok := asIndexable(object)
+	if !ok {
+		panic(NewRuntimeError(expr.Bracket, "Only lists, maps and strings can be indexed."))
+	}
+	return indexable.Get(expr.Bracket, index)
+}
+
+func (itrp *Interpreter) VisitSetIndex(expr *SetIndex) any {
+	object := itrp.evaluate(expr.Object)
+	index := itrp.evaluate(expr.Index)
+
+	indexable, ok := asIndexable(object)
+	if !ok {
+		panic(NewRuntimeError(expr.Bracket, "Only lists, maps and strings can be indexed."))
+	}
+
+	value := itrp.evaluate(expr.Value)
+	indexable.Set(expr.Bracket, index, value)
+	return value
+}
+
+func (itrp *Interpreter) VisitBinary(expr *Binary) any {
+	left := itrp.evaluate(expr.Left)
+	right := itrp.evaluate(expr.Right)
+
+	switch expr.Operator.t {
+	case TokenType_GREATER:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] > fs[1]
+	case TokenType_GREATER_EQUAL:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] >= fs[1]
+	case TokenType_LESS:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] < fs[1]
+	case TokenType_LESS_EQUAL:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] <= fs[1]
+	case TokenType_BANG_EQUAL:
+		return !isEqual(left, right)
+	case TokenType_EQUAL_EQUAL:
+		return isEqual(left, right)
+	case TokenType_MINUS:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] - fs[1]
+	case TokenType_PLUS:
+		lr := []any{left, right}
+		fs, err := toFloats(lr)
+		if err == nil {
+			return fs[0] + fs[1]
+		}
+		ss, err := toStrs(lr)
+		if err == nil {
+			return ss[0] + ss[1]
+		}
+		panic(NewRuntimeError(expr.Operator, "Operands must be two numbers or two strings."))
+	case TokenType_SLASH:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] / fs[1]
+	case TokenType_STAR:
+		fs := numberOperands(expr.Operator, left, right)
+		return fs[0] * fs[1]
+	}
+
+	panic(NewRuntimeError(expr.Operator, "Unknown binary operator."))
+}
+
+func numberOperands(operator *Token, left, right any) []float64 {
+	fs, err := toFloats([]any{left, right})
+	if err != nil {
+		panic(NewRuntimeError(operator, "Operands must be numbers."))
+	}
+	return fs
+}
+
+// stringify formats a value the way `print` shows it. Interpolated strings
+// and the elements of lists and maps are formatted the same way, except that
+// strings inside lists and maps are quoted.
+func stringify(v any) string {
+	var sb strings.Builder
+	writeValue(&sb, v, false, map[any]bool{})
+	return sb.String()
+}
+
+// writeValue writes v as stringify formats it, quoting strings if quote is
+// set. seen holds the lists and maps being written around v; one that
+// contains itself is written as [...] or {...} where it repeats.
+func writeValue(sb *strings.Builder, v any, quote bool, seen map[any]bool) {
+	switch v := v.(type) {
+	case nil:
+		sb.WriteString("nil")
+	case string:
+		if quote {
+			sb.WriteString(strconv.Quote(v))
+		} else {
+			sb.WriteString(v)
+		}
+	case *LoxList:
+		if seen[v] {
+			sb.WriteString("[...]")
+			return
+		}
+		seen[v] = true
+		defer delete(seen, v)
+
+		sb.WriteString("[")
+		for i, e := range v.elements {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeValue(sb, e, true, seen)
+		}
+		sb.WriteString("]")
+	case *LoxMap:
+		if seen[v] {
+			sb.WriteString("{...}")
+			return
+		}
+		seen[v] = true
+		defer delete(seen, v)
+
+		sb.WriteString("{")
+		for i, e := range v.entries {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeValue(sb, e.key, true, seen)
+			sb.WriteString(": ")
+			writeValue(sb, e.value, true, seen)
+		}
+		sb.WriteString("}")
+	default:
+		fmt.Fprint(sb, v)
+	}
+}
+
+// isEqual reports whether a and b are equal Lox values. Values of different
+// types are never equal, and objects are equal only to themselves.
+func isEqual(a, b any) bool {
+	// Host values of the same type may still not be comparable with ==,
+	// such as structs that hold slices.
+	if t := reflect.TypeOf(a); t != nil && t == reflect.TypeOf(b) && !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func toFloats(vs []any) ([]float64, error) {
+	return toTs(vs, make([]float64, len(vs)))
+}
+func toStrs(vs []any) ([]string, error) {
+	return toTs(vs, make([]string, len(vs)))
+}
+func toTs[T any](src []any, dst []T) ([]T, error) {
+	if len(src) != len(dst) {
+		return nil, fmt.Errorf("src and dst must be equal in len")
+	}
+	for i := range src {
+		f, ok := src[i].(T)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast")
+		}
+		dst[i] = f
+	}
+	return dst, nil
+}
+
+// isTruthy reports whether v counts as true in a condition: everything but
+// nil and false does.
+func isTruthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+func (itrp *Interpreter) VisitExpression(stmt *Expression) Completion {
+	itrp.evaluate(stmt.Expression)
+	return Completion_NORMAL
+}
+func (itrp *Interpreter) VisitPrint(stmt *Print) Completion {
+	v := itrp.evaluate(stmt.Expression)
+	fmt.Fprintln(itrp.lox.stdout, stringify(v))
+	return Completion_NORMAL
+}
+func (itrp *Interpreter) VisitVar(stmt *Var) Completion {
+	var value any
+	if stmt.Initializer != nil {
+		value = itrp.evaluate(stmt.Initializer)
+	}
+
+	itrp.env.define(stmt.Name.lexeme, value)
+	return Completion_NORMAL
+}
+func (itrp *Interpreter) VisitImport(stmt *Import) Completion {
+	path, module := itrp.lox.findModule(stmt.Path, stmt.Path.literal.(string))
+	if module == nil {
+		module = itrp.loadModule(stmt.Path, path)
+	}
+
+	if stmt.Alias != nil {
+		itrp.env.define(stmt.Alias.lexeme, module)
+	}
+	for _, name := range stmt.Names {
+		itrp.env.define(name.lexeme, module.Get(name))
+	}
+	return Completion_NORMAL
+}
+
+// loadModule runs the module at path in a global scope of its own.
+func (itrp *Interpreter) loadModule(token *Token, path string) *LoxModule {
+	statements := itrp.lox.parseModule(token, path)
+
+	// An error in the module's code is left for the handler that catches
+	// it to unwind, so that the innermost frame of its traceback is in the
+	// module's file.
+	itrp.lox.beginModule(path)
+
+	// The module's top level shows in tracebacks like a call from the import.
+	itrp.callSite = token
+	itrp.pushCall("")
+
+	previous, globals := itrp.env, itrp.globals
+	env := itrp.newGlobals()
+	itrp.env, itrp.globals = env, env
+	itrp.files[env] = path
+	for _, statement := range statements {
+		itrp.execute(statement)
+	}
+	itrp.env, itrp.globals = previous, globals
+	itrp.popCall()
+
+	module := &LoxModule{
+		path:    path,
+		exports: exportedNames(statements),
+		env:     env,
+	}
+	itrp.lox.endModule(module)
+	return module
+}
+
+func (itrp *Interpreter) VisitExport(stmt *Export) Completion {
+	return itrp.execute(stmt.Declaration)
+}
+
+func (itrp *Interpreter) VisitBlock(stmt *Block) Completion {
+	return itrp.executeBlock(stmt.Statements, NewEnvironmentFrom(itrp.env, stmt.locals))
+}
+
+// executeBlock runs statements in env until one of them completes other
+// than normally, and returns how the last one run completed.
+func (itrp *Interpreter) executeBlock(statements []Stmt, env *Environment) Completion {
+	previous := itrp.env
+	itrp.env = env
+	for _, statement := range statements {
+		if completion := itrp.execute(statement); completion != Completion_NORMAL {
+			itrp.env = previous
+			return completion
+		}
+	}
+	itrp.env = previous
+	return Completion_NORMAL
+}
+
+func (itrp *Interpreter) VisitIf(stmt *If) Completion {
+	if isTruthy(itrp.evaluate(stmt.Condition)) {
+		return itrp.execute(stmt.Then)
+	} else if stmt.Else != nil {
+		return itrp.execute(stmt.Else)
+	}
+	return Completion_NORMAL
+}
+
+func (itrp *Interpreter) VisitWhile(stmt *While) Completion {
+	for isTruthy(itrp.evaluate(stmt.Condition)) {
+		switch itrp.execute(stmt.Body) {
+		case Completion_BREAK:
+			return Completion_NORMAL
+		case Completion_RETURN:
+			return Completion_RETURN
+		}
+		if stmt.Increment != nil {
+			itrp.evaluate(stmt.Increment)
+		}
+	}
+	return Completion_NORMAL
+}
+
+func (itrp *Interpreter) VisitThrow(stmt *Throw) Completion {
+	panic(NewThrow(stmt.Keyword, itrp.evaluate(stmt.Value)))
+}
+
+// VisitTry runs the finally block however the try statement is left: normally,
+// by an error, or by `return`, `break` or `continue`. If the finally block
+// itself leaves abruptly, that replaces the original exit.
+func (itrp *Interpreter) VisitTry(stmt *Try) Completion {
+	if stmt.Finally == nil {
+		return itrp.executeTryCatch(stmt)
+	}
+
+	completion, escaped := itrp.catch(func() Completion {
+		return itrp.executeTryCatch(stmt)
+	})
+
+	// The finally block may call functions, which return values of their own.
+	returned := itrp.returned
+	if finally := itrp.executeBlock(stmt.Finally, NewEnvironmentFrom(itrp.env, stmt.finallyLocals)); finally != Completion_NORMAL {
+		return finally
+	}
+	if escaped != nil {
+		panic(escaped)
+	}
+	itrp.returned = returned
+	return completion
+}
+
+// executeTryCatch runs the try block of stmt, and its catch block if an error
+// escapes the try block.
+func (itrp *Interpreter) executeTryCatch(stmt *Try) Completion {
+	completion, caught := itrp.catch(func() Completion {
+		return itrp.executeBlock(stmt.Body, NewEnvironmentFrom(itrp.env, stmt.bodyLocals))
+	})
+	if caught == nil {
+		return completion
+	}
+	if stmt.Catch == nil {
+		panic(caught)
+	}
+
+	env := NewEnvironmentFrom(itrp.env, stmt.catchLocals)
+	env.define(stmt.CatchName.lexeme, itrp.errorValue(caught))
+	return itrp.executeBlock(stmt.Catch, env)
+}
+
+// catch runs fn and returns the runtime error that escaped it, if any,
+// having unwound the interpreter to where fn was called. Fatal errors, which
+// stop the program, and Go panics are not caught.
+func (itrp *Interpreter) catch(fn func() Completion) (completion Completion, caught *RuntimeError) {
+	defer func(m mark) {
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok || re.fatal {
+				panic(r)
+			}
+			itrp.unwind(m, re)
+			caught = re
+		}
+	}(itrp.mark())
+
+	return fn(), nil
+}
+
+// errorValue is the Lox value bound by `catch` for err.
+func (itrp *Interpreter) errorValue(err *RuntimeError) any {
+	if err.thrown {
+		return err.value
+	}
+
+	instance := NewLoxInstance(errorClass)
+	instance.fields["message"] = err.message
+	instance.fields["line"] = float64(err.line())
+	return instance
+}
+
+func (itrp *Interpreter) VisitBreak(stmt *Break) Completion {
+	return Completion_BREAK
+}
+
+func (itrp *Interpreter) VisitContinue(stmt *Continue) Completion {
+	return Completion_CONTINUE
+}
+
+func (itrp *Interpreter) VisitFunction(stmt *Function) Completion {
+	f := NewLoxFunction(stmt, itrp.env, false)
+	itrp.env.define(stmt.Name.lexeme, f)
+	return Completion_NORMAL
+}
+
+func (itrp *Interpreter) VisitReturn(stmt *Return) Completion {
+	var value any
+
+	if stmt.Value != nil {
+		value = itrp.evaluate(stmt.Value)
+	}
+
+	itrp.returned = value
+	return Completion_RETURN
+}
+
+func (itrp *Interpreter) VisitClass(stmt *Class) Completion {
+
+	var superclass *LoxClass
+	if stmt.SuperClass != nil {
+		result := itrp.evaluate(stmt.SuperClass)
+
+		lc, ok := result.(*LoxClass)
+		if !ok {
+			panic(NewRuntimeError(stmt.SuperClass.Name, "Superclass must be a class."))
+		}
+		superclass = lc
+	}
+
+	if stmt.SuperClass != nil {
+		itrp.env = NewEnvironmentFrom(itrp.env, superLocals)
+		itrp.env.define("super", superclass)
+	}
+
+	methods := map[string]*LoxFunction{}
+	for _, method := range stmt.Methods {
+		lfn := NewLoxFunction(method, itrp.env, method.Name.lexeme == "init")
+		methods[method.Name.lexeme] = lfn
+	}
+
+	klass := NewLoxClass(stmt.Name.lexeme, superclass, methods)
+
+	if superclass != nil {
+		itrp.env = itrp.env.enclosing
+	}
+
+	// Nothing runs before the class is defined, so the methods find it when
+	// they are called.
+	itrp.env.define(stmt.Name.lexeme, klass)
+	return Completion_NORMAL
+}
+
+// The scopes that bind `super` and `this` for the methods of a class.
+var (
+	superLocals = []string{"super"}
+	thisLocals  = []string{"this"}
+)
+
+// Completion is how a statement finished running: normally, or by jumping
+// out of the loop or function around it. A return leaves the value returned
+// in Interpreter.returned. Runtime errors, including those raised by
+// `throw`, aren't completions: they panic with a *RuntimeError, which the
+// try statement, host call or run that catches it recovers.
+type Completion byte
+
+const (
+	Completion_NORMAL Completion = iota
+	Completion_RETURN
+	Completion_BREAK
+	Completion_CONTINUE
+)
@@ -0,0 +1,360 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LintCode identifies a kind of warning. Codes don't change between
+// releases, so they can be used to suppress warnings.
+type LintCode string
+
+const (
+	// LintCode_UNUSED_VARIABLE is a local variable, function or class that
+	// is never read.
+	LintCode_UNUSED_VARIABLE LintCode = "W001"
+	// LintCode_UNUSED_PARAMETER is a parameter that is never read.
+	LintCode_UNUSED_PARAMETER LintCode = "W002"
+	// LintCode_SHADOWED is a local declaration hiding one in an enclosing
+	// scope.
+	LintCode_SHADOWED LintCode = "W003"
+	// LintCode_UNREACHABLE is a statement after a return, throw, break or
+	// continue.
+	LintCode_UNREACHABLE LintCode = "W004"
+	// LintCode_ARITY is a call with the wrong number of arguments to a
+	// function, class or native whose arity is known.
+	LintCode_ARITY LintCode = "W005"
+	// LintCode_UNASSIGNED_FIELD is a field read through `this` that nothing
+	// in the source assigns and that isn't a method.
+	LintCode_UNASSIGNED_FIELD LintCode = "W006"
+)
+
+// Warning is a likely mistake found by Lint.
+type Warning struct {
+	Span    Span
+	Code    LintCode
+	Message string
+}
+
+// Lint looks for likely mistakes in source without running it. Names
+// starting with an underscore are never reported as unused.
+//
+// A warning is suppressed by a comment containing `lint:ignore` at the end
+// of its line or on the line before, optionally followed by the codes to
+// ignore, as in `// lint:ignore W001 W003`. Syntax errors are returned as a
+// *CompileError.
+func Lint(source string) ([]Warning, error) {
+	l := New()
+	tokens := NewScanner(l, source).scanTokens()
+	statements := NewParser(l, tokens).parse()
+	if l.hadError {
+		return nil, l.compileError()
+	}
+
+	lint := &linter{
+		scopes:   []map[string]*lintVar{{}},
+		classes:  map[string]*Class{},
+		assigned: map[string]bool{},
+	}
+	resolver := NewResolver(l, l.interpreter)
+	resolver.lint = lint
+	resolver.resolveStmts(statements)
+	if l.hadError {
+		return nil, l.compileError()
+	}
+
+	return lint.finish(tokens), nil
+}
+
+// linter collects warnings as the resolver reports declarations and uses.
+// Its scopes follow the resolver's, under a scope of its own for the
+// globals.
+type linter struct {
+	scopes   []map[string]*lintVar
+	warnings []Warning
+	// classes are the classes declared in the source by name, and class
+	// those whose methods are being resolved, innermost last.
+	classes map[string]*Class
+	class   []*Class
+	// assigned holds the names of the fields assigned anywhere.
+	assigned map[string]bool
+	// calls and fields are checked once every declaration has been seen.
+	calls  []lintCall
+	fields []lintField
+}
+
+type lintVar struct {
+	name     *Token
+	kind     SymbolKind
+	read     bool
+	assigned bool
+	// function or class is what a function or class declaration declares,
+	// which calls are checked against.
+	function *Function
+	class    *Class
+}
+
+// lintCall is a call to the variable name, which was declared by v, or is a
+// global if v is nil.
+type lintCall struct {
+	call *Call
+	name *Token
+	v    *lintVar
+}
+
+// lintField is a field read through `this` in a method of class.
+type lintField struct {
+	name  *Token
+	class *Class
+}
+
+func (l *linter) warn(token *Token, code LintCode, format string, args ...any) {
+	l.warnings = append(l.warnings, Warning{Span: sourceSpan(token), Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) beginScope() {
+	l.scopes = append(l.scopes, map[string]*lintVar{})
+}
+
+// endScope reports the declarations of the innermost scope that were never
+// read.
+func (l *linter) endScope() {
+	scope := l.scopes[len(l.scopes)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+
+	for name, v := range scope {
+		if v.read || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch v.kind {
+		case SymbolKind_PARAMETER:
+			l.warn(v.name, LintCode_UNUSED_PARAMETER, "Parameter '%s' is never read.", name)
+		case SymbolKind_FUNCTION:
+			l.warn(v.name, LintCode_UNUSED_VARIABLE, "Function '%s' is never used.", name)
+		case SymbolKind_CLASS:
+			l.warn(v.name, LintCode_UNUSED_VARIABLE, "Class '%s' is never used.", name)
+		default:
+			l.warn(v.name, LintCode_UNUSED_VARIABLE, "Variable '%s' is never read.", name)
+		}
+	}
+}
+
+// declare records a declaration in the innermost scope, reporting it if it
+// hides another.
+func (l *linter) declare(name *Token, kind SymbolKind) {
+	if kind == SymbolKind_METHOD {
+		return
+	}
+
+	scope := l.scopes[len(l.scopes)-1]
+	if len(l.scopes) > 1 {
+		if outer := l.lookUp(name.lexeme, len(l.scopes)-2); outer != nil {
+			l.warn(name, LintCode_SHADOWED, "'%s' shadows the declaration on line %d.", name.lexeme, sourceSpan(outer.name).Line)
+		}
+	}
+	// A global declared twice can't be relied on to be either.
+	if v, ok := scope[name.lexeme]; ok && len(l.scopes) == 1 {
+		v.kind = SymbolKind_VARIABLE
+		v.function, v.class = nil, nil
+		return
+	}
+	scope[name.lexeme] = &lintVar{name: name, kind: kind}
+}
+
+// lookUp finds the declaration of name in the scopes up to index from,
+// innermost first.
+func (l *linter) lookUp(name string, from int) *lintVar {
+	for i := from; i >= 0; i-- {
+		if v, ok := l.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (l *linter) read(name *Token) {
+	if v := l.lookUp(name.lexeme, len(l.scopes)-1); v != nil {
+		v.read = true
+	}
+}
+
+func (l *linter) assign(name *Token) {
+	if v := l.lookUp(name.lexeme, len(l.scopes)-1); v != nil {
+		v.assigned = true
+	}
+}
+
+// function records the function that the declaration of its name, just
+// made, declares.
+func (l *linter) function(stmt *Function) {
+	if v := l.scopes[len(l.scopes)-1][stmt.Name.lexeme]; v != nil && v.name == stmt.Name {
+		v.function = stmt
+	}
+}
+
+func (l *linter) beginClass(stmt *Class) {
+	if v := l.scopes[len(l.scopes)-1][stmt.Name.lexeme]; v != nil && v.name == stmt.Name {
+		v.class = stmt
+	}
+	l.classes[stmt.Name.lexeme] = stmt
+	l.class = append(l.class, stmt)
+}
+
+func (l *linter) endClass() {
+	l.class = l.class[:len(l.class)-1]
+}
+
+func (l *linter) call(expr *Call) {
+	callee, ok := expr.Callee.(*Variable)
+	if !ok {
+		return
+	}
+	name := callee.Name
+	v := l.lookUp(name.lexeme, len(l.scopes)-1)
+	if v != nil && v == l.scopes[0][name.lexeme] {
+		// Globals are looked up at the end, as they may be declared later.
+		v = nil
+	}
+	l.calls = append(l.calls, lintCall{call: expr, name: name, v: v})
+}
+
+func (l *linter) get(expr *Get) {
+	if _, ok := expr.Object.(*This); ok && len(l.class) > 0 {
+		l.fields = append(l.fields, lintField{name: expr.Name, class: l.class[len(l.class)-1]})
+	}
+}
+
+func (l *linter) set(expr *Set) {
+	l.assigned[expr.Name.lexeme] = true
+}
+
+// unreachable reports the first statement after one that always jumps away.
+func (l *linter) unreachable(statements []Stmt) {
+	for i := 0; i+1 < len(statements); i++ {
+		switch statements[i].(type) {
+		case *Return, *Throw, *Break, *Continue:
+			if token := stmtToken(statements[i+1]); token != nil {
+				l.warn(token, LintCode_UNREACHABLE, "Unreachable code.")
+			}
+			return
+		}
+	}
+}
+
+// finish checks what needed every declaration, drops the suppressed
+// warnings and returns the rest in source order.
+func (l *linter) finish(tokens []*Token) []Warning {
+	for _, c := range l.calls {
+		l.checkCall(c)
+	}
+	for _, f := range l.fields {
+		l.checkField(f)
+	}
+
+	ignored := ignoredLines(tokens)
+	warnings := []Warning{}
+	for _, w := range l.warnings {
+		codes, ok := ignored[w.Span.Line]
+		if ok && (len(codes) == 0 || codes[w.Code]) {
+			continue
+		}
+		warnings = append(warnings, w)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Span.Offset < warnings[j].Span.Offset
+	})
+	return warnings
+}
+
+func (l *linter) checkCall(c lintCall) {
+	v := c.v
+	if v == nil {
+		v = l.scopes[0][c.name.lexeme]
+	}
+
+	arity, variadic := -1, false
+	switch {
+	case v == nil:
+		if native, ok := natives[c.name.lexeme].(*NativeFunction); ok {
+			arity, variadic = native.arity, native.variadic
+		}
+	case v.assigned:
+	case v.function != nil:
+		arity = len(v.function.Params)
+	case v.class != nil:
+		arity = l.initArity(v.class)
+	}
+	if arity < 0 {
+		return
+	}
+
+	if message := arityMessage(arity, variadic, len(c.call.Arguments)); message != "" {
+		l.warn(c.name, LintCode_ARITY, "%s for '%s'.", strings.TrimSuffix(message, "."), c.name.lexeme)
+	}
+}
+
+// initArity returns the number of arguments that constructing class takes,
+// or -1 if it inherits from a class that isn't in the source.
+func (l *linter) initArity(class *Class) int {
+	for class != nil {
+		for _, method := range class.Methods {
+			if method.Name.lexeme == "init" {
+				return len(method.Params)
+			}
+		}
+		if class.SuperClass == nil {
+			return 0
+		}
+		class = l.classes[class.SuperClass.Name.lexeme]
+	}
+	return -1
+}
+
+func (l *linter) checkField(f lintField) {
+	name := f.name.lexeme
+	if l.assigned[name] {
+		return
+	}
+	for class := f.class; class != nil; class = l.classes[class.SuperClass.Name.lexeme] {
+		for _, method := range class.Methods {
+			if method.Name.lexeme == name {
+				return
+			}
+		}
+		if class.SuperClass == nil {
+			l.warn(f.name, LintCode_UNASSIGNED_FIELD, "Field '%s' is never assigned.", name)
+			return
+		}
+	}
+}
+
+// ignoredLines finds the lint:ignore comments among the comments attached
+// to tokens. It returns the codes ignored on each line, which are all of
+// them if the set is empty.
+func ignoredLines(tokens []*Token) map[int]map[LintCode]bool {
+	ignored := map[int]map[LintCode]bool{}
+	ignore := func(comment *Token, line int) {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.lexeme, "//"))
+		fields := strings.Fields(text)
+		if len(fields) == 0 || fields[0] != "lint:ignore" {
+			return
+		}
+		codes := map[LintCode]bool{}
+		for _, code := range fields[1:] {
+			codes[LintCode(code)] = true
+		}
+		ignored[line] = codes
+	}
+
+	for _, token := range tokens {
+		// A comment on a line of its own applies to the next line.
+		for _, comment := range token.leading {
+			ignore(comment, comment.line+1)
+		}
+		if token.trailing != nil {
+			ignore(token.trailing, token.trailing.line)
+		}
+	}
+	return ignored
+}
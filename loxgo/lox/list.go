@@ -0,0 +1,82 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+)
+
+type LoxList struct {
+	elements []any
+}
+
+func NewLoxList(elements []any) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+func (l *LoxList) String() string {
+	return stringify(l)
+}
+
+func (l *LoxList) Get(token *Token, index any) any {
+	return l.elements[l.index(token, index, len(l.elements))]
+}
+
+func (l *LoxList) Set(token *Token, index any, value any) {
+	l.elements[l.index(token, index, len(l.elements))] = value
+}
+
+// index converts a Lox number into a position in [0, limit).
+func (l *LoxList) index(token *Token, index any, limit int) int {
+	f, ok := index.(float64)
+	if !ok || f != math.Trunc(f) {
+		panic(NewRuntimeError(token, "List index must be an integer."))
+	}
+	if f < 0 || f >= float64(limit) {
+		panic(NewRuntimeError(token, fmt.Sprintf("List index %v out of range.", f)))
+	}
+	return int(f)
+}
+
+// Method returns the built-in method called name bound to the list. Errors
+// raised by the method are reported at token.
+func (l *LoxList) Method(name *Token) *NativeFunction {
+	switch name.lexeme {
+	case "append":
+		return NewNativeFunction(1, func(arguments []any) any {
+			l.elements = append(l.elements, arguments[0])
+			return nil
+		})
+	case "pop":
+		return NewNativeFunction(0, func(arguments []any) any {
+			if len(l.elements) == 0 {
+				panic(NewRuntimeError(name, "Can't pop from an empty list."))
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last
+		})
+	case "len":
+		return NewNativeFunction(0, func(arguments []any) any {
+			return float64(len(l.elements))
+		})
+	case "insert":
+		return NewNativeFunction(2, func(arguments []any) any {
+			i := l.index(name, arguments[0], len(l.elements)+1)
+			l.elements = append(l.elements, nil)
+			copy(l.elements[i+1:], l.elements[i:])
+			l.elements[i] = arguments[1]
+			return nil
+		})
+	case "slice":
+		return NewNativeFunction(2, func(arguments []any) any {
+			start := l.index(name, arguments[0], len(l.elements)+1)
+			end := l.index(name, arguments[1], len(l.elements)+1)
+			if start > end {
+				panic(NewRuntimeError(name, "Slice start must not be after end."))
+			}
+			return NewLoxList(append([]any{}, l.elements[start:end]...))
+		})
+	}
+
+	panic(NewRuntimeError(name, "Undefined property '"+name.lexeme+"'."))
+}
@@ -0,0 +1,796 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var keywords = map[string]TokenType{
+	"and":      TokenType_AND,
+	"break":    TokenType_BREAK,
+	"catch":    TokenType_CATCH,
+	"class":    TokenType_CLASS,
+	"continue": TokenType_CONTINUE,
+	"else":     TokenType_ELSE,
+	"export":   TokenType_EXPORT,
+	"false":    TokenType_FALSE,
+	"finally":  TokenType_FINALLY,
+	"for":      TokenType_FOR,
+	"fun":      TokenType_FUN,
+	"if":       TokenType_IF,
+	"import":   TokenType_IMPORT,
+	"nil":      TokenType_NIL,
+	"or":       TokenType_OR,
+	"print":    TokenType_PRINT,
+	"return":   TokenType_RETURN,
+	"super":    TokenType_SUPER,
+	"this":     TokenType_THIS,
+	"throw":    TokenType_THROW,
+	"true":     TokenType_TRUE,
+	"try":      TokenType_TRY,
+	"var":      TokenType_VAR,
+	"while":    TokenType_WHILE,
+}
+
+// Lox runs Lox programs. Create one with New; it keeps its globals between
+// calls to Run so that a host, like the REPL, can build on earlier code. A
+// Lox is not safe for concurrent use.
+type Lox struct {
+	interpreter *Interpreter
+	// vm, when set, runs programs on the bytecode VM instead of the interpreter
+	vm          *VM
+	useVM       bool
+	disassemble bool
+	stdout      io.Writer
+	stderr      io.Writer
+	// diagnostics, when set, is called with each error as it is reported.
+	diagnostics func(error)
+	// debugger, when set, can pause programs run on the interpreter.
+	debugger *Debugger
+	// globals are defined by the host in the global scope of every module.
+	globals map[string]any
+	// modules caches loaded modules by absolute path. importing holds the
+	// modules being loaded, outermost first, starting with the script run
+	// by RunFile.
+	modules   map[string]*LoxModule
+	importing []string
+	// errors holds the syntax errors reported by the current run.
+	errors   []*SyntaxError
+	hadError bool
+
+	// maxSteps, if nonzero, limits the steps of each run, and maxCallDepth
+	// limits how deeply Lox calls nest.
+	maxSteps     int
+	maxCallDepth int
+	// ctx and steps belong to the run in progress. active counts the calls
+	// into Lox that are running, as the host may call back into Lox from a
+	// native; the outermost one sets the limits for all of them.
+	ctx    context.Context
+	steps  int
+	active int
+
+	// optionErr is the first error from an option passed to New. It is
+	// returned by every run, as New can't return it.
+	optionErr error
+}
+
+// New creates a Lox configured by opts. By default it runs programs on the
+// tree-walking interpreter, prints to os.Stdout and doesn't report errors
+// anywhere but in the values it returns.
+func New(opts ...Option) *Lox {
+	l := &Lox{
+		stdout:       os.Stdout,
+		stderr:       io.Discard,
+		globals:      map[string]any{},
+		maxCallDepth: framesMax,
+	}
+	for name, value := range natives {
+		l.globals[name] = value
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	// The interpreter is also used by the VM, for the resolver's side table.
+	l.interpreter = NewInterpreter(l)
+	if l.useVM {
+		l.vm = NewVM(l)
+	}
+	return l
+}
+
+// Run runs source as a script. Syntax errors are returned as a
+// *CompileError, and runtime errors as a *RuntimeError.
+func (l *Lox) Run(source string) error {
+	return l.run(context.Background(), source)
+}
+
+// RunContext is like Run but stops the script with a runtime error wrapping
+// ctx.Err() once ctx is done.
+func (l *Lox) RunContext(ctx context.Context, source string) error {
+	return l.run(ctx, source)
+}
+
+// RunFile runs the script at path. Imports in the script are relative to its
+// directory.
+func (l *Lox) RunFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	l.importing = []string{abs}
+	defer func() {
+		l.importing = nil
+	}()
+
+	return l.run(context.Background(), string(b))
+}
+
+// Eval evaluates a single expression, such as "1 + x", and returns its
+// value. It can read the globals defined by earlier calls to Run.
+func (l *Lox) Eval(source string) (any, error) {
+	return l.EvalContext(context.Background(), source)
+}
+
+// EvalContext is like Eval but stops the evaluation with a runtime error
+// wrapping ctx.Err() once ctx is done.
+func (l *Lox) EvalContext(ctx context.Context, source string) (any, error) {
+	if l.optionErr != nil {
+		return nil, l.optionErr
+	}
+	defer l.begin(ctx)()
+
+	expr, err := l.parseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return l.evalExpr(expr)
+}
+
+// parseExpr parses source as a single expression.
+func (l *Lox) parseExpr(source string) (Expr, error) {
+	l.resetErrors()
+
+	tokens := NewScanner(l, source).scanTokens()
+
+	expr := NewParser(l, tokens).parseExpression()
+	if l.hadError {
+		return nil, l.compileError()
+	}
+	return expr, nil
+}
+
+// evalExpr evaluates a parsed expression in the global scope.
+func (l *Lox) evalExpr(expr Expr) (any, error) {
+	NewResolver(l, l.interpreter).resolveExpr(expr)
+	if l.hadError {
+		return nil, l.compileError()
+	}
+
+	if l.vm != nil {
+		function := NewCompiler(l).compileExpression(expr)
+		if l.hadError {
+			return nil, l.compileError()
+		}
+		value, err := l.vm.interpret(function)
+		return value, l.reportRuntimeError(err)
+	}
+
+	value, err := l.interpreter.eval(expr)
+	return value, l.reportRuntimeError(err)
+}
+
+func (l *Lox) report(token *Token, where string, message string) {
+	err := &SyntaxError{Line: token.line, Where: where, Message: message, token: token}
+	fmt.Fprintln(l.stderr, err)
+	l.diagnose(err)
+	l.errors = append(l.errors, err)
+	l.hadError = true
+}
+
+func (l *Lox) resetErrors() {
+	l.errors = nil
+	l.hadError = false
+}
+
+func (l *Lox) compileError() error {
+	return &CompileError{Errors: l.errors}
+}
+
+// reportRuntimeError reports err, if it is a runtime error, followed by its
+// Lox call stack, innermost frame first. It returns err.
+func (l *Lox) reportRuntimeError(err error) error {
+	if re, ok := err.(*RuntimeError); ok {
+		fmt.Fprint(l.stderr, re.Traceback())
+		l.diagnose(re)
+	}
+	return err
+}
+
+// internalError reports a Go panic that isn't a Lox runtime error. These are
+// bugs in the interpreter rather than in the script.
+func (l *Lox) internalError(v any) error {
+	fmt.Fprintf(l.stderr, "Internal error: %v\n%s", v, debug.Stack())
+	err := fmt.Errorf("internal error: %v", v)
+	l.diagnose(err)
+	return err
+}
+
+func (l *Lox) diagnose(err error) {
+	if l.diagnostics != nil {
+		l.diagnostics(err)
+	}
+}
+
+// begin starts running Lox code under ctx and returns the func that ends
+// it. Calls that begin while another is running share its context and step
+// count.
+func (l *Lox) begin(ctx context.Context) (end func()) {
+	if l.active == 0 {
+		l.steps = 0
+		l.ctx = nil
+		// Background contexts are never done; skip checking them.
+		if ctx.Done() != nil {
+			l.ctx = ctx
+		}
+	}
+	l.active++
+	return func() {
+		l.active--
+	}
+}
+
+// step counts a step of the run in progress: a statement or expression on
+// the interpreter, or an instruction on the VM. It returns the error to
+// raise if the run has been cancelled or has run out of steps.
+func (l *Lox) step() *RuntimeError {
+	l.steps++
+	if l.maxSteps > 0 && l.steps > l.maxSteps {
+		return limitError(nil, ErrStepLimit)
+	}
+	// Checking the context is slower than counting; do it now and then.
+	if l.ctx != nil && l.steps%1024 == 0 {
+		if err := l.ctx.Err(); err != nil {
+			return limitError(nil, err)
+		}
+	}
+	return nil
+}
+
+func (l *Lox) run(ctx context.Context, source string) error {
+	if l.optionErr != nil {
+		return l.optionErr
+	}
+	defer l.begin(ctx)()
+	l.resetErrors()
+
+	scanner := NewScanner(l, source)
+	tokens := scanner.scanTokens()
+
+	parser := NewParser(l, tokens)
+	statements := parser.parse()
+
+	// Stop if there was a syntax error.
+	if l.hadError {
+		return l.compileError()
+	}
+
+	return l.exec(statements)
+}
+
+// runLine runs source typed at the REPL. A single expression statement is
+// evaluated and its value returned with isExpr set, so that the REPL can
+// show it. The semicolon ending the last statement may be left off.
+func (l *Lox) runLine(source string) (value any, isExpr bool, err error) {
+	if l.optionErr != nil {
+		return nil, false, l.optionErr
+	}
+	defer l.begin(context.Background())()
+	l.resetErrors()
+
+	tokens := NewScanner(l, source).scanTokens()
+	// The semicolon is added only when the line doesn't parse without it,
+	// so that a line ending in a block, such as a function declaration,
+	// isn't given an empty statement.
+	if n := len(tokens); n > 1 && tokens[n-2].t != TokenType_SEMICOLON && !parses(tokens) {
+		eof := tokens[n-1]
+		terminated := append(tokens[:n-1:n-1], NewToken(TokenType_SEMICOLON, ";", nil, eof.line), eof)
+		if parses(terminated) {
+			tokens = terminated
+		}
+	}
+
+	statements := NewParser(l, tokens).parse()
+	if l.hadError {
+		return nil, false, l.compileError()
+	}
+
+	if len(statements) == 1 {
+		if stmt, ok := statements[0].(*Expression); ok {
+			value, err := l.evalExpr(stmt.Expression)
+			return value, true, err
+		}
+	}
+	return nil, false, l.exec(statements)
+}
+
+// parses reports whether tokens parse without syntax errors.
+func parses(tokens []*Token) bool {
+	// Parse with a Lox of its own so that errors go unreported.
+	l := &Lox{stderr: io.Discard}
+	NewParser(l, tokens).parse()
+	return !l.hadError
+}
+
+// exec resolves and runs parsed statements.
+func (l *Lox) exec(statements []Stmt) error {
+	resolver := NewResolver(l, l.interpreter)
+	resolver.resolveStmts(statements)
+
+	if l.hadError {
+		return l.compileError()
+	}
+
+	if l.vm != nil {
+		function := NewCompiler(l).compile(statements)
+		if l.hadError {
+			return l.compileError()
+		}
+		if l.disassemble {
+			disassembleFunction(l.stdout, function)
+		}
+		_, err := l.vm.interpret(function)
+		return l.reportRuntimeError(err)
+	}
+
+	return l.reportRuntimeError(l.interpreter.interpret(statements))
+}
+
+type TokenType string
+
+const (
+	// Single-character tokens.
+	TokenType_LEFT_PAREN    TokenType = "LEFT_PAREN"
+	TokenType_RIGHT_PAREN   TokenType = "RIGHT_PAREN"
+	TokenType_LEFT_BRACE    TokenType = "LEFT_BRACE"
+	TokenType_RIGHT_BRACE   TokenType = "RIGHT_BRACE"
+	TokenType_LEFT_BRACKET  TokenType = "LEFT_BRACKET"
+	TokenType_RIGHT_BRACKET TokenType = "RIGHT_BRACKET"
+	TokenType_COMMA         TokenType = "COMMA"
+	TokenType_COLON         TokenType = "COLON"
+	TokenType_DOT           TokenType = "DOT"
+	TokenType_MINUS         TokenType = "MINUS"
+	TokenType_PLUS          TokenType = "PLUS"
+	TokenType_SEMICOLON     TokenType = "SEMICOLON"
+	TokenType_SLASH         TokenType = "SLASH"
+	TokenType_STAR          TokenType = "STAR"
+	// One or two character tokens.
+	TokenType_BANG          TokenType = "BANG"
+	TokenType_BANG_EQUAL    TokenType = "BANG_EQUAL"
+	TokenType_EQUAL         TokenType = "EQUAL"
+	TokenType_EQUAL_EQUAL   TokenType = "EQUAL_EQUAL"
+	TokenType_ARROW         TokenType = "ARROW"
+	TokenType_GREATER       TokenType = "GREATER"
+	TokenType_GREATER_EQUAL TokenType = "GREATER_EQUAL"
+	TokenType_LESS          TokenType = "LESS"
+	TokenType_LESS_EQUAL    TokenType = "LESS_EQUAL"
+	// Literals.
+	TokenType_IDENTIFIER TokenType = "IDENTIFIER"
+	TokenType_STRING     TokenType = "STRING"
+	// A string part followed by `${`.
+	TokenType_INTERPOLATION TokenType = "INTERPOLATION"
+	TokenType_NUMBER        TokenType = "NUMBER"
+	// Keywords.
+	TokenType_AND      TokenType = "AND"
+	TokenType_BREAK    TokenType = "BREAK"
+	TokenType_CATCH    TokenType = "CATCH"
+	TokenType_CLASS    TokenType = "CLASS"
+	TokenType_CONTINUE TokenType = "CONTINUE"
+	TokenType_ELSE     TokenType = "ELSE"
+	TokenType_EXPORT   TokenType = "EXPORT"
+	TokenType_FALSE    TokenType = "FALSE"
+	TokenType_FINALLY  TokenType = "FINALLY"
+	TokenType_FUN      TokenType = "FUN"
+	TokenType_FOR      TokenType = "FOR"
+	TokenType_IF       TokenType = "IF"
+	TokenType_IMPORT   TokenType = "IMPORT"
+	TokenType_NIL      TokenType = "NIL"
+	TokenType_OR       TokenType = "OR"
+	TokenType_PRINT    TokenType = "PRINT"
+	TokenType_RETURN   TokenType = "RETURN"
+	TokenType_SUPER    TokenType = "SUPER"
+	TokenType_THIS     TokenType = "THIS"
+	TokenType_THROW    TokenType = "THROW"
+	TokenType_TRUE     TokenType = "TRUE"
+	TokenType_TRY      TokenType = "TRY"
+	TokenType_VAR      TokenType = "VAR"
+	TokenType_WHILE    TokenType = "WHILE"
+	TokenType_EOF      TokenType = "EOF"
+
+	// COMMENT tokens aren't in the token stream: the scanner attaches them
+	// to the tokens around them.
+	TokenType_COMMENT TokenType = "COMMENT"
+)
+
+// Token is a lexeme of the source. line is the line the token ends on;
+// column is the column of its first character, counting runes from 1, and
+// offset is the byte offset of its first byte.
+type Token struct {
+	t       TokenType
+	lexeme  string
+	literal any
+	line    int
+	column  int
+	offset  int
+	// leading are the comments on the lines before the token, and trailing
+	// is a comment after it on the same line. Only the formatter uses them.
+	leading  []*Token
+	trailing *Token
+}
+
+func NewToken(t TokenType, lexeme string, literal any, line int) *Token {
+	return &Token{
+		t:       t,
+		lexeme:  lexeme,
+		literal: literal,
+		line:    line,
+	}
+}
+
+func (t *Token) String() string {
+	return fmt.Sprintf("%s %s %s", t.t, t.lexeme, t.literal)
+}
+
+// Scanner splits source into tokens. start and current are byte offsets,
+// but the scanner steps over whole runes so that identifiers and strings can
+// contain any Unicode letters.
+type Scanner struct {
+	lox     *Lox
+	source  string
+	tokens  []*Token
+	start   int
+	current int
+	line    int
+	// column counts the runes between the start of the line and current,
+	// and startColumn those before start, so that tokens are placed without
+	// rescanning the line.
+	column      int
+	startColumn int
+	// interpolations holds, for each string interpolation being scanned, the
+	// number of unclosed braces inside its `${}`.
+	interpolations []int
+	// comments holds the comments scanned since the last token, which lead
+	// the next one.
+	comments []*Token
+}
+
+func NewScanner(lox *Lox, source string) *Scanner {
+	return &Scanner{
+		lox:     lox,
+		source:  source,
+		tokens:  []*Token{},
+		start:   0,
+		current: 0,
+		line:    1,
+	}
+}
+
+func (s *Scanner) scanTokens() []*Token {
+	for !s.isAtEnd() {
+		// We are at the beginning of the next lexeme.
+		s.start, s.startColumn = s.current, s.column
+		s.scanToken()
+	}
+
+	if len(s.interpolations) > 0 {
+		s.error("Unterminated string interpolation.")
+	}
+
+	s.start, s.startColumn = s.current, s.column
+	s.addToken(TokenType_EOF)
+	return s.tokens
+}
+
+func (s *Scanner) isAtEnd() bool {
+	return s.current >= len(s.source)
+}
+
+func (s *Scanner) scanToken() {
+	c := s.advance()
+
+	switch c {
+	case '(':
+		s.addToken(TokenType_LEFT_PAREN)
+	case ')':
+		s.addToken(TokenType_RIGHT_PAREN)
+	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1]++
+		}
+		s.addToken(TokenType_LEFT_BRACE)
+	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				// The end of `${expr}`: the string continues.
+				s.interpolations = s.interpolations[:n-1]
+				s.string()
+				break
+			}
+			s.interpolations[n-1]--
+		}
+		s.addToken(TokenType_RIGHT_BRACE)
+	case '[':
+		s.addToken(TokenType_LEFT_BRACKET)
+	case ']':
+		s.addToken(TokenType_RIGHT_BRACKET)
+	case ',':
+		s.addToken(TokenType_COMMA)
+	case ':':
+		s.addToken(TokenType_COLON)
+	case '.':
+		s.addToken(TokenType_DOT)
+	case '-':
+		s.addToken(TokenType_MINUS)
+	case '+':
+		s.addToken(TokenType_PLUS)
+	case ';':
+		s.addToken(TokenType_SEMICOLON)
+	case '*':
+		s.addToken(TokenType_STAR)
+	case '!':
+		s.addToken(tern(s.match('='), TokenType_BANG_EQUAL, TokenType_BANG))
+	case '=':
+		if s.match('=') {
+			s.addToken(TokenType_EQUAL_EQUAL)
+		} else if s.match('>') {
+			s.addToken(TokenType_ARROW)
+		} else {
+			s.addToken(TokenType_EQUAL)
+		}
+	case '<':
+		s.addToken(tern(s.match('='), TokenType_LESS_EQUAL, TokenType_LESS))
+	case '>':
+		s.addToken(tern(s.match('='), TokenType_GREATER_EQUAL, TokenType_GREATER))
+	case '/':
+		if s.match('/') {
+			// A comment goes until the end of the line.
+			for s.peek() != '\n' && !s.isAtEnd() {
+				s.advance()
+			}
+			s.comment()
+		} else {
+			s.addToken(TokenType_SLASH)
+		}
+	case ' ':
+		// Ignore whitespace.
+	case '\r':
+		// Ignore whitespace.
+	case '\t':
+		// Ignore whitespace.
+	case '\n':
+		s.line++
+	case '"':
+		s.string()
+	default:
+		if isDigit(c) {
+			s.number()
+		} else if isAlpha(c) {
+			s.identifier()
+		} else {
+			s.error("Unexpected character.")
+		}
+	}
+}
+
+func tern[T any](c bool, t T, f T) T {
+	if c {
+		return t
+	}
+	return f
+}
+
+func (s *Scanner) peek() rune {
+	if s.isAtEnd() {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return r
+}
+
+func (s *Scanner) match(expected rune) bool {
+	if s.isAtEnd() || s.peek() != expected {
+		return false
+	}
+	s.advance()
+	return true
+}
+
+func (s *Scanner) advance() rune {
+	r, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	s.column++
+	if r == '\n' {
+		s.column = 0
+	}
+	return r
+}
+
+func (s *Scanner) addToken(t TokenType) {
+	s.addTokenL(t, nil)
+}
+
+func (s *Scanner) addTokenL(t TokenType, literal any) {
+	token := s.makeToken(t, literal)
+	token.leading, s.comments = s.comments, nil
+	s.tokens = append(s.tokens, token)
+}
+
+// comment keeps the comment just scanned: it trails the previous token if
+// it is on the same line, and otherwise leads the next one.
+func (s *Scanner) comment() {
+	comment := s.makeToken(TokenType_COMMENT, nil)
+	comment.lexeme = strings.TrimRight(comment.lexeme, " \t\r")
+	if n := len(s.tokens); n > 0 && s.tokens[n-1].line == s.line && len(s.comments) == 0 {
+		s.tokens[n-1].trailing = comment
+		return
+	}
+	s.comments = append(s.comments, comment)
+}
+
+// makeToken creates a token for the lexeme being scanned.
+func (s *Scanner) makeToken(t TokenType, literal any) *Token {
+	token := NewToken(t, s.source[s.start:s.current], literal, s.line)
+	token.column = s.startColumn + 1
+	token.offset = s.start
+	return token
+}
+
+// error reports a syntax error in the lexeme being scanned.
+func (s *Scanner) error(message string) {
+	s.lox.report(s.makeToken("", nil), "", message)
+}
+
+// string scans a string literal, or the part of one up to the next `${` or
+// the closing quote. A part followed by `${` is an INTERPOLATION token; the
+// parser reads the expression and then the rest of the string.
+func (s *Scanner) string() {
+	var value strings.Builder
+	for !s.isAtEnd() {
+		switch c := s.advance(); c {
+		case '"':
+			s.addTokenL(TokenType_STRING, value.String())
+			return
+		case '\\':
+			s.escape(&value)
+		case '$':
+			if s.match('{') {
+				s.interpolations = append(s.interpolations, 0)
+				s.addTokenL(TokenType_INTERPOLATION, value.String())
+				return
+			}
+			value.WriteRune(c)
+		case '\n':
+			s.line++
+			value.WriteRune(c)
+		default:
+			value.WriteRune(c)
+		}
+	}
+
+	s.error("Unterminated string.")
+}
+
+// escape writes the character denoted by the escape sequence after a
+// backslash.
+func (s *Scanner) escape(value *strings.Builder) {
+	switch c := s.advance(); c {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '0':
+		value.WriteByte(0)
+	case '"', '\\', '$':
+		value.WriteRune(c)
+	case 'u':
+		// \u{1F600}
+		if !s.match('{') {
+			s.error("Expect '{' after '\\u'.")
+			return
+		}
+		start := s.current
+		for isHexDigit(s.peek()) {
+			s.advance()
+		}
+		code, err := strconv.ParseUint(s.source[start:s.current], 16, 32)
+		if err != nil || s.current-start > 6 || !utf8.ValidRune(rune(code)) {
+			s.error("Invalid Unicode escape.")
+		} else {
+			value.WriteRune(rune(code))
+		}
+		if !s.match('}') {
+			s.error("Expect '}' after Unicode escape.")
+		}
+	default:
+		s.error("Invalid escape sequence.")
+	}
+}
+
+func isHexDigit(c rune) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (s *Scanner) number() {
+	for isDigit(s.peek()) {
+		s.advance()
+	}
+
+	// Look for a fractional part.
+	if s.peek() == '.' && isDigit(s.peekNext()) {
+		// Consume the "."
+		s.advance()
+
+		for isDigit(s.peek()) {
+			s.advance()
+		}
+	}
+
+	// The digits always parse, but may be out of range.
+	f, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	if err != nil {
+		s.error("Number literal is out of range.")
+	}
+	s.addTokenL(TokenType_NUMBER, f)
+}
+
+func (s *Scanner) peekNext() rune {
+	if s.isAtEnd() {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return r
+}
+
+func (s *Scanner) identifier() {
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+
+	text := s.source[s.start:s.current]
+	t, ok := keywords[text]
+	if !ok {
+		t = TokenType_IDENTIFIER
+	}
+	s.addToken(t)
+}
+
+func isAlpha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+func isAlphaNumeric(c rune) bool {
+	return isAlpha(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,1457 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLox(t *testing.T) {
+
+	prog := `class Bacon {
+  eat() {
+    print "Crunch crunch crunch!";
+  }
+}
+
+print "After";
+Bacon().eat(); // Prints "Crunch crunch crunch!".
+print "Before";
+`
+	var stdout, stderr bytes.Buffer
+	err := New(WithStdout(&stdout), WithStderr(&stderr)).Run(prog)
+	require.Nil(t, err)
+	require.Equal(t, "After\nCrunch crunch crunch!\nBefore\n", stdout.String())
+	require.Empty(t, stderr.String())
+}
+
+func TestOutputSinks(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		var stdout, stderr bytes.Buffer
+		var diagnostics []error
+		opts := []Option{
+			WithStdout(&stdout),
+			WithStderr(&stderr),
+			WithDiagnostics(func(err error) { diagnostics = append(diagnostics, err) }),
+		}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		l := New(opts...)
+
+		err := l.Run("print 1;\nprint nil.x;")
+		require.IsType(t, &RuntimeError{}, err)
+		require.Equal(t, "1\n", stdout.String())
+		require.Equal(t, "Only instances have properties.\n[line 2] in script\n", stderr.String())
+
+		stderr.Reset()
+		err = l.Run("print 2;\nvar = 1;\nprint;")
+		require.IsType(t, &CompileError{}, err)
+		require.Equal(t, "1\n", stdout.String())
+		require.Equal(t, "[line 2] Error at '=': Expect variable name.\n[line 3] Error at ';': Expect expression.\n", stderr.String())
+
+		require.Len(t, diagnostics, 3)
+		require.Equal(t, err.(*CompileError).Errors[1], diagnostics[2])
+		require.IsType(t, &RuntimeError{}, diagnostics[0])
+	}
+}
+
+// runCaptured runs prog on a fresh Lox and returns what it printed,
+// including any errors it reported.
+func runCaptured(t *testing.T, prog string, useVM bool) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	opts := []Option{WithStdout(&out), WithStderr(&out)}
+	if useVM {
+		opts = append(opts, WithVM())
+	}
+	_ = New(opts...).Run(prog)
+	return out.String()
+}
+
+func TestVMMatchesInterpreter(t *testing.T) {
+	paths, err := filepath.Glob("*.lox")
+	require.Nil(t, err)
+
+	for _, path := range paths {
+		// prints elapsed time from clock()
+		if filepath.Base(path) == "fibrec.lox" {
+			continue
+		}
+
+		b, err := os.ReadFile(path)
+		require.Nil(t, err)
+
+		t.Run(path, func(t *testing.T) {
+			require.Equal(t, runCaptured(t, string(b), false), runCaptured(t, string(b), true))
+		})
+	}
+}
+
+func TestListIndexOutOfRange(t *testing.T) {
+	err := New().Run("var xs = [1, 2];\nprint xs[2];")
+	require.IsType(t, &RuntimeError{}, err)
+	require.Equal(t, "List index 2 out of range.\n[line 2] in script\n", err.(*RuntimeError).Traceback())
+}
+
+func TestCallInstance(t *testing.T) {
+	prog := `class A {}
+var a = A();
+print a();`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "Can only call functions and classes.\n[line 3] in script\n", runCaptured(t, prog, useVM))
+	}
+}
+
+func TestPrintList(t *testing.T) {
+	prog := `var xs = [1];
+xs.append(xs);
+print xs;
+var ys = [xs, xs];
+print ys;
+print [1, "1", "a\"b"];`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, `[1, [...]]
+[[1, [...]], [1, [...]]]
+[1, "1", "a\"b"]
+`, runCaptured(t, prog, useVM))
+	}
+}
+
+func TestPrintMap(t *testing.T) {
+	prog := `var m = {"1": 1, 1: 2};
+print m;
+m["self"] = m;
+m["list"] = [m];
+print m;`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, `{"1": 1, 1: 2}
+{"1": 1, 1: 2, "self": {...}, "list": [{...}]}
+`, runCaptured(t, prog, useVM))
+	}
+}
+
+func TestLoxMapKeys(t *testing.T) {
+	m := NewLoxMap()
+	m.Set(nil, "a", 1.0)
+	m.Set(nil, 0.0, "zero")
+	m.Set(nil, "b", 2.0)
+
+	require.Equal(t, "zero", m.Get(nil, math.Copysign(0, -1)))
+	require.True(t, m.delete(nil, "a"))
+	require.False(t, m.delete(nil, "a"))
+	require.Equal(t, 2.0, m.Get(nil, "b"))
+	require.Equal(t, `{0: "zero", "b": 2}`, m.String())
+	require.Panics(t, func() { m.Set(nil, NewLoxList(nil), 1.0) })
+	require.Panics(t, func() { m.Set(nil, math.NaN(), 1.0) })
+	require.Equal(t, `{0: "zero", "b": 2}`, m.String())
+
+	for _, opts := range [][]Option{nil, {WithVM()}} {
+		err := New(opts...).Run("var m = {};\nm[0/0] = 1;")
+		require.IsType(t, &RuntimeError{}, err)
+		require.Equal(t, "NaN can't be a map key.\n[line 2] in script\n", err.(*RuntimeError).Traceback())
+	}
+}
+
+func TestRuntimeErrorTraceback(t *testing.T) {
+	prog := `fun inner() {
+  return 1 + nil;
+}
+fun outer() {
+  inner();
+}
+outer();`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, `Operands must be two numbers or two strings.
+[line 2] in inner()
+[line 5] in outer()
+[line 7] in script
+`, runCaptured(t, prog, useVM))
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	l := New(WithGlobals(map[string]any{
+		"boom": NewNativeFunction(0, func(arguments []any) any {
+			var m map[string]int
+			m["x"] = 1
+			return nil
+		}),
+	}))
+
+	err := l.Run("boom();")
+	require.NotNil(t, err)
+	_, isRuntimeError := err.(*RuntimeError)
+	require.False(t, isRuntimeError)
+	require.Contains(t, err.Error(), "internal error: assignment to entry in nil map")
+
+	// A Go panic isn't a Lox error, so finally blocks don't run for it.
+	var stdout bytes.Buffer
+	l = New(WithStdout(&stdout), WithGlobals(map[string]any{"boom": l.globals["boom"]}))
+	err = l.Run(`try { boom(); } finally { print "finally"; }`)
+	require.Contains(t, err.Error(), "internal error: assignment to entry in nil map")
+	require.Empty(t, stdout.String())
+}
+
+func TestBadGlobal(t *testing.T) {
+	l := New(WithGlobals(map[string]any{"f": func(ch chan int) {}}))
+	err := l.Run("print 1;")
+	require.EqualError(t, err, "lox: can't convert Lox values to chan int")
+	_, err = l.Eval("1")
+	require.EqualError(t, err, "lox: can't convert Lox values to chan int")
+}
+
+func TestTracebackThroughFinally(t *testing.T) {
+	prog := `fun inner() { return 1 + nil; }
+fun outer() {
+  try { inner(); } finally { print "finally"; }
+}
+outer();`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, `finally
+Operands must be two numbers or two strings.
+[line 1] in inner()
+[line 3] in outer()
+[line 5] in script
+`, runCaptured(t, prog, useVM))
+	}
+}
+
+func TestTryFinally(t *testing.T) {
+	prog := `fun f() {
+  try {
+    throw "thrown";
+  } catch (e) {
+    return e;
+  } finally {
+    print "finally";
+  }
+}
+print f();
+
+try {
+  print [][0];
+} catch (e) {
+  print e.message;
+  print e.line;
+}`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "finally\nthrown\nList index 0 out of range.\n13\n", runCaptured(t, prog, useVM))
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	prog := `fun g() { return "g"; }
+fun f() {
+  try {
+    return "f";
+  } finally {
+    g();
+  }
+}
+print f();
+
+fun h() {
+  try {
+    return "try";
+  } finally {
+    return "finally";
+  }
+}
+print h();
+
+for (var i = 0; i < 4; i = i + 1) {
+  try {
+    if (i == 1) continue;
+    if (i == 3) break;
+    print i;
+  } finally {
+    print "next";
+  }
+}
+
+class Foo {
+  init(early) {
+    this.early = early;
+    if (early) return;
+    this.late = true;
+  }
+}
+var foo = Foo(true);
+print foo.init(true) == foo;
+print Foo(false).late;`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "f\nfinally\n0\nnext\nnext\n2\nnext\nnext\ntrue\ntrue\n", runCaptured(t, prog, useVM))
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/counter.lox": `var count = 0;
+fun bump() { count = count + 1; }
+export fun next() { bump(); return count; }
+export var name = "counter";
+print "loaded";`,
+		"lib/shapes.lox": `import "counter.lox" as counter;
+export class Square {
+  init(side) { this.side = side; counter.next(); }
+}`,
+		"cycle/a.lox": `import "b.lox" as b;`,
+		"cycle/b.lox": `import "a.lox" as a;`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, os.WriteFile(path, []byte(source), 0o644))
+	}
+
+	prog := `import "lib/shapes.lox" as shapes;
+import { next, name } from "lib/counter.lox";
+shapes.Square(2);
+print next();
+print name;
+try {
+  print shapes.counter;
+} catch (e) {
+  print e.message;
+}
+import "cycle/a.lox";`
+
+	for _, useVM := range []bool{false, true} {
+		var buf bytes.Buffer
+		opts := []Option{WithStdout(&buf), WithStderr(&buf)}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		l := New(opts...)
+		l.importing = []string{filepath.Join(dir, "main.lox")}
+
+		err := l.run(context.Background(), prog)
+		require.IsType(t, &RuntimeError{}, err)
+		require.Equal(t, []string{filepath.Join(dir, "main.lox")}, l.importing)
+
+		out := buf.String()
+		require.Contains(t, out, "loaded\n2\ncounter\nModule '")
+		require.Contains(t, out, "' does not export 'counter'.\n")
+		require.Contains(t, out, "a.lox -> ")
+		require.Equal(t, 1, strings.Count(out, "loaded"))
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib.lox": `export fun fail() {
+  return nil + 1;
+}`,
+		"a.lox": `import "b.lox";`,
+		"b.lox": `var x = 1;
+import "a.lox";`,
+	}
+	for name, source := range files {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644))
+	}
+	main := filepath.Join(dir, "main.lox")
+
+	type frame struct {
+		file string
+		line int
+	}
+	for _, opts := range [][]Option{nil, {WithVM()}} {
+		run := func(prog string) error {
+			l := New(append([]Option{WithStdout(io.Discard), WithStderr(io.Discard)}, opts...)...)
+			l.importing = []string{main}
+			return l.run(context.Background(), prog)
+		}
+		frames := func(err error) []frame {
+			var re *RuntimeError
+			require.ErrorAs(t, err, &re)
+			ret := []frame{}
+			for _, f := range re.Trace() {
+				ret = append(ret, frame{filepath.Base(f.File()), f.Line()})
+			}
+			return ret
+		}
+
+		// Each frame names the file it is in, so the import that closes a
+		// cycle can be found.
+		err := run("import { fail } from \"lib.lox\";\nfail();")
+		require.Equal(t, []frame{{"lib.lox", 2}, {"main.lox", 2}}, frames(err))
+		require.Contains(t, err.(*RuntimeError).Traceback(), "[line 2] in fail() (")
+
+		err = run("\nimport \"a.lox\";")
+		require.Equal(t, []frame{{"b.lox", 2}, {"a.lox", 1}, {"main.lox", 2}}, frames(err))
+
+		err = run("fun f() {\n  import \"lib.lox\" as lib;\n  print lib;\n}")
+		require.Equal(t, "[line 2] Error at 'import': Can only import at top level.", err.Error())
+	}
+}
+
+func TestHostCallErrors(t *testing.T) {
+	prog := `fun apply(f, x) { return f(x); }
+fun each(f) {
+  apply(f, 1);
+  print "unreachable";
+}`
+
+	for _, opts := range [][]Option{nil, {WithVM()}} {
+		l := New(append([]Option{WithStdout(io.Discard)}, opts...)...)
+		require.Nil(t, l.Define("check", func(n int) (int, error) {
+			return 0, fmt.Errorf("bad %d", n)
+		}))
+		require.Nil(t, l.Define("callBack", func(n int) (any, error) {
+			check, err := l.Global("check")
+			require.Nil(t, err)
+			_, err = check.Call(n + 1)
+			return nil, err
+		}))
+		require.Nil(t, l.Run(prog))
+
+		// A Go func returning an error, whether called by Lox code the host
+		// called or by the host directly, gives the host the error.
+		each, err := l.Global("each")
+		require.Nil(t, err)
+		check, err := l.Global("check")
+		require.Nil(t, err)
+		_, err = each.Call(check)
+		require.IsType(t, &RuntimeError{}, err)
+		require.True(t, strings.HasPrefix(err.(*RuntimeError).Traceback(), "bad 1\n[line 1] in apply()\n[line 3] in each()\n"))
+
+		_, err = check.Call(3)
+		require.IsType(t, &RuntimeError{}, err)
+		require.EqualError(t, err, "bad 3")
+
+		_, err = check.Call("x")
+		require.EqualError(t, err, "Argument 1 must be an integer.")
+
+		// So does one called back from a native, and Lox can still run.
+		callBack, err := l.Global("callBack")
+		require.Nil(t, err)
+		_, err = each.Call(callBack)
+		require.IsType(t, &RuntimeError{}, err)
+		require.EqualError(t, err, "bad 2")
+
+		v, err := l.Eval("1 + 1")
+		require.Nil(t, err)
+		require.Equal(t, 2.0, v)
+	}
+}
+
+func TestGoFunctionCallback(t *testing.T) {
+	prog := `var n = 10;
+print mapList([1, 2], x => x + n);
+print mapList([], fun (x) { return x; });`
+
+	for _, opts := range [][]Option{nil, {WithVM()}} {
+		var out bytes.Buffer
+		l := New(append([]Option{WithStdout(&out)}, opts...)...)
+		require.Nil(t, l.Define("mapList", func(xs []Value, f Value) ([]Value, error) {
+			results := make([]Value, len(xs))
+			for i, x := range xs {
+				result, err := f.Call(x)
+				if err != nil {
+					return nil, err
+				}
+				results[i] = result
+			}
+			return results, nil
+		}))
+		require.Nil(t, l.Run(prog))
+		require.Equal(t, "[11, 12]\n[]\n", out.String())
+	}
+}
+
+func TestLambda(t *testing.T) {
+	prog := `fun twice(f, x) { return f(f(x)); }
+print twice(fun (n) { return n + 1; }, 0);
+print twice(n => n * 3, 1);
+var pair = (a, b) => [a, b];
+print pair(1, 2);
+print pair;`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "2\n9\n[1, 2]\n<fn anonymous@4>\n", runCaptured(t, prog, useVM))
+	}
+
+	// Neither form takes a trailing comma after the parameters.
+	for _, source := range []string{"var f = (a,) => a;", "var f = fun (a,) {};"} {
+		err := New().Run(source)
+		require.EqualError(t, err, "[line 1] Error at ')': Expect parameter name.", source)
+	}
+}
+
+func TestStringEscapesAndInterpolation(t *testing.T) {
+	prog := `var who = "wörld";
+print "a\tb\n\"${who}\" \${x} \u{263A}";
+print "${1 + 2} ${[who.len(), who[1]]} ${"x${"y"}"}";`
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "a\tb\n\"wörld\" ${x} ☺\n3 [5, \"ö\"] xy\n", runCaptured(t, prog, useVM))
+	}
+}
+
+func TestScanner(t *testing.T) {
+	l := New()
+	tokens := NewScanner(l, `größe = "é${x}";`).scanTokens()
+	require.False(t, l.hadError)
+
+	types := []TokenType{}
+	for _, token := range tokens {
+		types = append(types, token.t)
+	}
+	require.Equal(t, []TokenType{
+		TokenType_IDENTIFIER, TokenType_EQUAL, TokenType_INTERPOLATION, TokenType_IDENTIFIER,
+		TokenType_STRING, TokenType_SEMICOLON, TokenType_EOF,
+	}, types)
+	require.Equal(t, "größe", tokens[0].lexeme)
+	require.Equal(t, "é", tokens[2].literal)
+	require.Equal(t, 7, tokens[1].column)
+	require.Equal(t, 9, tokens[2].column)
+
+	tokens = NewScanner(l, "\"a\nb\" c\n  d").scanTokens()
+	require.Equal(t, []int{1, 4, 3}, []int{tokens[0].column, tokens[1].column, tokens[2].column})
+
+	err := New().Run("print 1;\nprint " + strings.Repeat("9", 400) + ";")
+	require.EqualError(t, err, "[line 2] Error: Number literal is out of range.")
+}
+
+func TestEmbed(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		var out bytes.Buffer
+		opts := []Option{WithStdout(&out), WithGlobals(map[string]any{"greeting": "hi"})}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		l := New(opts...)
+
+		require.Nil(t, l.Run(`var x = 20; print greeting;`))
+		require.Equal(t, "hi\n", out.String())
+
+		v, err := l.Eval("x * 2 + 2")
+		require.Nil(t, err)
+		require.Equal(t, 42.0, v)
+
+		_, err = l.Eval("x +")
+		var compileErr *CompileError
+		require.ErrorAs(t, err, &compileErr)
+		require.Equal(t, "[line 1] Error at end: Expect expression.", compileErr.Error())
+
+		err = l.Run(`throw "oops";`)
+		var runtimeErr *RuntimeError
+		require.ErrorAs(t, err, &runtimeErr)
+		require.Equal(t, "oops", runtimeErr.Value())
+		require.Equal(t, 1, runtimeErr.Line())
+
+		// The Lox is still usable after an error.
+		v, err = l.Eval("x")
+		require.Nil(t, err)
+		require.Equal(t, 20.0, v)
+	}
+}
+
+func TestGoFunction(t *testing.T) {
+	prog := `print repeat("ab", 3);
+print sum(1, 2, 3);
+print sum();
+print split("a,b");
+print describe(nil, [1, 2]);
+try {
+  repeat("x", -1);
+} catch (e) {
+  print e.message;
+}
+try {
+  repeat("x", 1.5);
+} catch (e) {
+  print e.message;
+}
+repeat("x");`
+
+	globals := map[string]any{
+		"repeat": func(s string, n int) (string, error) {
+			if n < 0 {
+				return "", fmt.Errorf("count must not be negative")
+			}
+			return strings.Repeat(s, n), nil
+		},
+		"sum": func(ns ...float64) float64 {
+			total := 0.0
+			for _, n := range ns {
+				total += n
+			}
+			return total
+		},
+		"split": func(s string) []string {
+			return strings.Split(s, ",")
+		},
+		"describe": func(p *LoxInstance, xs []int) map[string]any {
+			return map[string]any{"instance": p == nil, "len": len(xs)}
+		},
+	}
+
+	for _, useVM := range []bool{false, true} {
+		var out bytes.Buffer
+		opts := []Option{WithStdout(&out), WithStderr(&out), WithGlobals(globals)}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		err := New(opts...).Run(prog)
+		require.IsType(t, &RuntimeError{}, err)
+		require.Equal(t, `ababab
+6
+0
+["a", "b"]
+{"instance": true, "len": 2}
+count must not be negative
+Argument 2 must be an integer.
+Expected 2 arguments but got 1.
+[line 16] in script
+`, out.String())
+	}
+
+	_, err := NewGoFunction(func(c chan int) {})
+	require.NotNil(t, err)
+	_, err = NewGoFunction(func() (int, int) { return 0, 0 })
+	require.NotNil(t, err)
+
+	// Integers are accepted up to the bounds of their type, and values just
+	// past them are rejected rather than wrapped.
+	for _, v := range []any{int8(0), int16(0), int32(0), int64(0), uint8(0), uint16(0), uint32(0), uint64(0)} {
+		typ := reflect.TypeOf(v)
+		lo, hi := 0.0, math.Ldexp(1, typ.Bits())
+		if typ.Kind() < reflect.Uint {
+			lo, hi = -math.Ldexp(1, typ.Bits()-1), math.Ldexp(1, typ.Bits()-1)
+		}
+		// The nearest integers inside and outside the bounds, which for the
+		// 64-bit types are further apart than 1.
+		bottom, top := math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, 0)
+		if typ.Bits() < 53 {
+			bottom, top = lo-1, hi-1
+		}
+
+		for _, f := range []float64{lo, top} {
+			value, ok := fromLox(nil, f, typ)
+			require.True(t, ok, "%v %s", f, typ)
+			require.Equal(t, f, value.Convert(reflect.TypeOf(0.0)).Float())
+		}
+		for _, f := range []float64{bottom, hi, math.Inf(1), math.NaN()} {
+			_, ok := fromLox(nil, f, typ)
+			require.False(t, ok, "%v %s", f, typ)
+		}
+	}
+}
+
+func TestHostCalls(t *testing.T) {
+	prog := `class Point {
+  init(x, y) { this.x = x; this.y = y; }
+  norm() { return this.x * this.x + this.y * this.y; }
+}
+fun apply(f, x) { return f(x); }
+fun fail() { throw "failed"; }
+fun guarded() {
+  try {
+    return callHost();
+  } catch (e) {
+    return "caught " + e;
+  }
+}`
+
+	for _, useVM := range []bool{false, true} {
+		opts := []Option{WithStdout(io.Discard)}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		l := New(opts...)
+		require.Nil(t, l.Define("callHost", func() (any, error) {
+			fail, err := l.Global("fail")
+			require.Nil(t, err)
+			_, err = fail.Call()
+			return nil, err
+		}))
+		require.Nil(t, l.Run(prog))
+
+		pointClass, err := l.Global("Point")
+		require.Nil(t, err)
+		p, err := pointClass.Call(3, 4)
+		require.Nil(t, err)
+		require.Equal(t, "Point instance", p.String())
+
+		x, err := p.Get("x")
+		require.Nil(t, err)
+		n, err := As[int](x)
+		require.Nil(t, err)
+		require.Equal(t, 3, n)
+
+		require.Nil(t, p.Set("x", 6))
+		norm, err := p.Get("norm")
+		require.Nil(t, err)
+		result, err := norm.Call()
+		require.Nil(t, err)
+		require.Equal(t, 52.0, result.Interface())
+
+		apply, err := l.Global("apply")
+		require.Nil(t, err)
+		result, err = apply.Call(func(s string) string { return s + "!" }, "hi")
+		require.Nil(t, err)
+		s, err := As[string](result)
+		require.Nil(t, err)
+		require.Equal(t, "hi!", s)
+
+		_, err = As[bool](result)
+		require.NotNil(t, err)
+
+		// Errors come back as values, and Lox code around a native that
+		// calls back into Lox can still catch them.
+		_, err = pointClass.Call(1)
+		require.EqualError(t, err, "Expected 2 arguments but got 1.")
+		_, err = p.Get("z")
+		require.EqualError(t, err, "Undefined property 'z'.")
+
+		guarded, err := l.Global("guarded")
+		require.Nil(t, err)
+		result, err = guarded.Call()
+		require.Nil(t, err)
+		require.Equal(t, "caught failed", result.Interface())
+
+		v, err := l.Eval("apply(x => x * 2, 21)")
+		require.Nil(t, err)
+		require.Equal(t, 42.0, v)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, useVM := range []bool{false, true} {
+		var out bytes.Buffer
+		opts := []Option{WithStdout(&out), WithMaxSteps(10000), WithMaxCallDepth(100)}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		l := New(opts...)
+
+		// A step limit can't be caught, and finally blocks don't run.
+		err := l.Run(`try {
+  while (true) {}
+} catch (e) {
+  print "caught";
+} finally {
+  print "finally";
+}`)
+		require.ErrorIs(t, err, ErrStepLimit)
+		require.EqualError(t, err, "Step limit exceeded.")
+		require.Equal(t, 2, err.(*RuntimeError).Line())
+		require.Empty(t, out.String())
+
+		// A stack overflow can.
+		require.Nil(t, l.Run(`fun f(n) { return f(n + 1); }
+try {
+  f(0);
+} catch (e) {
+  print e.message;
+}`))
+		require.Equal(t, "Stack overflow.\n", out.String())
+
+		err = l.Run(`f(0);`)
+		require.ErrorIs(t, err, ErrStackOverflow)
+		trace := err.(*RuntimeError).Trace()
+		require.Len(t, trace, 100)
+		require.Equal(t, "f", trace[0].Function())
+		require.Equal(t, 1, trace[0].Line())
+		require.Equal(t, "Stack overflow.\n[line 1] in f()\n[previous line repeated 98 more times]\n[line 1] in script\n", err.(*RuntimeError).Traceback())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = New(opts[2:]...).EvalContext(ctx, "fun () { while (true) {} }()")
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.EqualError(t, err, "Run cancelled: context deadline exceeded.")
+
+		// Hitting a limit leaves the Lox ready for the next run.
+		v, err := l.Eval("f")
+		require.Nil(t, err)
+		result, err := l.ValueOf(v)
+		require.Nil(t, err)
+		_, err = result.CallContext(context.Background(), 0)
+		require.ErrorIs(t, err, ErrStackOverflow)
+		v, err = l.Eval("1 + 2")
+		require.Nil(t, err)
+		require.Equal(t, 3.0, v)
+	}
+}
+
+func TestTooManyLocals(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("fun f() {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&sb, "  var v%d;\n", i)
+	}
+	sb.WriteString("}\n")
+
+	err := New(WithVM()).Run(sb.String())
+	var compileErr *CompileError
+	require.ErrorAs(t, err, &compileErr)
+	require.Len(t, compileErr.Errors, 1)
+	require.Equal(t, "Too many local variables in function.", compileErr.Errors[0].Message)
+}
+
+func TestValueSemantics(t *testing.T) {
+	for _, v := range []any{nil, false} {
+		require.False(t, isTruthy(v), "%v", v)
+	}
+	for _, v := range []any{true, 0.0, "", NewLoxList(nil), NewLoxMap()} {
+		require.True(t, isTruthy(v), "%v", v)
+	}
+
+	require.True(t, isEqual(nil, nil))
+	require.False(t, isEqual(nil, false))
+	require.False(t, isEqual(1.0, "1"))
+	require.False(t, isEqual(math.NaN(), math.NaN()))
+	list := NewLoxList(nil)
+	require.True(t, isEqual(list, list))
+	require.False(t, isEqual(list, NewLoxList(nil)))
+	// Host values that == can't compare are unequal rather than a panic.
+	type hostValue struct{ xs []int }
+	require.False(t, isEqual(hostValue{}, hostValue{}))
+
+	require.Equal(t, "nil", stringify(nil))
+	require.Equal(t, "3", stringify(3.0))
+	require.Equal(t, "0.1", stringify(0.1))
+	require.Equal(t, `[nil, "a"]`, stringify(NewLoxList([]any{nil, "a"})))
+
+	for _, useVM := range []bool{false, true} {
+		require.Equal(t, "Operands must be two numbers or two strings.\n[line 1] in script\n", runCaptured(t, `print "a" + 1;`, useVM))
+	}
+}
+
+func TestREPL(t *testing.T) {
+	input := `var a = 1;
+fun add(x) {
+  return x + a;
+}
+add(2)
+print nil.x;
+a = a + 1;
+"two
+lines"
+:ast -a * (1 + "s")
+:env
+:history
+:quit
+print "unreachable";
+`
+
+	for _, useVM := range []bool{false, true} {
+		var out, stderr bytes.Buffer
+		opts := []Option{WithStdout(&out), WithStderr(&stderr)}
+		if useVM {
+			opts = append(opts, WithVM())
+		}
+		history := filepath.Join(t.TempDir(), "history")
+		require.Nil(t, os.WriteFile(history, []byte(`"print 0;"`+"\n"), 0o600))
+
+		repl := NewREPL(New(opts...), strings.NewReader(input), &out)
+		require.Nil(t, repl.LoadHistory(history))
+		require.Nil(t, repl.Run())
+
+		require.Equal(t, `> > ... ... > 3
+> > 2
+> ... two
+lines
+> (* (- a) (group (+ 1 "s")))
+> a = 2
+add = <fn add>
+clock = <native fn>
+>    1  print 0;
+   2  var a = 1;
+   3  fun add(x) {
+  return x + a;
+}
+   4  add(2)
+   5  print nil.x;
+   6  a = a + 1;
+   7  "two
+lines"
+   8  :ast -a * (1 + "s")
+   9  :env
+  10  :history
+> `, out.String())
+		require.Equal(t, "Only instances have properties.\n[line 1] in script\n", stderr.String())
+
+		b, err := os.ReadFile(history)
+		require.Nil(t, err)
+		require.Equal(t, 11, strings.Count(string(b), "\n"))
+		require.Contains(t, string(b), `"fun add(x) {\n  return x + a;\n}"`)
+	}
+
+	// A line ending in a brace can still leave off its semicolon.
+	for _, opts := range [][]Option{nil, {WithVM()}} {
+		var out bytes.Buffer
+		l := New(append([]Option{WithStdout(&out)}, opts...)...)
+		for _, line := range []string{
+			`var f = fun () {}`,
+			`var m = {}`,
+			`print {"a": 1}`,
+			`var x`,
+			`x = fun (a) { return a; }`,
+			`fun g() {}`,
+			`class C {}`,
+			`{ print x(2); }`,
+		} {
+			_, _, err := l.runLine(line)
+			require.Nil(t, err, line)
+		}
+		require.Equal(t, "{\"a\": 1}\n2\n", out.String())
+
+		_, _, err := l.runLine("print 1 +")
+		require.EqualError(t, err, "[line 1] Error at end: Expect expression.")
+	}
+
+	require.True(t, incomplete("fun f() {\n"))
+	require.True(t, incomplete(`print "a`))
+	require.True(t, incomplete(`print [1,`))
+	require.False(t, incomplete("print 1; }"))
+}
+
+func TestAnalyze(t *testing.T) {
+	source := `var a = 1;
+fun f(x) {
+  return x + a + b;
+}
+var b = "é" + undefined;
+{ var c = c; }
+`
+	analysis := Analyze(source)
+
+	require.Equal(t, []Diagnostic{
+		{Span: Span{Line: 6, Column: 11, Offset: 80, Length: 1}, Message: "Can't read local variable in its own initializer."},
+	}, analysis.Diagnostics)
+
+	require.Len(t, analysis.Symbols, 3)
+	require.Equal(t, "fun f(x)", analysis.Symbols[1].Detail)
+	require.Equal(t, Span{Line: 2, Column: 5, Offset: 15, Length: 1}, analysis.Symbols[1].Span)
+
+	// b is used before it is declared, and undefined is never declared.
+	names := map[string]*Symbol{}
+	for _, reference := range analysis.References {
+		span := reference.Span
+		names[source[span.Offset:span.Offset+span.Length]] = reference.Symbol
+	}
+	require.Equal(t, SymbolKind_PARAMETER, names["x"].Kind)
+	require.Equal(t, analysis.Symbols[0], names["a"])
+	require.Equal(t, analysis.Symbols[2], names["b"])
+	require.Nil(t, names["undefined"])
+
+	require.Equal(t, analysis.Symbols[0], analysis.SymbolAt(35))
+	require.Equal(t, []Span{{Line: 3, Column: 14, Offset: 35, Length: 1}}, analysis.ReferencesTo(analysis.Symbols[0]))
+}
+
+func TestDebugger(t *testing.T) {
+	source := `class Shape {
+  area() { return 0; }
+}
+class Square < Shape {
+  init(side) { this.side = side; }
+  area() {
+    var base = super.area();
+    return base + this.side * this.side;
+  }
+}
+fun counter() {
+  var count = 0;
+  fun increment() {
+    count = count + 1;
+    return count;
+  }
+  return increment;
+}
+var next = counter();
+next();
+print next();
+print Square(3).area();
+print "done";
+`
+
+	type stopped struct {
+		reason StopReason
+		line   int
+		frames string
+	}
+	var stops []stopped
+	var actions []func(stop *Stop) Resume
+
+	d := NewDebugger(func(stop *Stop) Resume {
+		frames := []string{}
+		for _, frame := range stop.Frames() {
+			frames = append(frames, fmt.Sprintf("%s:%d", frame, frame.Line))
+		}
+		stops = append(stops, stopped{stop.Reason, stop.Line, strings.Join(frames, " ")})
+
+		action := actions[0]
+		actions = actions[1:]
+		return action(stop)
+	})
+	d.SetBreakpoint("", 14)
+	d.SetBreakpoint("", 8)
+
+	resume := func(resume Resume) func(stop *Stop) Resume {
+		return func(stop *Stop) Resume { return resume }
+	}
+	actions = []func(stop *Stop) Resume{
+		// In increment(), count is in the closure and next is global.
+		func(stop *Stop) Resume {
+			scopes, err := stop.Scopes(0)
+			require.Nil(t, err)
+			names := []string{}
+			for _, scope := range scopes {
+				names = append(names, scope.Name)
+			}
+			require.Equal(t, []string{"Locals", "Enclosing", "Globals"}, names)
+			require.Empty(t, scopes[0].Variables)
+			require.Equal(t, []Binding{{"count", 0.0}, {"increment", scopes[1].Variables[1].Value}}, scopes[1].Variables)
+
+			value, err := stop.Evaluate(0, "count + 10")
+			require.Nil(t, err)
+			require.Equal(t, 10.0, value)
+			_, err = stop.Evaluate(0, "undefined")
+			require.EqualError(t, err, "Undefined variable 'undefined'.")
+			_, err = stop.Evaluate(0, "this")
+			require.EqualError(t, err, "[line 1] Error at 'this': Can't use 'this' outside of a class.")
+
+			// The program sees the new count.
+			value, err = stop.SetVariable(0, 1, "count", "count + 40")
+			require.Nil(t, err)
+			require.Equal(t, 40.0, value)
+			return Resume_STEP_OVER
+		},
+		resume(Resume_STEP_OUT),
+		func(stop *Stop) Resume {
+			d.ClearBreakpoint("", 14)
+			return Resume_CONTINUE
+		},
+		// In Square.area(), super and this are bound in scopes of their own.
+		func(stop *Stop) Resume {
+			scopes, err := stop.Scopes(0)
+			require.Nil(t, err)
+			require.Equal(t, "this", scopes[1].Name)
+			require.Equal(t, "super", scopes[2].Name)
+			require.Equal(t, []Binding{{"side", 3.0}}, Members(scopes[1].Variables[0].Value))
+
+			value, err := stop.Evaluate(0, "super.area() + this.side + base")
+			require.Nil(t, err)
+			require.Equal(t, 3.0, value)
+			_, err = stop.Evaluate(0, "this.side = 4")
+			require.Nil(t, err)
+
+			// Frames further out see their own scopes.
+			value, err = stop.Evaluate(1, "next()")
+			require.Nil(t, err)
+			require.Equal(t, 43.0, value)
+			return Resume_STEP_IN
+		},
+		resume(Resume_STEP_IN),
+	}
+
+	var stdout bytes.Buffer
+	require.Nil(t, New(WithStdout(&stdout), WithDebugger(d)).Run(source))
+	require.Equal(t, "42\n16\ndone\n", stdout.String())
+	require.Empty(t, actions)
+	require.Equal(t, []stopped{
+		{StopReason_BREAKPOINT, 14, "increment():14 script:20"},
+		{StopReason_STEP, 15, "increment():15 script:20"},
+		{StopReason_STEP, 21, "script:21"},
+		{StopReason_BREAKPOINT, 8, "area():8 script:22"},
+		{StopReason_STEP, 23, "script:23"},
+	}, stops)
+
+	// Breakpoints stop again each time a loop comes back to them.
+	hits := 0
+	d = NewDebugger(func(stop *Stop) Resume {
+		hits++
+		return Resume_CONTINUE
+	})
+	d.SetBreakpoint("", 2)
+	require.Nil(t, New(WithDebugger(d)).Run("var i = 0;\nwhile (i < 3) { i = i + 1; }"))
+	require.Equal(t, 3, hits)
+
+	// A stopped program can't catch the error.
+	d = NewDebugger(func(stop *Stop) Resume { return Resume_STOP })
+	d.Pause()
+	stdout.Reset()
+	err := New(WithStdout(&stdout), WithDebugger(d)).Run(`try { print 1; } catch (e) { print 2; }`)
+	require.ErrorIs(t, err, ErrStopped)
+	require.Empty(t, stdout.String())
+}
+
+func TestDebugConsole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greet.lox")
+	require.Nil(t, os.WriteFile(path, []byte(`fun greet(name) {
+  var greeting = "Hello, " + name;
+  print greeting;
+}
+greet("world");
+greet("lox");
+`), 0o644))
+
+	input := `break 3
+step
+step
+bt
+vars
+up
+print name + "!"
+down
+continue
+set greeting = "Bye"
+clear 3
+break
+continue
+`
+	var out, stdout bytes.Buffer
+	console := NewDebugConsole(strings.NewReader(input), &out)
+	err := New(WithStdout(&stdout), WithDebugger(console.Debugger())).RunFile(path)
+	require.Nil(t, err)
+	require.Equal(t, "Bye\nHello, lox\n", stdout.String())
+
+	file := displayPath(path)
+	require.Equal(t, fmt.Sprintf(`Stopped in script at %[1]s:1
+=>    1  fun greet(name) {
+(debug) Breakpoint at %[1]s:3
+(debug) Stopped in script at %[1]s:5
+=>    5  greet("world");
+(debug) Stopped in greet() at %[1]s:2
+=>    2    var greeting = "Hello, " + name;
+(debug) * #0 greet() at %[1]s:2
+  #1 script at %[1]s:5
+(debug) Locals:
+  name = "world"
+Globals:
+  clock = <native fn>
+  greet = <fn greet>
+(debug) #1 script at %[1]s:5
+(debug) Undefined variable 'name'.
+(debug) #0 greet() at %[1]s:2
+(debug) Breakpoint in greet() at %[1]s:3
+=>    3    print greeting;
+(debug) "Bye"
+(debug) (debug) (debug) `, file), out.String())
+}
+
+func TestFormat(t *testing.T) {
+	source := `// Counts.
+
+var n=0;   // trailing
+for(var i=0;i<3;i=i+1) n=n+i;
+for(;;){break;}
+class A < B { init(a){ this.a=-a; } // after init
+
+
+  get() { return super.get(); }
+}
+var l = [
+  1, // one
+  2
+];
+var m = {"a": 1,};
+var f = (x) => x*2;
+if (n) { print "${n + 1}"; } else if (!n) print "no";
+try { throw "x"; } catch (e) {
+  // nothing
+}
+`
+	expected := `// Counts.
+
+var n = 0; // trailing
+for (var i = 0; i < 3; i = i + 1) n = n + i;
+for (;;) {
+  break;
+}
+class A < B {
+  init(a) {
+    this.a = -a;
+  } // after init
+
+  get() {
+    return super.get();
+  }
+}
+var l = [
+  1, // one
+  2,
+];
+var m = {"a": 1};
+var f = x => x * 2;
+if (n) {
+  print "${n + 1}";
+} else if (!n) print "no";
+try {
+  throw "x";
+} catch (e) {
+  // nothing
+}
+`
+	formatted, err := Format(source)
+	require.Nil(t, err)
+	require.Equal(t, expected, formatted)
+
+	again, err := Format(formatted)
+	require.Nil(t, err)
+	require.Equal(t, formatted, again)
+
+	_, err = Format("print (1;")
+	require.Equal(t, "[line 1] Error at ';': Expect ')' after expression.", err.Error())
+}
+
+func TestFormatCorpus(t *testing.T) {
+	paths, err := filepath.Glob("*.lox")
+	require.Nil(t, err)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		require.Nil(t, err)
+
+		formatted, err := Format(string(b))
+		require.Nil(t, err, path)
+		again, err := Format(formatted)
+		require.Nil(t, err, path)
+		require.Equal(t, formatted, again, path)
+
+		// Formatting keeps every comment and doesn't change what runs.
+		require.Equal(t, strings.Count(string(b), "//"), strings.Count(formatted, "//"), path)
+		if strings.Contains(string(b), "clock()") {
+			continue
+		}
+		var want, got bytes.Buffer
+		New(WithStdout(&want)).Run(string(b))
+		New(WithStdout(&got)).Run(formatted)
+		// Anonymous functions are named after the line they are on.
+		anonymous := regexp.MustCompile(`anonymous@\d+`)
+		require.Equal(t, anonymous.ReplaceAllString(want.String(), "anonymous"), anonymous.ReplaceAllString(got.String(), "anonymous"), path)
+	}
+}
+
+func TestDump(t *testing.T) {
+	source := `var a = 1;
+fun f(x, y) {
+  for (var i = 0; i < x; i = i + 1) print a + i;
+  return () => x + y;
+}
+`
+	var out bytes.Buffer
+	require.Nil(t, DumpAST(&out, source, DumpFormat_SEXPR, true))
+	require.Equal(t, `(var a 1)
+(fun f (x y)
+  (block
+    (var i 0)
+    (while (< i@0:0 x@1:0)
+      (print (+ a i@0:0))
+      (increment (= i@0:0 (+ i@0:0 1)))))
+  (return (fun ()
+    (return (+ x@1:0 y@1:1)))))
+`, out.String())
+
+	out.Reset()
+	require.Nil(t, DumpAST(&out, "print -x;", DumpFormat_JSON, false))
+	var tree []map[string]any
+	require.Nil(t, json.Unmarshal(out.Bytes(), &tree))
+	require.Equal(t, []map[string]any{{
+		"type":    "Print",
+		"line":    1.0,
+		"column":  1.0,
+		"keyword": "print",
+		"expression": map[string]any{
+			"type":     "Unary",
+			"line":     1.0,
+			"column":   7.0,
+			"operator": "-",
+			"right":    map[string]any{"type": "Variable", "line": 1.0, "column": 8.0, "name": "x"},
+		},
+	}}, tree)
+
+	out.Reset()
+	require.Nil(t, DumpAST(&out, "fun f(x) { return x; }", DumpFormat_JSON, true))
+	require.Contains(t, out.String(), `"depth": 0,`)
+	require.Contains(t, out.String(), `"slot": 0,`)
+
+	out.Reset()
+	require.Nil(t, DumpTokens(&out, `x = "a";`, DumpFormat_SEXPR))
+	require.Equal(t, `(IDENTIFIER "x" 1:1)
+(EQUAL "=" 1:3)
+(STRING "\"a\"" "a" 1:5)
+(SEMICOLON ";" 1:8)
+(EOF "" 1:9)
+`, out.String())
+
+	var compileErr *CompileError
+	require.ErrorAs(t, DumpAST(&out, "return 1;", DumpFormat_SEXPR, true), &compileErr)
+}
+
+func TestAST(t *testing.T) {
+	parse := func(source string) []Stmt {
+		l := New()
+		tokens := NewScanner(l, source).scanTokens()
+		statements := NewParser(l, tokens).parse()
+		require.False(t, l.hadError, source)
+		return statements
+	}
+
+	// Nodes start where their first token does.
+	statements := parse("var a = 1;\nprint a\n  + \"x\ny\";")
+	printStmt := statements[1].(*Print)
+	sum := printStmt.Expression.(*Binary)
+	require.Equal(t, Position{Line: 2, Column: 1, Offset: 11}, printStmt.Pos())
+	require.Equal(t, Position{Line: 2, Column: 7, Offset: 17}, sum.Pos())
+	require.Equal(t, Position{Line: 3, Column: 5, Offset: 23}, sum.Right.Pos())
+
+	// Anonymous functions start at `fun` or their parameters.
+	statements = parse("var a = 1;\nvar f = fun () {};\nvar g = x => x;")
+	require.Equal(t, Position{Line: 2, Column: 9, Offset: 19}, statements[1].(*Var).Initializer.Pos())
+	require.Equal(t, Position{Line: 3, Column: 11, Offset: 40}, statements[2].(*Var).Initializer.Pos())
+
+	// Equal ignores layout, but not names or values.
+	require.True(t, Equal(parse("print 1+f(2);")[0], parse("print 1 +\n  f(2);")[0]))
+	require.False(t, Equal(parse("print 1+f(2);")[0], parse("print 1+f(3);")[0]))
+	require.False(t, Equal(parse("print 1+f(2);")[0], parse("print 1+g(2);")[0]))
+	require.False(t, Equal(parse("print 1;")[0], parse("1;")[0]))
+	require.True(t, Equal(nil, nil))
+
+	// Clones are equal but share no nodes with the original.
+	paths, err := filepath.Glob("*.lox")
+	require.Nil(t, err)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		require.Nil(t, err)
+		for _, stmt := range parse(string(b)) {
+			clone := CloneStmt(stmt)
+			require.True(t, Equal(stmt, clone), path)
+
+			nodes := map[Node]bool{}
+			InspectAST(stmt, func(n Node) bool {
+				nodes[n] = n != nil
+				return true
+			})
+			InspectAST(clone, func(n Node) bool {
+				require.False(t, nodes[n], path)
+				return true
+			})
+		}
+	}
+	class := parse("class A < B { f() { return 1; } }")[0].(*Class)
+	clone := class.Clone()
+	clone.Methods[0].Body[0].(*Return).Value.(*Literal).Value = 2.0
+	require.False(t, Equal(class, clone))
+	require.Equal(t, 1.0, class.Methods[0].Body[0].(*Return).Value.(*Literal).Value)
+
+	// Walk visits each node before its children, and calls Visit(nil) after
+	// them.
+	var visited []string
+	InspectAST(parse("if (a) print -b; else { c = [d]; }")[0], func(n Node) bool {
+		if n == nil {
+			visited = append(visited, ")")
+			return true
+		}
+		visited = append(visited, strings.TrimPrefix(fmt.Sprintf("%T", n), "*lox."))
+		// The else branch is skipped.
+		_, ok := n.(*Block)
+		return !ok
+	})
+	require.Equal(t, "If Variable ) Print Unary Variable ) ) ) Block )", strings.Join(visited, " "))
+}
+
+func TestLint(t *testing.T) {
+	warnings, err := Lint(`var g = 1;
+fun add(a, b) { return a; }
+fun f(x, _y) {
+  var g = 2;
+  var z = 1;
+  z = 2;
+  return g;
+  print x;
+}
+class P {
+  init(x) { this.x = x; }
+  sum() { return this.x + this.y + this.sum(); }
+}
+class Q < P {}
+print add(1, 2, 3) + f(1, 2);
+print Q() + P(1) + clock(1);
+fun h() {
+  // lint:ignore W001
+  var q = 1;
+  var r = 1; // lint:ignore W003
+  var s = 1; // lint:ignore
+}
+`)
+	require.Nil(t, err)
+
+	type warning struct {
+		line    int
+		code    LintCode
+		message string
+	}
+	got := []warning{}
+	for _, w := range warnings {
+		got = append(got, warning{w.Span.Line, w.Code, w.Message})
+	}
+	require.Equal(t, []warning{
+		{2, LintCode_UNUSED_PARAMETER, "Parameter 'b' is never read."},
+		{4, LintCode_SHADOWED, "'g' shadows the declaration on line 1."},
+		{5, LintCode_UNUSED_VARIABLE, "Variable 'z' is never read."},
+		{8, LintCode_UNREACHABLE, "Unreachable code."},
+		{12, LintCode_UNASSIGNED_FIELD, "Field 'y' is never assigned."},
+		{15, LintCode_ARITY, "Expected 2 arguments but got 3 for 'add'."},
+		{16, LintCode_ARITY, "Expected 1 arguments but got 0 for 'Q'."},
+		{16, LintCode_ARITY, "Expected 0 arguments but got 1 for 'clock'."},
+		{20, LintCode_UNUSED_VARIABLE, "Variable 'r' is never read."},
+	}, got)
+
+	_, err = Lint("var x = ;")
+	var compileErr *CompileError
+	require.ErrorAs(t, err, &compileErr)
+}
+
+// benchmarkScripts runs the scripts at paths on the interpreter, discarding
+// what they print. Only running them is timed, not scanning and parsing.
+func benchmarkScripts(b *testing.B, paths ...string) {
+	l := New(WithStdout(io.Discard))
+	scripts := make([][]Stmt, len(paths))
+	for i, path := range paths {
+		source, err := os.ReadFile(path)
+		require.Nil(b, err)
+		tokens := NewScanner(l, string(source)).scanTokens()
+		scripts[i] = NewParser(l, tokens).parse()
+		require.False(b, l.hadError, path)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, statements := range scripts {
+			require.Nil(b, l.exec(statements))
+		}
+	}
+}
+
+func BenchmarkFibrec(b *testing.B) {
+	benchmarkScripts(b, "fibrec.lox")
+}
+
+func BenchmarkClasses(b *testing.B) {
+	benchmarkScripts(b, "bacon.lox", "bagel.lox", "cake.lox", "class.lox", "donut.lox", "init.lox")
+}
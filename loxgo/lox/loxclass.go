@@ -1,4 +1,4 @@
-package main
+package lox
 
 var _ Callable = (*LoxClass)(nil)
 
@@ -19,6 +19,8 @@ func (lc LoxClass) String() string {
 func (lc *LoxClass) Call(itrp *Interpreter, arguments []any) any {
 	instance := NewLoxInstance(lc)
 
+	// The initializer's frame is pushed by LoxFunction.Call and reports the
+	// same call site as the class call.
 	initializer := lc.findMethod("init")
 	if initializer != nil {
 		initializer.bind(instance).Call(itrp, arguments)
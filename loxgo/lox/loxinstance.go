@@ -1,7 +1,7 @@
-package main
+package lox
 
 type LoxInstance struct {
-	*LoxClass
+	class  *LoxClass
 	fields map[string]any
 }
 
@@ -10,7 +10,7 @@ func NewLoxInstance(class *LoxClass) *LoxInstance {
 }
 
 func (li LoxInstance) String() string {
-	return li.name + " instance"
+	return li.class.name + " instance"
 }
 
 func (li *LoxInstance) Get(name *Token) any {
@@ -18,12 +18,12 @@ func (li *LoxInstance) Get(name *Token) any {
 		return v
 	}
 
-	method := li.LoxClass.findMethod(name.lexeme)
+	method := li.class.findMethod(name.lexeme)
 	if method != nil {
 		return method.bind(li)
 	}
 
-	panic("Undefined property '" + name.lexeme + "'.")
+	panic(NewRuntimeError(name, "Undefined property '"+name.lexeme+"'."))
 }
 func (li *LoxInstance) Set(name *Token, value any) {
 	li.fields[name.lexeme] = value
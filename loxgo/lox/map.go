@@ -0,0 +1,127 @@
+package lox
+
+import (
+	"fmt"
+)
+
+type mapEntry struct {
+	key   any
+	value any
+}
+
+// LoxMap is a hash map that remembers insertion order so that printing and
+// iteration are deterministic.
+type LoxMap struct {
+	// index maps a hashed key to its position in entries
+	index   map[any]int
+	entries []mapEntry
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{
+		index:   map[any]int{},
+		entries: []mapEntry{},
+	}
+}
+
+// hashKey returns the Go map key used to store the Lox value v. Numbers
+// other than NaN, strings, booleans and nil are compared by value; instances
+// are compared by identity.
+func hashKey(token *Token, v any) any {
+	switch k := v.(type) {
+	case nil, bool, string:
+		return k
+	case float64:
+		// -0 and 0 are equal in Lox and must hash the same. NaN equals
+		// nothing, so an entry stored under it could never be found.
+		if k == 0 {
+			return 0.0
+		}
+		if k != k {
+			panic(NewRuntimeError(token, "NaN can't be a map key."))
+		}
+		return k
+	case *LoxInstance, *ObjInstance:
+		return k
+	}
+	panic(NewRuntimeError(token, "Only strings, numbers, booleans, nil and instances can be map keys."))
+}
+
+func (m *LoxMap) String() string {
+	return stringify(m)
+}
+
+func (m *LoxMap) Get(token *Token, key any) any {
+	i, ok := m.index[hashKey(token, key)]
+	if !ok {
+		panic(NewRuntimeError(token, fmt.Sprintf("Undefined key '%s'.", stringify(key))))
+	}
+	return m.entries[i].value
+}
+
+func (m *LoxMap) Set(token *Token, key any, value any) {
+	h := hashKey(token, key)
+	if i, ok := m.index[h]; ok {
+		m.entries[i].value = value
+		return
+	}
+	m.index[h] = len(m.entries)
+	m.entries = append(m.entries, mapEntry{key, value})
+}
+
+func (m *LoxMap) has(token *Token, key any) bool {
+	_, ok := m.index[hashKey(token, key)]
+	return ok
+}
+
+func (m *LoxMap) delete(token *Token, key any) bool {
+	h := hashKey(token, key)
+	i, ok := m.index[h]
+	if !ok {
+		return false
+	}
+
+	delete(m.index, h)
+	m.entries = append(m.entries[:i], m.entries[i+1:]...)
+	for j := i; j < len(m.entries); j++ {
+		m.index[hashKey(token, m.entries[j].key)] = j
+	}
+	return true
+}
+
+// Method returns the built-in method called name bound to the map. Errors
+// raised by the method are reported at token.
+func (m *LoxMap) Method(name *Token) *NativeFunction {
+	switch name.lexeme {
+	case "has":
+		return NewNativeFunction(1, func(arguments []any) any {
+			return m.has(name, arguments[0])
+		})
+	case "delete":
+		return NewNativeFunction(1, func(arguments []any) any {
+			return m.delete(name, arguments[0])
+		})
+	case "keys":
+		return NewNativeFunction(0, func(arguments []any) any {
+			keys := make([]any, len(m.entries))
+			for i, e := range m.entries {
+				keys[i] = e.key
+			}
+			return NewLoxList(keys)
+		})
+	case "values":
+		return NewNativeFunction(0, func(arguments []any) any {
+			values := make([]any, len(m.entries))
+			for i, e := range m.entries {
+				values[i] = e.value
+			}
+			return NewLoxList(values)
+		})
+	case "len":
+		return NewNativeFunction(0, func(arguments []any) any {
+			return float64(len(m.entries))
+		})
+	}
+
+	panic(NewRuntimeError(name, "Undefined property '"+name.lexeme+"'."))
+}
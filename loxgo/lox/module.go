@@ -0,0 +1,144 @@
+package lox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoxModule is the value bound by an import. Only the names its source
+// exports are visible through it; everything else stays private to the
+// module.
+type LoxModule struct {
+	path    string
+	exports map[string]bool
+	// The module's globals live in env when it ran on the interpreter and in
+	// globals when it ran on the VM.
+	env     *Environment
+	globals map[string]any
+}
+
+func (m *LoxModule) String() string {
+	return "<module " + displayPath(m.path) + ">"
+}
+
+// Get reads an exported name. Exports are read when accessed, so assignments
+// made by the module after it was imported are visible.
+func (m *LoxModule) Get(name *Token) any {
+	if !m.exports[name.lexeme] {
+		panic(NewRuntimeError(name, fmt.Sprintf("Module '%s' does not export '%s'.", displayPath(m.path), name.lexeme)))
+	}
+
+	if m.env != nil {
+		return m.env.get(name)
+	}
+	value, ok := m.globals[name.lexeme]
+	if !ok {
+		panic(NewRuntimeError(name, "Undefined variable '"+name.lexeme+"'."))
+	}
+	return value
+}
+
+// exportedNames returns the names declared by the export statements among a
+// module's top-level statements.
+func exportedNames(statements []Stmt) map[string]bool {
+	names := map[string]bool{}
+	for _, stmt := range statements {
+		export, ok := stmt.(*Export)
+		if !ok {
+			continue
+		}
+
+		switch decl := export.Declaration.(type) {
+		case *Class:
+			names[decl.Name.lexeme] = true
+		case *Function:
+			names[decl.Name.lexeme] = true
+		case *Var:
+			names[decl.Name.lexeme] = true
+		}
+	}
+	return names
+}
+
+// findModule resolves an import path against the directory of the importing
+// module, or the working directory at the REPL. It returns the absolute path
+// of the module along with the module itself if it has already been loaded.
+// Importing a module that is still being loaded is a cycle. token is nil
+// when called from the VM.
+func (l *Lox) findModule(token *Token, path string) (string, *LoxModule) {
+	dir := "."
+	if len(l.importing) > 0 {
+		dir = filepath.Dir(l.importing[len(l.importing)-1])
+	}
+
+	abs, err := filepath.Abs(filepath.Join(dir, path))
+	if err != nil {
+		panic(NewRuntimeError(token, fmt.Sprintf("Could not resolve module '%s'.", path)))
+	}
+
+	for i, importing := range l.importing {
+		if importing == abs {
+			chain := []string{}
+			for _, p := range append(l.importing[i:len(l.importing):len(l.importing)], abs) {
+				chain = append(chain, displayPath(p))
+			}
+			panic(NewRuntimeError(token, "Import cycle: "+strings.Join(chain, " -> ")+"."))
+		}
+	}
+
+	return abs, l.modules[abs]
+}
+
+// parseModule scans, parses and resolves the module at path. Syntax errors
+// are reported as usual and then raised as a runtime error at the import.
+func (l *Lox) parseModule(token *Token, path string) []Stmt {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		panic(NewRuntimeError(token, fmt.Sprintf("Could not read module '%s'.", displayPath(path))))
+	}
+
+	scanner := NewScanner(l, string(b))
+	tokens := scanner.scanTokens()
+
+	statements := NewParser(l, tokens).parse()
+	if !l.hadError {
+		NewResolver(l, l.interpreter).resolveStmts(statements)
+	}
+	if l.hadError {
+		panic(NewRuntimeError(token, fmt.Sprintf("Could not compile module '%s'.", displayPath(path))))
+	}
+	return statements
+}
+
+// beginModule marks path as being loaded until endModule is called.
+func (l *Lox) beginModule(path string) {
+	l.importing = append(l.importing, path)
+}
+
+// endModule finishes loading the innermost module, caching module if it
+// loaded successfully.
+func (l *Lox) endModule(module *LoxModule) {
+	if module != nil {
+		if l.modules == nil {
+			l.modules = map[string]*LoxModule{}
+		}
+		l.modules[module.path] = module
+	}
+	l.importing = l.importing[:len(l.importing)-1]
+}
+
+// displayPath shortens path for messages, relative to the working directory
+// where possible.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,279 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// natives are the functions defined in the global scope of every module.
+var natives = map[string]any{
+	"clock": MustGoFunction(func() float64 {
+		return float64(time.Now().Unix())
+	}),
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	valueType = reflect.TypeOf(Value{})
+)
+
+// NewGoFunction wraps the Go func fn so that Lox code can call it.
+//
+// Arguments are converted from Lox values to the parameter types of fn:
+// numbers to any integer or float type (integers must be whole numbers),
+// strings, booleans, lists to slices, and nil to pointers, interfaces, slices
+// and maps. A Value parameter receives a handle to any Lox value, such as a
+// function to call back. Parameters of other types, such as *LoxInstance or
+// any, receive the Lox value as it is. A variadic fn can be called with any number of
+// trailing arguments.
+//
+// fn may return nothing, a value, an error, or a value and an error. Numbers
+// are converted to float64, slices to lists and maps to maps, and a Value
+// gives the Lox value it holds. A non-nil error
+// is raised as a Lox runtime error at the call.
+func NewGoFunction(fn any) (*NativeFunction, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("lox: %T is not a func", fn)
+	}
+
+	t := v.Type()
+	params := make([]reflect.Type, t.NumIn())
+	for i := range params {
+		params[i] = t.In(i)
+		if t.IsVariadic() && i == len(params)-1 {
+			params[i] = params[i].Elem()
+		}
+		if !isLoxParam(params[i]) {
+			return nil, fmt.Errorf("lox: can't convert Lox values to %s", params[i])
+		}
+	}
+
+	switch {
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("lox: the second result of %s must be an error", t)
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("lox: %s returns too many results", t)
+	}
+
+	arity := len(params)
+	if t.IsVariadic() {
+		arity--
+	}
+
+	native := &NativeFunction{arity: arity, variadic: t.IsVariadic()}
+	native.fn = func(l *Lox, arguments []any) any {
+		in := make([]reflect.Value, len(arguments))
+		for i, argument := range arguments {
+			// Extra arguments to a variadic func share its last parameter.
+			param := params[len(params)-1]
+			if i < len(params) {
+				param = params[i]
+			}
+			value, ok := fromLox(l, argument, param)
+			if !ok {
+				panic(NewRuntimeError(nil, fmt.Sprintf("Argument %d must be %s.", i+1, describeType(param))))
+			}
+			in[i] = value
+		}
+
+		return goResults(v.Call(in))
+	}
+	return native, nil
+}
+
+// MustGoFunction is like NewGoFunction but panics if fn can't be wrapped.
+func MustGoFunction(fn any) *NativeFunction {
+	native, err := NewGoFunction(fn)
+	if err != nil {
+		panic(err)
+	}
+	return native
+}
+
+// Define defines a global variable in the script and in every module it
+// imports from then on. A Go func value is wrapped with NewGoFunction.
+func (l *Lox) Define(name string, value any) error {
+	value, err := loxGlobal(value)
+	if err != nil {
+		return err
+	}
+
+	l.globals[name] = value
+	l.interpreter.globals.define(name, value)
+	if l.vm != nil {
+		l.vm.globals[name] = value
+	}
+	return nil
+}
+
+// loxGlobal converts a value given by the host into a Lox value.
+func loxGlobal(value any) (any, error) {
+	if reflect.ValueOf(value).Kind() == reflect.Func {
+		return NewGoFunction(value)
+	}
+	return value, nil
+}
+
+func isLoxParam(t reflect.Type) bool {
+	if t == valueType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String, reflect.Bool, reflect.Interface, reflect.Pointer:
+		return true
+	case reflect.Slice:
+		return isLoxParam(t.Elem())
+	}
+	return false
+}
+
+// describeType names the Lox values accepted for a parameter of type t.
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice:
+		return "a list"
+	}
+	return "a " + t.String()
+}
+
+// fromLox converts the Lox value v, held by l, to type t. It reports false
+// if v can't be converted.
+func fromLox(l *Lox, v any, t reflect.Type) (reflect.Value, bool) {
+	if t == valueType {
+		return reflect.ValueOf(Value{l, v}), true
+	}
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), true
+		}
+		return reflect.Value{}, false
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if f, ok := v.(float64); ok {
+			return reflect.ValueOf(f).Convert(t), true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// The bounds are checked on the float, as converting one out of range
+		// gives an implementation-defined integer.
+		limit := math.Ldexp(1, t.Bits()-1)
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && f >= -limit && f < limit {
+			return reflect.ValueOf(int64(f)).Convert(t), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		limit := math.Ldexp(1, t.Bits())
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && f >= 0 && f < limit {
+			return reflect.ValueOf(uint64(f)).Convert(t), true
+		}
+	case reflect.Slice:
+		if list, ok := v.(*LoxList); ok {
+			slice := reflect.MakeSlice(t, len(list.elements), len(list.elements))
+			for i, element := range list.elements {
+				value, ok := fromLox(l, element, t.Elem())
+				if !ok {
+					return reflect.Value{}, false
+				}
+				slice.Index(i).Set(value)
+			}
+			return slice, true
+		}
+	}
+
+	if value := reflect.ValueOf(v); value.Type().AssignableTo(t) {
+		return value, true
+	}
+	return reflect.Value{}, false
+}
+
+// goResults converts the results of a Go func into the Lox return value,
+// raising a returned error.
+func goResults(out []reflect.Value) any {
+	if len(out) > 0 && out[len(out)-1].Type() == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			if re, ok := err.(*RuntimeError); ok {
+				panic(re)
+			}
+			panic(NewRuntimeError(nil, err.Error()))
+		}
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return toLox(out[0])
+}
+
+// toLox converts a Go value to a Lox value. Values with no Lox equivalent
+// are passed through unchanged.
+func toLox(v reflect.Value) any {
+	if v.IsValid() && v.Type() == valueType {
+		return v.Interface().(Value).value
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toLox(v.Elem())
+	case reflect.Pointer, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := make([]any, v.Len())
+		for i := range elements {
+			elements[i] = toLox(v.Index(i))
+		}
+		return NewLoxList(elements)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		// Go maps are unordered; sort the keys so that Lox sees a stable
+		// order.
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		m := NewLoxMap()
+		for _, key := range keys {
+			m.Set(nil, toLox(key), toLox(v.MapIndex(key)))
+		}
+		return m
+	}
+	return v.Interface()
+}
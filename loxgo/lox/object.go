@@ -0,0 +1,95 @@
+package lox
+
+// Runtime objects used by the bytecode VM. Strings, numbers, booleans and nil
+// are plain Go values, the same as in the tree-walking interpreter.
+
+type ObjFunction struct {
+	name         string
+	arity        int
+	upvalueCount int
+	chunk        *Chunk
+	// file is the path of the script or module the function is in.
+	file string
+}
+
+func NewObjFunction(name string) *ObjFunction {
+	return &ObjFunction{
+		name:  name,
+		chunk: NewChunk(),
+	}
+}
+
+func (f *ObjFunction) String() string {
+	if f.name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.name + ">"
+}
+
+type ObjUpvalue struct {
+	// index into the VM stack while the upvalue is open
+	slot   int
+	closed any
+	open   bool
+	next   *ObjUpvalue
+}
+
+type ObjClosure struct {
+	function *ObjFunction
+	upvalues []*ObjUpvalue
+	// globals belong to the module the closure was created in.
+	globals map[string]any
+}
+
+func NewObjClosure(function *ObjFunction, globals map[string]any) *ObjClosure {
+	return &ObjClosure{
+		function: function,
+		upvalues: make([]*ObjUpvalue, function.upvalueCount),
+		globals:  globals,
+	}
+}
+
+func (c *ObjClosure) String() string {
+	return c.function.String()
+}
+
+type ObjClass struct {
+	name    string
+	methods map[string]*ObjClosure
+}
+
+func NewObjClass(name string) *ObjClass {
+	return &ObjClass{
+		name:    name,
+		methods: map[string]*ObjClosure{},
+	}
+}
+
+func (c *ObjClass) String() string {
+	return c.name
+}
+
+type ObjInstance struct {
+	class  *ObjClass
+	fields map[string]any
+}
+
+func NewObjInstance(class *ObjClass) *ObjInstance {
+	return &ObjInstance{
+		class:  class,
+		fields: map[string]any{},
+	}
+}
+
+func (i *ObjInstance) String() string {
+	return i.class.name + " instance"
+}
+
+type ObjBoundMethod struct {
+	receiver any
+	method   *ObjClosure
+}
+
+func (b *ObjBoundMethod) String() string {
+	return b.method.String()
+}
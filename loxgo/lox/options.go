@@ -0,0 +1,96 @@
+package lox
+
+import "io"
+
+// Option configures a Lox created by New.
+type Option func(*Lox)
+
+// WithStdout sets where `print` writes. It defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(l *Lox) {
+		l.stdout = w
+	}
+}
+
+// WithStderr sets where errors are reported as they happen, in the format
+// the loxgo command prints them. Errors are returned either way; by default
+// they aren't written anywhere.
+func WithStderr(w io.Writer) Option {
+	return func(l *Lox) {
+		l.stderr = w
+	}
+}
+
+// WithDiagnostics calls fn with each error as it is reported, alongside
+// writing it to stderr: a *SyntaxError for each syntax error, a
+// *RuntimeError for a runtime error that the program didn't catch, and any
+// other error for a bug in the interpreter.
+func WithDiagnostics(fn func(err error)) Option {
+	return func(l *Lox) {
+		l.diagnostics = fn
+	}
+}
+
+// WithGlobals defines each name in globals as a global variable holding its
+// value, in the script and in every module it imports. Values must be Lox
+// values: nil, bool, float64, string, or one created by the package such as
+// a *NativeFunction. Go funcs are wrapped with NewGoFunction; if one can't
+// be, every Run and Eval returns the error.
+func WithGlobals(globals map[string]any) Option {
+	return func(l *Lox) {
+		for name, value := range globals {
+			value, err := loxGlobal(value)
+			if err != nil {
+				if l.optionErr == nil {
+					l.optionErr = err
+				}
+				continue
+			}
+			l.globals[name] = value
+		}
+	}
+}
+
+// WithVM runs programs on the bytecode VM instead of the tree-walking
+// interpreter.
+func WithVM() Option {
+	return func(l *Lox) {
+		l.useVM = true
+	}
+}
+
+// WithDisassemble prints the bytecode of each program to stdout before the
+// VM runs it.
+func WithDisassemble() Option {
+	return func(l *Lox) {
+		l.disassemble = true
+	}
+}
+
+// WithMaxSteps stops each run with a runtime error wrapping ErrStepLimit
+// once it has executed n steps. The interpreter counts statements and
+// expressions, and the VM counts instructions. Zero, the default, means no
+// limit.
+func WithMaxSteps(n int) Option {
+	return func(l *Lox) {
+		l.maxSteps = n
+	}
+}
+
+// WithMaxCallDepth sets how deeply Lox calls may nest, counting the script
+// itself, before a call raises a "Stack overflow." runtime error wrapping
+// ErrStackOverflow. It defaults to 1024.
+func WithMaxCallDepth(n int) Option {
+	return func(l *Lox) {
+		l.maxCallDepth = n
+	}
+}
+
+// WithDebugger lets d pause programs at its breakpoints and step through
+// them. Only the tree-walking interpreter can be debugged; d is ignored with
+// WithVM.
+func WithDebugger(d *Debugger) Option {
+	return func(l *Lox) {
+		l.debugger = d
+	}
+}
@@ -0,0 +1,1000 @@
+package lox
+
+import "fmt"
+
+type Parser struct {
+	lox     *Lox
+	tokens  []*Token
+	current int
+}
+
+func NewParser(l *Lox, tokens []*Token) *Parser {
+	return &Parser{
+		lox:     l,
+		tokens:  tokens,
+		current: 0,
+	}
+}
+
+func (p *Parser) parse() []Stmt {
+	ret := []Stmt{}
+
+	for !p.isAtEnd() {
+		ret = append(ret, p.declaration())
+	}
+
+	return ret
+}
+
+// parseExpression parses source that must be a single expression. It
+// returns nil after reporting a syntax error.
+func (p *Parser) parseExpression() Expr {
+	expr, err := p.expression()
+	if err != nil {
+		return nil
+	}
+	if !p.isAtEnd() {
+		p.error(p.peek(), "Expect end of expression.")
+		return nil
+	}
+	return expr
+}
+
+// declaration parses a declaration or statement. After a syntax error, it
+// skips to the next statement and returns nil.
+func (p *Parser) declaration() Stmt {
+	var stmt Stmt
+	var err error
+	if p.match(TokenType_IMPORT) {
+		stmt, err = p.importDeclaration()
+	} else if p.match(TokenType_EXPORT) {
+		stmt, err = p.exportDeclaration()
+	} else if p.match(TokenType_CLASS) {
+		stmt, err = p.classDeclaration()
+	} else if p.check(TokenType_FUN) && p.checkNext(TokenType_IDENTIFIER) {
+		// `fun (` starts an anonymous function expression instead.
+		p.advance()
+		stmt, err = p.function("function")
+	} else if p.match(TokenType_VAR) {
+		stmt, err = p.varDeclaration()
+	} else {
+		stmt, err = p.statement()
+	}
+
+	if err != nil {
+		p.synchronize()
+		return nil
+	}
+	return stmt
+}
+
+// importDeclaration parses one of
+//
+//	import "path.lox";
+//	import "path.lox" as name;
+//	import { a, b } from "path.lox";
+//
+// `as` and `from` are only special here, so they remain usable as names.
+func (p *Parser) importDeclaration() (*Import, error) {
+	keyword := p.previous()
+
+	names := []*Token{}
+	if p.match(TokenType_LEFT_BRACE) {
+		for {
+			name, err := p.consume(TokenType_IDENTIFIER, "Expect imported name.")
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			if !p.match(TokenType_COMMA) {
+				break
+			}
+		}
+		if _, err := p.consume(TokenType_RIGHT_BRACE, "Expect '}' after imported names."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consumeContextual("from", "Expect 'from' after imported names."); err != nil {
+			return nil, err
+		}
+	}
+
+	path, err := p.consume(TokenType_STRING, "Expect module path.")
+	if err != nil {
+		return nil, err
+	}
+
+	var alias *Token
+	if len(names) == 0 && p.check(TokenType_IDENTIFIER) && p.peek().lexeme == "as" {
+		p.advance()
+		if alias, err = p.consume(TokenType_IDENTIFIER, "Expect module name after 'as'."); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after import."); err != nil {
+		return nil, err
+	}
+	return &Import{Keyword: keyword, Path: path, Alias: alias, Names: names}, nil
+}
+
+func (p *Parser) exportDeclaration() (*Export, error) {
+	keyword := p.previous()
+
+	var decl Stmt
+	var err error
+	if p.match(TokenType_CLASS) {
+		decl, err = p.classDeclaration()
+	} else if p.match(TokenType_FUN) {
+		decl, err = p.function("function")
+	} else if p.match(TokenType_VAR) {
+		decl, err = p.varDeclaration()
+	} else {
+		err = p.error(p.peek(), "Expect declaration after 'export'.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Export{Keyword: keyword, Declaration: decl}, nil
+}
+
+func (p *Parser) classDeclaration() (*Class, error) {
+	name, err := p.consume(TokenType_IDENTIFIER, "Expect class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *Variable
+	if p.match(TokenType_LESS) {
+		if _, err := p.consume(TokenType_IDENTIFIER, "Expect superclass name."); err != nil {
+			return nil, err
+		}
+		superclass = &Variable{Name: p.previous()}
+	}
+
+	if _, err := p.consume(TokenType_LEFT_BRACE, "Expect '{' before class body."); err != nil {
+		return nil, err
+	}
+
+	methods := []*Function{}
+	for !p.check(TokenType_RIGHT_BRACE) && !p.isAtEnd() {
+		method, err := p.function("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	if _, err := p.consume(TokenType_RIGHT_BRACE, "Expect '}' after class body."); err != nil {
+		return nil, err
+	}
+
+	return &Class{Name: name, SuperClass: superclass, Methods: methods}, nil
+}
+
+func (p *Parser) varDeclaration() (*Var, error) {
+	name, err := p.consume(TokenType_IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var initializer Expr
+	if p.match(TokenType_EQUAL) {
+		if initializer, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after variable declaration."); err != nil {
+		return nil, err
+	}
+	return &Var{Name: name, Initializer: initializer}, nil
+}
+
+func (p *Parser) function(kind string) (*Function, error) {
+	name, err := p.consume(TokenType_IDENTIFIER, "Expect "+kind+" name.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after "+kind+" name."); err != nil {
+		return nil, err
+	}
+	return p.functionBody(kind, name)
+}
+
+// functionBody parses the parameters and body of a function after the
+// opening paren.
+func (p *Parser) functionBody(kind string, name *Token) (*Function, error) {
+	parameters, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(TokenType_LEFT_BRACE, "Expect '{' before "+kind+" body."); err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &Function{Name: name, Params: parameters, Body: body, end: p.previous()}, nil
+}
+
+// parameters parses the parameters of a function after the opening paren,
+// and the closing paren.
+func (p *Parser) parameters() ([]*Token, error) {
+	parameters := []*Token{}
+	if !p.check(TokenType_RIGHT_PAREN) {
+		for {
+			if len(parameters) >= 255 {
+				p.error(p.peek(), "Can't have more than 255 parameters.")
+			}
+
+			parameter, err := p.consume(TokenType_IDENTIFIER, "Expect parameter name.")
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(parameters, parameter)
+
+			if !p.match(TokenType_COMMA) {
+				break
+			}
+		}
+	}
+	if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after parameters."); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
+
+// lambda parses an anonymous function after `fun`.
+func (p *Parser) lambda() (*Lambda, error) {
+	keyword := p.previous()
+	if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after 'fun'."); err != nil {
+		return nil, err
+	}
+	function, err := p.functionBody("function", anonymousName(keyword))
+	if err != nil {
+		return nil, err
+	}
+	return &Lambda{Function: function}, nil
+}
+
+// arrow parses the short form of an anonymous function, `x => expr` or
+// `(a, b) => expr`. Its body returns the expression.
+func (p *Parser) arrow() (*Lambda, error) {
+	var parameters []*Token
+	if p.match(TokenType_LEFT_PAREN) {
+		var err error
+		if parameters, err = p.parameters(); err != nil {
+			return nil, err
+		}
+	} else {
+		parameter, err := p.consume(TokenType_IDENTIFIER, "Expect parameter name.")
+		if err != nil {
+			return nil, err
+		}
+		parameters = []*Token{parameter}
+	}
+
+	arrow, err := p.consume(TokenType_ARROW, "Expect '=>' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	body := []Stmt{&Return{Keyword: arrow, Value: value}}
+	return &Lambda{Function: &Function{Name: anonymousName(arrow), Params: parameters, Body: body, end: p.previous()}}, nil
+}
+
+// isArrow reports whether the tokens ahead are the parameters of an arrow
+// function, which needs more lookahead than the rest of the grammar.
+func (p *Parser) isArrow() bool {
+	if p.check(TokenType_IDENTIFIER) {
+		return p.checkNext(TokenType_ARROW)
+	}
+	if !p.check(TokenType_LEFT_PAREN) {
+		return false
+	}
+
+	i := p.current + 1
+	for p.tokens[i].t == TokenType_IDENTIFIER {
+		i++
+		if p.tokens[i].t != TokenType_COMMA {
+			break
+		}
+		i++
+	}
+	return p.tokens[i].t == TokenType_RIGHT_PAREN && p.tokens[i+1].t == TokenType_ARROW
+}
+
+// anonymousName is the name given to a function declared at token without
+// one. It can't clash with an identifier. It is placed at token, so that the
+// function is located where it starts.
+func anonymousName(token *Token) *Token {
+	name := NewToken(TokenType_IDENTIFIER, fmt.Sprintf("anonymous@%d", token.line), nil, token.line)
+	name.column, name.offset = token.column, token.offset
+	return name
+}
+
+func (p *Parser) statement() (Stmt, error) {
+	if p.match(TokenType_FOR) {
+		return p.forStatement()
+	}
+	if p.match(TokenType_IF) {
+		return p.ifStatement()
+	}
+	if p.match(TokenType_RETURN) {
+		return p.returnStatement()
+	}
+	if p.match(TokenType_WHILE) {
+		return p.whileStatement()
+	}
+	if p.match(TokenType_TRY) {
+		return p.tryStatement()
+	}
+	if p.match(TokenType_THROW) {
+		keyword := p.previous()
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after thrown value."); err != nil {
+			return nil, err
+		}
+		return &Throw{Keyword: keyword, Value: value}, nil
+	}
+	if p.match(TokenType_BREAK) {
+		keyword := p.previous()
+		if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after 'break'."); err != nil {
+			return nil, err
+		}
+		return &Break{Keyword: keyword}, nil
+	}
+	if p.match(TokenType_CONTINUE) {
+		keyword := p.previous()
+		if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after 'continue'."); err != nil {
+			return nil, err
+		}
+		return &Continue{Keyword: keyword}, nil
+	}
+	if p.match(TokenType_PRINT) {
+		return p.printStatement()
+	}
+	if p.match(TokenType_LEFT_BRACE) {
+		brace := p.previous()
+		statements, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return &Block{Brace: brace, Statements: statements, end: p.previous()}, nil
+	}
+	return p.expressionStatement()
+}
+
+func (p *Parser) forStatement() (Stmt, error) {
+	keyword := p.previous()
+	if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after 'for'."); err != nil {
+		return nil, err
+	}
+
+	var initializer Stmt
+	var err error
+	if p.match(TokenType_SEMICOLON) {
+		initializer = nil
+	} else if p.match(TokenType_VAR) {
+		initializer, err = p.varDeclaration()
+	} else {
+		initializer, err = p.expressionStatement()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition Expr
+	if !p.check(TokenType_SEMICOLON) {
+		if condition, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after loop condition."); err != nil {
+		return nil, err
+	}
+
+	var increment Expr
+	if !p.check(TokenType_RIGHT_PAREN) {
+		if increment, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after for clauses."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	if condition == nil {
+		condition = &Literal{Value: true, Token: keyword}
+	}
+	// The increment is kept on the loop rather than appended to the body so
+	// that it still runs after a `continue`.
+	body = &While{Keyword: keyword, Condition: condition, Body: body, Increment: increment}
+
+	if initializer != nil {
+		body = &Block{Brace: keyword, Statements: []Stmt{initializer, body}, end: p.previous()}
+	}
+
+	return body, nil
+}
+
+func (p *Parser) tryStatement() (*Try, error) {
+	keyword := p.previous()
+	if _, err := p.consume(TokenType_LEFT_BRACE, "Expect '{' after 'try'."); err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	bodyEnd := p.previous()
+
+	var catchName *Token
+	var catch []Stmt
+	var catchEnd *Token
+	if p.match(TokenType_CATCH) {
+		if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+		if catchName, err = p.consume(TokenType_IDENTIFIER, "Expect exception variable name."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after exception variable."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(TokenType_LEFT_BRACE, "Expect '{' before catch body."); err != nil {
+			return nil, err
+		}
+		if catch, err = p.block(); err != nil {
+			return nil, err
+		}
+		catchEnd = p.previous()
+	}
+
+	var finally []Stmt
+	var finallyEnd *Token
+	if p.match(TokenType_FINALLY) {
+		if _, err := p.consume(TokenType_LEFT_BRACE, "Expect '{' after 'finally'."); err != nil {
+			return nil, err
+		}
+		if finally, err = p.block(); err != nil {
+			return nil, err
+		}
+		finallyEnd = p.previous()
+	}
+
+	if catch == nil && finally == nil {
+		return nil, p.error(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+
+	return &Try{
+		Keyword: keyword, Body: body, CatchName: catchName, Catch: catch, Finally: finally,
+		bodyEnd: bodyEnd, catchEnd: catchEnd, finallyEnd: finallyEnd,
+	}, nil
+}
+
+func (p *Parser) ifStatement() (*If, error) {
+	keyword := p.previous()
+	if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after 'if'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after if condition."); err != nil {
+		return nil, err
+	}
+
+	thenBranch, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	var elseBranch Stmt
+	if p.match(TokenType_ELSE) {
+		if elseBranch, err = p.statement(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &If{Keyword: keyword, Condition: condition, Then: thenBranch, Else: elseBranch}, nil
+}
+func (p *Parser) whileStatement() (*While, error) {
+	keyword := p.previous()
+	if _, err := p.consume(TokenType_LEFT_PAREN, "Expect '(' after 'while'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after condition."); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return &While{Keyword: keyword, Condition: condition, Body: body}, nil
+}
+
+func (p *Parser) returnStatement() (*Return, error) {
+	keyword := p.previous()
+	var value Expr
+	var err error
+	if !p.check(TokenType_SEMICOLON) {
+		if value, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after return value."); err != nil {
+		return nil, err
+	}
+	return &Return{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) printStatement() (*Print, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after value."); err != nil {
+		return nil, err
+	}
+	return &Print{Keyword: keyword, Expression: value}, nil
+}
+
+func (p *Parser) expressionStatement() (*Expression, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(TokenType_SEMICOLON, "Expect ';' after expression."); err != nil {
+		return nil, err
+	}
+	return &Expression{Expression: expr}, nil
+}
+
+// block parses the declarations of a block up to its closing brace. Syntax
+// errors in them are recovered from by declaration; only a missing brace
+// fails the block.
+func (p *Parser) block() ([]Stmt, error) {
+	statements := []Stmt{}
+
+	for !p.check(TokenType_RIGHT_BRACE) && !p.isAtEnd() {
+		statements = append(statements, p.declaration())
+	}
+
+	if _, err := p.consume(TokenType_RIGHT_BRACE, "Expect '}' after block."); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+func (p *Parser) expression() (Expr, error) {
+	return p.assignment()
+}
+func (p *Parser) assignment() (Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(TokenType_EQUAL) {
+		equals := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		switch target := expr.(type) {
+		case *Variable:
+			return &Assign{Name: target.Name, Value: value}, nil
+		case *Get:
+			return &Set{Object: target.Object, Name: target.Name, Value: value}, nil
+		case *Index:
+			return &SetIndex{Object: target.Object, Bracket: target.Bracket, Index: target.Index, Value: value}, nil
+		}
+
+		p.error(equals, "Invalid assignment target.")
+	}
+
+	return expr, nil
+}
+
+// binary parses a left-associative chain of operands joined by any of
+// operators, using operand for each side and join to build each node.
+func (p *Parser) binary(operand func() (Expr, error), join func(left Expr, operator *Token, right Expr) Expr, operators ...TokenType) (Expr, error) {
+	expr, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(operators...) {
+		operator := p.previous()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		expr = join(expr, operator, right)
+	}
+
+	return expr, nil
+}
+
+func binaryExpr(left Expr, operator *Token, right Expr) Expr {
+	return &Binary{Left: left, Operator: operator, Right: right}
+}
+
+func logicalExpr(left Expr, operator *Token, right Expr) Expr {
+	return &Logical{Left: left, Operator: operator, Right: right}
+}
+
+func (p *Parser) equality() (Expr, error) {
+	return p.binary(p.comparison, binaryExpr, TokenType_BANG_EQUAL, TokenType_EQUAL_EQUAL)
+}
+func (p *Parser) or() (Expr, error) {
+	return p.binary(p.and, logicalExpr, TokenType_OR)
+}
+
+func (p *Parser) and() (Expr, error) {
+	return p.binary(p.equality, logicalExpr, TokenType_AND)
+}
+
+func (p *Parser) comparison() (Expr, error) {
+	return p.binary(p.term, binaryExpr, TokenType_GREATER, TokenType_GREATER_EQUAL, TokenType_LESS, TokenType_LESS_EQUAL)
+}
+
+func (p *Parser) term() (Expr, error) {
+	return p.binary(p.factor, binaryExpr, TokenType_MINUS, TokenType_PLUS)
+}
+
+func (p *Parser) factor() (Expr, error) {
+	return p.binary(p.unary, binaryExpr, TokenType_SLASH, TokenType_STAR)
+}
+
+func (p *Parser) unary() (Expr, error) {
+	if p.match(TokenType_BANG, TokenType_MINUS) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Operator: operator, Right: right}, nil
+	}
+
+	return p.call()
+}
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.match(TokenType_LEFT_PAREN) {
+			if expr, err = p.finishCall(expr); err != nil {
+				return nil, err
+			}
+		} else if p.match(TokenType_DOT) {
+			name, err := p.consume(TokenType_IDENTIFIER, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &Get{Object: expr, Name: name}
+		} else if p.match(TokenType_LEFT_BRACKET) {
+			bracket := p.previous()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(TokenType_RIGHT_BRACKET, "Expect ']' after index."); err != nil {
+				return nil, err
+			}
+			expr = &Index{Object: expr, Bracket: bracket, Index: index}
+		} else {
+			break
+		}
+	}
+	return expr, nil
+}
+func (p *Parser) finishCall(callee Expr) (*Call, error) {
+	arguments := []Expr{}
+
+	if !p.check(TokenType_RIGHT_PAREN) {
+		for {
+			if len(arguments) >= 255 {
+				p.error(p.peek(), "Cannot have more than 255 arguments")
+			}
+
+			argument, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(TokenType_COMMA) {
+				break
+			}
+		}
+	}
+
+	paren, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after arguments.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Call{Callee: callee, Paren: paren, Arguments: arguments}, nil
+}
+
+func (p *Parser) primary() (Expr, error) {
+	if p.match(TokenType_FALSE) {
+		return &Literal{Value: false, Token: p.previous()}, nil
+	}
+	if p.match(TokenType_TRUE) {
+		return &Literal{Value: true, Token: p.previous()}, nil
+	}
+	if p.match(TokenType_NIL) {
+		return &Literal{Value: nil, Token: p.previous()}, nil
+	}
+	if p.match(TokenType_NUMBER, TokenType_STRING) {
+		return &Literal{Value: p.previous().literal, Token: p.previous()}, nil
+	}
+	if p.match(TokenType_INTERPOLATION) {
+		return p.interpolation()
+	}
+
+	if p.match(TokenType_SUPER) {
+		keyword := p.previous()
+		if _, err := p.consume(TokenType_DOT, "Expect '.' after 'super'."); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(TokenType_IDENTIFIER, "Expect superclass method name.")
+		if err != nil {
+			return nil, err
+		}
+		return &Super{Keyword: keyword, Method: method}, nil
+	}
+
+	if p.match(TokenType_THIS) {
+		return &This{Keyword: p.previous()}, nil
+	}
+	if p.match(TokenType_FUN) {
+		return p.lambda()
+	}
+	if p.isArrow() {
+		return p.arrow()
+	}
+	if p.match(TokenType_IDENTIFIER) {
+		return &Variable{Name: p.previous()}, nil
+	}
+
+	if p.match(TokenType_LEFT_PAREN) {
+		paren := p.previous()
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(TokenType_RIGHT_PAREN, "Expect ')' after expression."); err != nil {
+			return nil, err
+		}
+		return &Grouping{Paren: paren, Expression: expr}, nil
+	}
+
+	if p.match(TokenType_LEFT_BRACKET) {
+		return p.list()
+	}
+
+	if p.match(TokenType_LEFT_BRACE) {
+		return p.mapLiteral()
+	}
+
+	return nil, p.error(p.peek(), "Expect expression.")
+}
+
+// interpolation parses the rest of a string containing `${expr}`. The
+// scanner has split it into INTERPOLATION tokens, each followed by the tokens
+// of an expression, ending with a STRING token.
+func (p *Parser) interpolation() (*Interpolation, error) {
+	start := p.previous()
+	parts := []Expr{&Literal{Value: start.literal, Token: start}}
+	for {
+		part, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if p.match(TokenType_INTERPOLATION) {
+			parts = append(parts, &Literal{Value: p.previous().literal, Token: p.previous()})
+			continue
+		}
+		end, err := p.consume(TokenType_STRING, "Expect '}' after interpolated expression.")
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &Literal{Value: end.literal, Token: end})
+		return &Interpolation{Start: start, Parts: parts}, nil
+	}
+}
+
+func (p *Parser) list() (*List, error) {
+	bracket := p.previous()
+	elements := []Expr{}
+
+	if !p.check(TokenType_RIGHT_BRACKET) {
+		for {
+			// Allow a trailing comma.
+			if p.check(TokenType_RIGHT_BRACKET) {
+				break
+			}
+			element, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+			if !p.match(TokenType_COMMA) {
+				break
+			}
+		}
+	}
+
+	if _, err := p.consume(TokenType_RIGHT_BRACKET, "Expect ']' after list elements."); err != nil {
+		return nil, err
+	}
+	return &List{Bracket: bracket, Elements: elements}, nil
+}
+
+func (p *Parser) mapLiteral() (*Map, error) {
+	brace := p.previous()
+	keys := []Expr{}
+	values := []Expr{}
+
+	if !p.check(TokenType_RIGHT_BRACE) {
+		for {
+			// Allow a trailing comma.
+			if p.check(TokenType_RIGHT_BRACE) {
+				break
+			}
+			key, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(TokenType_COLON, "Expect ':' after map key."); err != nil {
+				return nil, err
+			}
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+			if !p.match(TokenType_COMMA) {
+				break
+			}
+		}
+	}
+
+	if _, err := p.consume(TokenType_RIGHT_BRACE, "Expect '}' after map entries."); err != nil {
+		return nil, err
+	}
+	return &Map{Brace: brace, Keys: keys, Values: values}, nil
+}
+
+func (p *Parser) match(types ...TokenType) bool {
+	for _, t := range types {
+		if p.check(t) {
+			p.advance()
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) consume(t TokenType, message string) (*Token, error) {
+	if p.check(t) {
+		return p.advance(), nil
+	}
+
+	return nil, p.error(p.peek(), message)
+}
+
+// consumeContextual consumes an identifier that acts as a keyword only in
+// the current position.
+func (p *Parser) consumeContextual(lexeme string, message string) (*Token, error) {
+	if p.check(TokenType_IDENTIFIER) && p.peek().lexeme == lexeme {
+		return p.advance(), nil
+	}
+	return nil, p.error(p.peek(), message)
+}
+
+// error reports a syntax error at token. Where the parser can't go on, the
+// *ParseError it returns is passed up to declaration, which skips ahead to
+// the next statement.
+func (p *Parser) error(token *Token, message string) *ParseError {
+	p.lox.error(token, message)
+	return &ParseError{}
+}
+
+func (l *Lox) error(token *Token, message string) {
+	if token.t == TokenType_EOF {
+		l.report(token, " at end", message)
+	} else {
+		l.report(token, " at '"+token.lexeme+"'", message)
+	}
+}
+
+// ParseError is returned by the parser after it has reported a syntax error
+// it can't continue from.
+type ParseError struct{}
+
+func (e *ParseError) Error() string {
+	return "parse error"
+}
+
+func (p *Parser) check(t TokenType) bool {
+	if p.isAtEnd() {
+		return false
+	}
+	return p.peek().t == t
+}
+
+func (p *Parser) advance() *Token {
+	if !p.isAtEnd() {
+		p.current++
+	}
+	return p.previous()
+}
+
+func (p *Parser) checkNext(t TokenType) bool {
+	if p.isAtEnd() || p.tokens[p.current+1].t == TokenType_EOF {
+		return false
+	}
+	return p.tokens[p.current+1].t == t
+}
+
+func (p *Parser) isAtEnd() bool {
+	return p.peek().t == TokenType_EOF
+}
+func (p *Parser) peek() *Token {
+	return p.tokens[p.current]
+}
+func (p *Parser) previous() *Token {
+	return p.tokens[p.current-1]
+}
+
+func (p *Parser) synchronize() {
+	p.advance()
+
+	for !p.isAtEnd() {
+		if p.previous().t == TokenType_SEMICOLON {
+			return
+		}
+
+		switch p.peek().t {
+		case TokenType_CLASS:
+		case TokenType_FUN:
+		case TokenType_VAR:
+		case TokenType_FOR:
+		case TokenType_IF:
+		case TokenType_WHILE:
+		case TokenType_PRINT:
+		case TokenType_RETURN:
+			return
+		}
+		p.advance()
+	}
+}
@@ -0,0 +1,358 @@
+package lox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var _ = (VisitorExpr[string])(&ASTPrinter{})
+var _ = (VisitorStmt[string])(&ASTPrinter{})
+
+// ASTPrinter formats syntax trees as S-expressions, such as
+// (* (- 123) (group 45.67)), to show how they parse. Statements that hold
+// other statements put each on a line of its own, indented. Variables the
+// resolver has resolved to a local are printed with their scope depth and
+// slot, as in x@1:0.
+type ASTPrinter struct {
+	depth int
+}
+
+func (p *ASTPrinter) print(expr Expr) string {
+	return acceptExpr[string](expr, p)
+}
+
+func (p *ASTPrinter) printStmt(stmt Stmt) string {
+	return acceptStmt[string](stmt, p)
+}
+
+func (p *ASTPrinter) parenthesize(name string, exprs ...Expr) string {
+	var sb strings.Builder
+
+	sb.WriteString("(" + name)
+	for _, expr := range exprs {
+		sb.WriteString(" " + p.print(expr))
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// nest is like parenthesize for a node holding statements, which follow
+// head on lines of their own. A nil statement, left by a syntax error, is
+// skipped.
+func (p *ASTPrinter) nest(head string, stmts ...Stmt) string {
+	return p.indent(head, func(line func(string)) {
+		for _, stmt := range stmts {
+			if stmt != nil {
+				line(p.printStmt(stmt))
+			}
+		}
+	})
+}
+
+// indent formats a node whose children, which body passes to line, are on
+// lines of their own below head.
+func (p *ASTPrinter) indent(head string, body func(line func(string))) string {
+	var sb strings.Builder
+
+	sb.WriteString("(" + head)
+	p.depth++
+	body(func(s string) {
+		sb.WriteString("\n" + strings.Repeat("  ", p.depth) + s)
+	})
+	p.depth--
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// name formats the name of a variable, with where it was resolved to if
+// that is known.
+func (p *ASTPrinter) name(name *Token, ref slotRef) string {
+	if ref.resolved && ref.depth >= 0 {
+		return fmt.Sprintf("%s@%d:%d", name.lexeme, ref.depth, ref.slot)
+	}
+	return name.lexeme
+}
+
+func (p *ASTPrinter) VisitBinary(expr *Binary) string {
+	return p.parenthesize(expr.Operator.lexeme, expr.Left, expr.Right)
+}
+
+func (p *ASTPrinter) VisitGrouping(expr *Grouping) string {
+	return p.parenthesize("group", expr.Expression)
+}
+
+func (p *ASTPrinter) VisitLiteral(expr *Literal) string {
+	if s, ok := expr.Value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return stringify(expr.Value)
+}
+
+func (p *ASTPrinter) VisitUnary(expr *Unary) string {
+	return p.parenthesize(expr.Operator.lexeme, expr.Right)
+}
+
+func (p *ASTPrinter) VisitLogical(expr *Logical) string {
+	return p.parenthesize(expr.Operator.lexeme, expr.Left, expr.Right)
+}
+
+func (p *ASTPrinter) VisitVariable(expr *Variable) string {
+	return p.name(expr.Name, expr.ref)
+}
+
+func (p *ASTPrinter) VisitAssign(expr *Assign) string {
+	return p.parenthesize("= "+p.name(expr.Name, expr.ref), expr.Value)
+}
+
+func (p *ASTPrinter) VisitCall(expr *Call) string {
+	return p.parenthesize("call", append([]Expr{expr.Callee}, expr.Arguments...)...)
+}
+
+func (p *ASTPrinter) VisitGet(expr *Get) string {
+	return p.parenthesize(". "+expr.Name.lexeme, expr.Object)
+}
+
+func (p *ASTPrinter) VisitSet(expr *Set) string {
+	return p.parenthesize(".= "+expr.Name.lexeme, expr.Object, expr.Value)
+}
+
+func (p *ASTPrinter) VisitThis(expr *This) string {
+	return p.name(expr.Keyword, expr.ref)
+}
+
+func (p *ASTPrinter) VisitSuper(expr *Super) string {
+	return "(" + p.name(expr.Keyword, expr.ref) + " " + expr.Method.lexeme + ")"
+}
+
+func (p *ASTPrinter) VisitInterpolation(expr *Interpolation) string {
+	return p.parenthesize("interpolate", expr.Parts...)
+}
+
+func (p *ASTPrinter) VisitLambda(expr *Lambda) string {
+	return p.nest("fun "+nameList(expr.Function.Params), expr.Function.Body...)
+}
+
+func (p *ASTPrinter) VisitList(expr *List) string {
+	return p.parenthesize("list", expr.Elements...)
+}
+
+func (p *ASTPrinter) VisitMap(expr *Map) string {
+	entries := []Expr{}
+	for i := range expr.Keys {
+		entries = append(entries, expr.Keys[i], expr.Values[i])
+	}
+	return p.parenthesize("map", entries...)
+}
+
+func (p *ASTPrinter) VisitIndex(expr *Index) string {
+	return p.parenthesize("index", expr.Object, expr.Index)
+}
+
+func (p *ASTPrinter) VisitSetIndex(expr *SetIndex) string {
+	return p.parenthesize("index=", expr.Object, expr.Index, expr.Value)
+}
+
+func (p *ASTPrinter) VisitExpression(stmt *Expression) string {
+	return p.parenthesize(";", stmt.Expression)
+}
+
+func (p *ASTPrinter) VisitIf(stmt *If) string {
+	if stmt.Else == nil {
+		return p.nest("if "+p.print(stmt.Condition), stmt.Then)
+	}
+	return p.nest("if "+p.print(stmt.Condition), stmt.Then, stmt.Else)
+}
+
+func (p *ASTPrinter) VisitFunction(stmt *Function) string {
+	return p.nest("fun "+stmt.Name.lexeme+" "+nameList(stmt.Params), stmt.Body...)
+}
+
+func (p *ASTPrinter) VisitReturn(stmt *Return) string {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return p.parenthesize("return", stmt.Value)
+}
+
+func (p *ASTPrinter) VisitPrint(stmt *Print) string {
+	return p.parenthesize("print", stmt.Expression)
+}
+
+func (p *ASTPrinter) VisitVar(stmt *Var) string {
+	if stmt.Initializer == nil {
+		return "(var " + stmt.Name.lexeme + ")"
+	}
+	return p.parenthesize("var "+stmt.Name.lexeme, stmt.Initializer)
+}
+
+func (p *ASTPrinter) VisitWhile(stmt *While) string {
+	return p.indent("while "+p.print(stmt.Condition), func(line func(string)) {
+		line(p.printStmt(stmt.Body))
+		// The increment of a desugared `for` loop runs after the body.
+		if stmt.Increment != nil {
+			line(p.parenthesize("increment", stmt.Increment))
+		}
+	})
+}
+
+func (p *ASTPrinter) VisitBreak(stmt *Break) string {
+	return "(break)"
+}
+
+func (p *ASTPrinter) VisitContinue(stmt *Continue) string {
+	return "(continue)"
+}
+
+func (p *ASTPrinter) VisitImport(stmt *Import) string {
+	path := stmt.Path.lexeme
+	switch {
+	case len(stmt.Names) > 0:
+		return "(import " + nameList(stmt.Names) + " from " + path + ")"
+	case stmt.Alias != nil:
+		return "(import " + path + " as " + stmt.Alias.lexeme + ")"
+	}
+	return "(import " + path + ")"
+}
+
+func (p *ASTPrinter) VisitExport(stmt *Export) string {
+	return "(export " + p.printStmt(stmt.Declaration) + ")"
+}
+
+func (p *ASTPrinter) VisitThrow(stmt *Throw) string {
+	return p.parenthesize("throw", stmt.Value)
+}
+
+func (p *ASTPrinter) VisitTry(stmt *Try) string {
+	return p.indent("try", func(line func(string)) {
+		for _, s := range stmt.Body {
+			if s != nil {
+				line(p.printStmt(s))
+			}
+		}
+		if stmt.CatchName != nil {
+			line(p.nest("catch "+stmt.CatchName.lexeme, stmt.Catch...))
+		}
+		if stmt.Finally != nil {
+			line(p.nest("finally", stmt.Finally...))
+		}
+	})
+}
+
+func (p *ASTPrinter) VisitBlock(stmt *Block) string {
+	return p.nest("block", stmt.Statements...)
+}
+
+func (p *ASTPrinter) VisitClass(stmt *Class) string {
+	head := "class " + stmt.Name.lexeme
+	if stmt.SuperClass != nil {
+		head += " < " + p.print(stmt.SuperClass)
+	}
+	methods := make([]Stmt, len(stmt.Methods))
+	for i, method := range stmt.Methods {
+		methods[i] = method
+	}
+	return p.nest(head, methods...)
+}
+
+// nameList formats a list of names, such as (a b).
+func nameList(names []*Token) string {
+	ss := make([]string, len(names))
+	for i, name := range names {
+		ss[i] = name.lexeme
+	}
+	return "(" + strings.Join(ss, " ") + ")"
+}
+
+type DumpFormat string
+
+const (
+	DumpFormat_SEXPR DumpFormat = "sexpr"
+	DumpFormat_JSON  DumpFormat = "json"
+)
+
+// DumpTokens writes the tokens source scans to, one per line: as
+// (TYPE "lexeme" line:column), with the literal value of strings and
+// numbers after the lexeme, or as a JSON array of objects.
+func DumpTokens(w io.Writer, source string, format DumpFormat) error {
+	l := New()
+	tokens := NewScanner(l, source).scanTokens()
+	if l.hadError {
+		return l.compileError()
+	}
+
+	if format == DumpFormat_JSON {
+		objects := make([]map[string]any, len(tokens))
+		for i, token := range tokens {
+			objects[i] = map[string]any{
+				"type":    token.t,
+				"lexeme":  token.lexeme,
+				"literal": token.literal,
+				"line":    token.line,
+				"column":  token.column,
+				"offset":  token.offset,
+			}
+		}
+		return writeJSON(w, objects)
+	}
+
+	for _, token := range tokens {
+		literal := ""
+		switch token.literal.(type) {
+		case string:
+			literal = " " + strconv.Quote(token.literal.(string))
+		case float64:
+			literal = " " + stringify(token.literal)
+		}
+		if _, err := fmt.Fprintf(w, "(%s %s%s %d:%d)\n", token.t, strconv.Quote(token.lexeme), literal, token.line, token.column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpAST writes the statements source parses to, as S-expressions one
+// statement to a line, or as a JSON array of the nodes' JSON encoding. With resolved, source is also
+// resolved, and each variable resolved to a local scope is annotated with
+// the number of scopes between it and its declaration and its slot there.
+// Variables without one are globals.
+func DumpAST(w io.Writer, source string, format DumpFormat, resolved bool) error {
+	l := New()
+	tokens := NewScanner(l, source).scanTokens()
+	statements := NewParser(l, tokens).parse()
+	if l.hadError {
+		return l.compileError()
+	}
+
+	if resolved {
+		NewResolver(l, l.interpreter).resolveStmts(statements)
+		if l.hadError {
+			return l.compileError()
+		}
+	}
+
+	if format == DumpFormat_JSON {
+		return writeJSON(w, statements)
+	}
+
+	p := &ASTPrinter{}
+	for _, stmt := range statements {
+		if _, err := fmt.Fprintln(w, p.printStmt(stmt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,221 @@
+package lox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const replHelp = `Enter Lox statements or expressions. The value of an expression is printed
+unless it is nil. Lines are read until brackets and strings are closed.
+
+  :load <file>  run a script, keeping its globals
+  :env          list the global variables
+  :ast <expr>   show how an expression parses
+  :history      list the previous entries
+  :help         show this help
+  :quit         leave the REPL
+`
+
+// REPL reads Lox code from a user, runs it on a Lox and prints the results.
+// Definitions persist from one entry to the next, including after errors.
+// Errors are reported to the Lox's stderr.
+type REPL struct {
+	lox *Lox
+	in  *bufio.Reader
+	out io.Writer
+	// history holds the entries read so far, oldest first. New entries are
+	// appended to historyFile when it is set.
+	history     []string
+	historyFile string
+}
+
+func NewREPL(lox *Lox, in io.Reader, out io.Writer) *REPL {
+	return &REPL{
+		lox: lox,
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// LoadHistory reads the entries saved in the history file at path, if it
+// exists, and saves the entries read from then on to it.
+func (r *REPL) LoadHistory(path string) error {
+	r.historyFile = path
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Entries are quoted, one per line, as they may span several lines.
+	for _, line := range strings.Split(string(b), "\n") {
+		if entry, err := strconv.Unquote(line); err == nil {
+			r.history = append(r.history, entry)
+		}
+	}
+	return nil
+}
+
+// Run reads and runs entries until the input ends or the user quits.
+func (r *REPL) Run() error {
+	for {
+		entry, err := r.read()
+		if err == io.EOF {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if err := r.addHistory(entry); err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry, ":") {
+			if quit := r.command(entry); quit {
+				return nil
+			}
+			continue
+		}
+
+		value, isExpr, err := r.lox.runLine(entry)
+		if err == nil && isExpr && value != nil {
+			fmt.Fprintln(r.out, stringify(value))
+		}
+	}
+}
+
+// read reads an entry, prompting for more lines while it has unclosed
+// brackets or strings.
+func (r *REPL) read() (string, error) {
+	var sb strings.Builder
+	prompt := "> "
+	for {
+		fmt.Fprint(r.out, prompt)
+		line, err := r.in.ReadString('\n')
+		sb.WriteString(line)
+		if err == io.EOF && sb.Len() > 0 {
+			// Run what was typed before the input ended.
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		entry := sb.String()
+		if strings.HasPrefix(strings.TrimSpace(entry), ":") || !incomplete(entry) {
+			return entry, nil
+		}
+		prompt = "... "
+	}
+}
+
+// incomplete reports whether source ends inside brackets or a string.
+func incomplete(source string) bool {
+	// Scan with a Lox of its own so that errors go unreported.
+	l := &Lox{stderr: io.Discard}
+	tokens := NewScanner(l, source).scanTokens()
+	for _, err := range l.errors {
+		if strings.HasPrefix(err.Message, "Unterminated string") {
+			return true
+		}
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.t {
+		case TokenType_LEFT_PAREN, TokenType_LEFT_BRACE, TokenType_LEFT_BRACKET:
+			depth++
+		case TokenType_RIGHT_PAREN, TokenType_RIGHT_BRACE, TokenType_RIGHT_BRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func (r *REPL) addHistory(entry string) error {
+	r.history = append(r.history, entry)
+	if r.historyFile == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, strconv.Quote(entry)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// command runs a meta command such as ":env". It reports true if the user
+// asked to quit.
+func (r *REPL) command(entry string) (quit bool) {
+	name, arg, _ := strings.Cut(entry, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":quit", ":exit":
+		return true
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":load":
+		if arg == "" {
+			fmt.Fprintln(r.out, "usage: :load <file>")
+			break
+		}
+		// Errors in the script have been reported.
+		if err := r.lox.RunFile(arg); err != nil && !isLoxError(err) {
+			fmt.Fprintln(r.out, err.Error())
+		}
+	case ":env":
+		globals := r.lox.globalValues()
+		names := make([]string, 0, len(globals))
+		for name := range globals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(r.out, "%s = %s\n", name, stringify(globals[name]))
+		}
+	case ":ast":
+		if arg == "" {
+			fmt.Fprintln(r.out, "usage: :ast <expr>")
+			break
+		}
+		if expr, err := r.lox.parseExpr(arg); err == nil {
+			fmt.Fprintln(r.out, (&ASTPrinter{}).print(expr))
+		}
+	case ":history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, entry)
+		}
+	default:
+		fmt.Fprintf(r.out, "Unknown command '%s'. Enter :help for a list.\n", name)
+	}
+	return false
+}
+
+// isLoxError reports whether err is an error in a Lox program, which has
+// already been reported.
+func isLoxError(err error) bool {
+	var compileErr *CompileError
+	var runtimeErr *RuntimeError
+	return errors.As(err, &compileErr) || errors.As(err, &runtimeErr)
+}
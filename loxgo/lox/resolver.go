@@ -0,0 +1,510 @@
+package lox
+
+var (
+	_ (VisitorExpr[any]) = (*Resolver)(nil)
+	_ (VisitorStmt[any]) = (*Resolver)(nil)
+)
+
+type Resolver struct {
+	lox          *Lox
+	itrp         *Interpreter
+	scopes       []*scope
+	currentFn    FunctionType
+	currentClass ClassType
+	loopDepth    int
+	// analysis, when set, records declarations and uses for Analyze.
+	// declared holds the tokens that declare the names in each scope, and
+	// ends the last token of each scope's source.
+	analysis *analyzer
+	declared []map[string]*Token
+	ends     []*Token
+	// lint, when set, collects the warnings of Lint.
+	lint *linter
+}
+
+// scope is a scope being resolved. The names declared in it are numbered in
+// order, giving their slots in the environments created for the scope.
+type scope struct {
+	slots map[string]int
+	names []string
+	// defined is false for a name while its declaration is being resolved.
+	defined []bool
+}
+
+func newScope() *scope {
+	return &scope{slots: map[string]int{}}
+}
+
+// add declares name in the next slot.
+func (s *scope) add(name string) {
+	s.slots[name] = len(s.names)
+	s.names = append(s.names, name)
+	s.defined = append(s.defined, false)
+}
+
+type FunctionType string
+
+const (
+	FunctionType_NONE        FunctionType = "NONE"
+	FunctionType_FUNCTION    FunctionType = "FUNCTION"
+	FunctionType_METHOD      FunctionType = "METHOD"
+	FunctionType_INITIALIZER FunctionType = "INITIALIZER"
+)
+
+type ClassType string
+
+const (
+	ClassType_NONE     ClassType = "NONE"
+	ClassType_CLASS    ClassType = "CLASS"
+	ClassType_SUBCLASS ClassType = "SUBCLASS"
+)
+
+func NewResolver(lox *Lox, itrp *Interpreter) *Resolver {
+	return &Resolver{
+		lox:          lox,
+		itrp:         itrp,
+		scopes:       []*scope{},
+		currentFn:    FunctionType_NONE,
+		currentClass: ClassType_NONE,
+	}
+}
+
+func (r *Resolver) VisitBinary(expr *Binary) any {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+func (r *Resolver) VisitGrouping(expr *Grouping) any {
+	r.resolveExpr(expr.Expression)
+	return nil
+}
+func (r *Resolver) VisitCall(expr *Call) any {
+	r.resolveExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		r.resolveExpr(arg)
+	}
+	if r.lint != nil {
+		r.lint.call(expr)
+	}
+	return nil
+}
+func (r *Resolver) VisitLiteral(expr *Literal) any {
+	return nil
+}
+func (r *Resolver) VisitUnary(expr *Unary) any {
+	r.resolveExpr(expr.Right)
+	return nil
+}
+func (r *Resolver) VisitSet(expr *Set) any {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	if r.lint != nil {
+		r.lint.set(expr)
+	}
+	return nil
+}
+func (r *Resolver) VisitSuper(expr *Super) any {
+	if r.currentClass == ClassType_NONE {
+		r.lox.error(expr.Keyword, "Can't use 'super' outside of a class.")
+	} else if r.currentClass != ClassType_SUBCLASS {
+		r.lox.error(expr.Keyword, "Can't use 'super' in a class with no superclass.")
+	}
+
+	expr.ref = r.resolveLocal(expr.Keyword)
+	return nil
+}
+func (r *Resolver) VisitThis(expr *This) any {
+	if r.currentClass == ClassType_NONE {
+		r.lox.error(expr.Keyword, "Can't use 'this' outside of a class.")
+	}
+
+	expr.ref = r.resolveLocal(expr.Keyword)
+	return nil
+}
+func (r *Resolver) VisitLogical(expr *Logical) any {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+func (r *Resolver) VisitVariable(expr *Variable) any {
+	if len(r.scopes) > 0 {
+		last := r.scopes[len(r.scopes)-1]
+		slot, ok := last.slots[expr.Name.lexeme]
+		if ok && !last.defined[slot] {
+			r.lox.error(expr.Name,
+				"Can't read local variable in its own initializer.")
+		}
+	}
+
+	expr.ref = r.resolveLocal(expr.Name)
+	if r.lint != nil {
+		r.lint.read(expr.Name)
+	}
+	return nil
+}
+func (r *Resolver) VisitAssign(expr *Assign) any {
+	r.resolveExpr(expr.Value)
+	expr.ref = r.resolveLocal(expr.Name)
+	if r.lint != nil {
+		r.lint.assign(expr.Name)
+	}
+	return nil
+}
+func (r *Resolver) VisitInterpolation(expr *Interpolation) any {
+	for _, part := range expr.Parts {
+		r.resolveExpr(part)
+	}
+	return nil
+}
+func (r *Resolver) VisitLambda(expr *Lambda) any {
+	r.resolveFunction(expr.Function, FunctionType_FUNCTION)
+	return nil
+}
+func (r *Resolver) VisitList(expr *List) any {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+func (r *Resolver) VisitMap(expr *Map) any {
+	for i := range expr.Keys {
+		r.resolveExpr(expr.Keys[i])
+		r.resolveExpr(expr.Values[i])
+	}
+	return nil
+}
+func (r *Resolver) VisitIndex(expr *Index) any {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+func (r *Resolver) VisitSetIndex(expr *SetIndex) any {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+func (r *Resolver) VisitExpression(stmt *Expression) any {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+func (r *Resolver) VisitGet(stmt *Get) any {
+	r.resolveExpr(stmt.Object)
+	if r.lint != nil {
+		r.lint.get(stmt)
+	}
+	return nil
+}
+func (r *Resolver) VisitIf(stmt *If) any {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Then)
+	if stmt.Else != nil {
+		r.resolveStmt(stmt.Else)
+	}
+	return nil
+}
+func (r *Resolver) VisitFunction(stmt *Function) any {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.describe(stmt.Name, SymbolKind_FUNCTION, "fun "+signature(stmt), nil)
+	if r.lint != nil {
+		r.lint.function(stmt)
+	}
+
+	r.resolveFunction(stmt, FunctionType_FUNCTION)
+	return nil
+}
+func (r *Resolver) VisitReturn(stmt *Return) any {
+	if r.currentFn == FunctionType_NONE {
+		r.lox.error(stmt.Keyword, "Can't return from top-level code.")
+	}
+
+	if stmt.Value != nil {
+		if r.currentFn == FunctionType_INITIALIZER {
+			r.lox.error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+func (r *Resolver) VisitPrint(stmt *Print) any {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+func (r *Resolver) VisitVar(stmt *Var) any {
+	r.declare(stmt.Name)
+	r.describe(stmt.Name, SymbolKind_VARIABLE, "var "+stmt.Name.lexeme, nil)
+	if stmt.Initializer != nil {
+		r.resolveExpr(stmt.Initializer)
+	}
+	r.define(stmt.Name)
+	return nil
+}
+func (r *Resolver) VisitWhile(stmt *While) any {
+	r.resolveExpr(stmt.Condition)
+	r.loopDepth++
+	r.resolveStmt(stmt.Body)
+	r.loopDepth--
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+func (r *Resolver) VisitImport(stmt *Import) any {
+	if len(r.scopes) != 0 {
+		r.lox.error(stmt.Keyword, "Can only import at top level.")
+		return nil
+	}
+
+	// The names are defined when the import runs; declaring them only
+	// matters to Analyze.
+	if stmt.Alias != nil {
+		r.declare(stmt.Alias)
+		r.describe(stmt.Alias, SymbolKind_VARIABLE, "import "+stmt.Path.lexeme+" as "+stmt.Alias.lexeme, nil)
+	}
+	for _, name := range stmt.Names {
+		r.declare(name)
+		r.describe(name, SymbolKind_VARIABLE, "import { "+name.lexeme+" } from "+stmt.Path.lexeme, nil)
+	}
+	return nil
+}
+func (r *Resolver) VisitExport(stmt *Export) any {
+	if len(r.scopes) != 0 {
+		r.lox.error(stmt.Keyword, "Can only export at top level.")
+	}
+	r.resolveStmt(stmt.Declaration)
+	return nil
+}
+func (r *Resolver) VisitThrow(stmt *Throw) any {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+func (r *Resolver) VisitTry(stmt *Try) any {
+	r.beginScope(stmt.bodyEnd)
+	r.resolveStmts(stmt.Body)
+	stmt.bodyLocals = r.endScope()
+
+	if stmt.Catch != nil {
+		r.beginScope(stmt.catchEnd)
+		r.declare(stmt.CatchName)
+		r.define(stmt.CatchName)
+		r.describe(stmt.CatchName, SymbolKind_VARIABLE, "catch ("+stmt.CatchName.lexeme+")", nil)
+		r.resolveStmts(stmt.Catch)
+		stmt.catchLocals = r.endScope()
+	}
+
+	if stmt.Finally != nil {
+		r.beginScope(stmt.finallyEnd)
+		r.resolveStmts(stmt.Finally)
+		stmt.finallyLocals = r.endScope()
+	}
+	return nil
+}
+func (r *Resolver) VisitBreak(stmt *Break) any {
+	if r.loopDepth == 0 {
+		r.lox.error(stmt.Keyword, "Can't use 'break' outside of a loop.")
+	}
+	return nil
+}
+func (r *Resolver) VisitContinue(stmt *Continue) any {
+	if r.loopDepth == 0 {
+		r.lox.error(stmt.Keyword, "Can't use 'continue' outside of a loop.")
+	}
+	return nil
+}
+func (r *Resolver) VisitBlock(stmt *Block) any {
+	r.beginScope(stmt.end)
+	r.resolveStmts(stmt.Statements)
+	stmt.locals = r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitClass(stmt *Class) any {
+	enclosing := r.currentClass
+	r.currentClass = ClassType_CLASS
+
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	detail := "class " + stmt.Name.lexeme
+	if stmt.SuperClass != nil {
+		detail += " < " + stmt.SuperClass.Name.lexeme
+	}
+	class := r.describe(stmt.Name, SymbolKind_CLASS, detail, nil)
+	if r.lint != nil {
+		r.lint.beginClass(stmt)
+		defer r.lint.endClass()
+	}
+
+	if stmt.SuperClass != nil && stmt.Name.lexeme == stmt.SuperClass.Name.lexeme {
+		r.lox.error(stmt.SuperClass.Name, "A class can't inherit from itself.")
+	}
+
+	if stmt.SuperClass != nil {
+		r.currentClass = ClassType_SUBCLASS
+		r.resolveExpr(stmt.SuperClass)
+	}
+
+	if stmt.SuperClass != nil {
+		r.beginScope(nil)
+		r.declareImplicit("super")
+	}
+
+	r.beginScope(nil)
+	r.declareImplicit("this")
+
+	for _, method := range stmt.Methods {
+		declaration := FunctionType_METHOD
+		if method.Name.lexeme == "init" {
+			declaration = FunctionType_INITIALIZER
+		}
+		r.describe(method.Name, SymbolKind_METHOD, stmt.Name.lexeme+"."+signature(method), class)
+		r.resolveFunction(method, declaration)
+	}
+	r.endScope()
+
+	if stmt.SuperClass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosing
+	return nil
+}
+
+func (r *Resolver) resolveStmts(statements []Stmt) {
+	for _, statement := range statements {
+		r.resolveStmt(statement)
+	}
+	if r.lint != nil {
+		r.lint.unreachable(statements)
+	}
+}
+
+func (r *Resolver) resolveStmt(stmt Stmt) {
+	// Statements that failed to parse are nil; Analyze resolves the rest.
+	if stmt == nil {
+		return
+	}
+	acceptStmt[any](stmt, r)
+}
+
+func (r *Resolver) resolveExpr(expr Expr) {
+	acceptExpr[any](expr, r)
+}
+
+// beginScope begins a scope whose source ends with the token end, or nil
+// for the implicit scopes of a class.
+func (r *Resolver) beginScope(end *Token) {
+	r.scopes = append(r.scopes, newScope())
+	if r.analysis != nil {
+		r.declared = append(r.declared, map[string]*Token{})
+		r.ends = append(r.ends, end)
+	}
+	if r.lint != nil {
+		r.lint.beginScope()
+	}
+}
+
+// endScope returns the names of the slots of the scope it ends.
+func (r *Resolver) endScope() []string {
+	names := r.scopes[len(r.scopes)-1].names
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	if r.analysis != nil {
+		r.declared = r.declared[:len(r.declared)-1]
+		r.ends = r.ends[:len(r.ends)-1]
+	}
+	if r.lint != nil {
+		r.lint.endScope()
+	}
+	return names
+}
+func (r *Resolver) declare(name *Token) {
+	if r.analysis != nil {
+		if len(r.declared) == 0 {
+			r.analysis.global(name)
+		} else {
+			r.declared[len(r.declared)-1][name.lexeme] = name
+		}
+	}
+
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+
+	if _, ok := scope.slots[name.lexeme]; ok {
+		r.lox.error(name,
+			"Already a variable with this name in this scope.")
+		return
+	}
+
+	scope.add(name.lexeme)
+}
+
+// declareImplicit declares and defines a name that the code of the scope
+// doesn't declare itself, as `this` is.
+func (r *Resolver) declareImplicit(name string) {
+	scope := r.scopes[len(r.scopes)-1]
+	scope.add(name)
+	scope.defined[len(scope.defined)-1] = true
+}
+func (r *Resolver) define(name *Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	scope.defined[scope.slots[name.lexeme]] = true
+}
+
+// resolveLocal finds the variable that name refers to where it is used. A
+// name that isn't declared in an enclosing scope is a global.
+func (r *Resolver) resolveLocal(name *Token) slotRef {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, ok := r.scopes[i].slots[name.lexeme]; ok {
+			if r.analysis != nil {
+				r.analysis.use(name, r.declared[i][name.lexeme])
+			}
+			return slotRef{depth: len(r.scopes) - 1 - i, slot: slot, resolved: true}
+		}
+	}
+
+	if r.analysis != nil {
+		r.analysis.use(name, nil)
+	}
+	return slotRef{depth: -1, slot: r.itrp.globalNames.slot(name.lexeme), resolved: true}
+}
+
+// describe records the declaration of name for Analyze and Lint, if they are
+// running. Methods are recorded with their class as parent.
+func (r *Resolver) describe(name *Token, kind SymbolKind, detail string, parent *Symbol) *Symbol {
+	if r.lint != nil {
+		r.lint.declare(name, kind)
+	}
+	if r.analysis == nil {
+		return nil
+	}
+	var end *Token
+	if len(r.ends) > 0 {
+		end = r.ends[len(r.ends)-1]
+	}
+	return r.analysis.symbol(name, kind, detail, parent, len(r.scopes) == 0, end)
+}
+func (r *Resolver) resolveFunction(fn *Function, ft FunctionType) {
+	enclosingFn := r.currentFn
+	r.currentFn = ft
+	// A loop around a function declaration doesn't extend into its body.
+	enclosingLoopDepth := r.loopDepth
+	r.loopDepth = 0
+
+	r.beginScope(fn.end)
+	for _, param := range fn.Params {
+		r.declare(param)
+		r.define(param)
+		r.describe(param, SymbolKind_PARAMETER, "parameter "+param.lexeme, nil)
+	}
+	r.resolveStmts(fn.Body)
+	fn.locals = r.endScope()
+	r.currentFn = enclosingFn
+	r.loopDepth = enclosingLoopDepth
+}
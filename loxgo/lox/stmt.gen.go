@@ -0,0 +1,752 @@
+// DO NOT EDIT - generated code!
+package lox
+
+import "encoding/json"
+
+// Stmt is implemented by every statement node of the syntax tree.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+type Expression struct {
+	Expression Expr
+}
+
+func (e *Expression) Pos() Position { return e.Expression.Pos() }
+func (*Expression) stmtNode()       {}
+
+type If struct {
+	Keyword   *Token
+	Condition Expr
+	Then      Stmt
+	Else      Stmt
+}
+
+func (e *If) Pos() Position { return e.Keyword.pos() }
+func (*If) stmtNode()       {}
+
+type Function struct {
+	Name   *Token
+	Params []*Token
+	Body   []Stmt
+	end    *Token
+	locals []string
+}
+
+func (e *Function) Pos() Position { return e.Name.pos() }
+func (*Function) stmtNode()       {}
+
+type Return struct {
+	Keyword *Token
+	Value   Expr
+}
+
+func (e *Return) Pos() Position { return e.Keyword.pos() }
+func (*Return) stmtNode()       {}
+
+type Print struct {
+	Keyword    *Token
+	Expression Expr
+}
+
+func (e *Print) Pos() Position { return e.Keyword.pos() }
+func (*Print) stmtNode()       {}
+
+type Var struct {
+	Name        *Token
+	Initializer Expr
+}
+
+func (e *Var) Pos() Position { return e.Name.pos() }
+func (*Var) stmtNode()       {}
+
+type While struct {
+	Keyword   *Token
+	Condition Expr
+	Body      Stmt
+	Increment Expr
+}
+
+func (e *While) Pos() Position { return e.Keyword.pos() }
+func (*While) stmtNode()       {}
+
+type Break struct {
+	Keyword *Token
+}
+
+func (e *Break) Pos() Position { return e.Keyword.pos() }
+func (*Break) stmtNode()       {}
+
+type Continue struct {
+	Keyword *Token
+}
+
+func (e *Continue) Pos() Position { return e.Keyword.pos() }
+func (*Continue) stmtNode()       {}
+
+type Import struct {
+	Keyword *Token
+	Path    *Token
+	Alias   *Token
+	Names   []*Token
+}
+
+func (e *Import) Pos() Position { return e.Keyword.pos() }
+func (*Import) stmtNode()       {}
+
+type Export struct {
+	Keyword     *Token
+	Declaration Stmt
+}
+
+func (e *Export) Pos() Position { return e.Keyword.pos() }
+func (*Export) stmtNode()       {}
+
+type Throw struct {
+	Keyword *Token
+	Value   Expr
+}
+
+func (e *Throw) Pos() Position { return e.Keyword.pos() }
+func (*Throw) stmtNode()       {}
+
+type Try struct {
+	Keyword       *Token
+	Body          []Stmt
+	CatchName     *Token
+	Catch         []Stmt
+	Finally       []Stmt
+	bodyEnd       *Token
+	catchEnd      *Token
+	finallyEnd    *Token
+	bodyLocals    []string
+	catchLocals   []string
+	finallyLocals []string
+}
+
+func (e *Try) Pos() Position { return e.Keyword.pos() }
+func (*Try) stmtNode()       {}
+
+type Block struct {
+	Brace      *Token
+	Statements []Stmt
+	end        *Token
+	locals     []string
+}
+
+func (e *Block) Pos() Position { return e.Brace.pos() }
+func (*Block) stmtNode()       {}
+
+type Class struct {
+	Name       *Token
+	SuperClass *Variable
+	Methods    []*Function
+}
+
+func (e *Class) Pos() Position { return e.Name.pos() }
+func (*Class) stmtNode()       {}
+
+// VisitorStmt has a method for each type of statement, returning an R.
+type VisitorStmt[R any] interface {
+	VisitExpression(stmt *Expression) R
+	VisitIf(stmt *If) R
+	VisitFunction(stmt *Function) R
+	VisitReturn(stmt *Return) R
+	VisitPrint(stmt *Print) R
+	VisitVar(stmt *Var) R
+	VisitWhile(stmt *While) R
+	VisitBreak(stmt *Break) R
+	VisitContinue(stmt *Continue) R
+	VisitImport(stmt *Import) R
+	VisitExport(stmt *Export) R
+	VisitThrow(stmt *Throw) R
+	VisitTry(stmt *Try) R
+	VisitBlock(stmt *Block) R
+	VisitClass(stmt *Class) R
+}
+
+// acceptStmt calls the method of v for the type of stmt.
+func acceptStmt[R any](stmt Stmt, v VisitorStmt[R]) R {
+	switch e := stmt.(type) {
+	case *Expression:
+		return v.VisitExpression(e)
+	case *If:
+		return v.VisitIf(e)
+	case *Function:
+		return v.VisitFunction(e)
+	case *Return:
+		return v.VisitReturn(e)
+	case *Print:
+		return v.VisitPrint(e)
+	case *Var:
+		return v.VisitVar(e)
+	case *While:
+		return v.VisitWhile(e)
+	case *Break:
+		return v.VisitBreak(e)
+	case *Continue:
+		return v.VisitContinue(e)
+	case *Import:
+		return v.VisitImport(e)
+	case *Export:
+		return v.VisitExport(e)
+	case *Throw:
+		return v.VisitThrow(e)
+	case *Try:
+		return v.VisitTry(e)
+	case *Block:
+		return v.VisitBlock(e)
+	case *Class:
+		return v.VisitClass(e)
+	}
+	panic("lox: unknown stmt")
+}
+
+// CloneStmt returns a deep copy of stmt, as the Clone method of its type does.
+func CloneStmt(stmt Stmt) Stmt {
+	switch e := stmt.(type) {
+	case *Expression:
+		return e.Clone()
+	case *If:
+		return e.Clone()
+	case *Function:
+		return e.Clone()
+	case *Return:
+		return e.Clone()
+	case *Print:
+		return e.Clone()
+	case *Var:
+		return e.Clone()
+	case *While:
+		return e.Clone()
+	case *Break:
+		return e.Clone()
+	case *Continue:
+		return e.Clone()
+	case *Import:
+		return e.Clone()
+	case *Export:
+		return e.Clone()
+	case *Throw:
+		return e.Clone()
+	case *Try:
+		return e.Clone()
+	case *Block:
+		return e.Clone()
+	case *Class:
+		return e.Clone()
+	}
+	return nil
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Expression) Clone() *Expression {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Expression = CloneExpr(e.Expression)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *If) Clone() *If {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Condition = CloneExpr(e.Condition)
+	c.Then = CloneStmt(e.Then)
+	c.Else = CloneStmt(e.Else)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Function) Clone() *Function {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Params = cloneList(e.Params, sameToken)
+	c.Body = cloneList(e.Body, CloneStmt)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Return) Clone() *Return {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Value = CloneExpr(e.Value)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Print) Clone() *Print {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Expression = CloneExpr(e.Expression)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Var) Clone() *Var {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Initializer = CloneExpr(e.Initializer)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *While) Clone() *While {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Condition = CloneExpr(e.Condition)
+	c.Body = CloneStmt(e.Body)
+	c.Increment = CloneExpr(e.Increment)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Break) Clone() *Break {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Continue) Clone() *Continue {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Import) Clone() *Import {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Names = cloneList(e.Names, sameToken)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Export) Clone() *Export {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Declaration = CloneStmt(e.Declaration)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Throw) Clone() *Throw {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Value = CloneExpr(e.Value)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Try) Clone() *Try {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Body = cloneList(e.Body, CloneStmt)
+	c.Catch = cloneList(e.Catch, CloneStmt)
+	c.Finally = cloneList(e.Finally, CloneStmt)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Block) Clone() *Block {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Statements = cloneList(e.Statements, CloneStmt)
+	return &c
+}
+
+// Clone returns a deep copy of e. Tokens, which don't change once scanned, are shared.
+func (e *Class) Clone() *Class {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.SuperClass = e.SuperClass.Clone()
+	c.Methods = cloneList(e.Methods, (*Function).Clone)
+	return &c
+}
+
+func equalStmt(stmt Stmt, other Node) bool {
+	switch e := stmt.(type) {
+	case *Expression:
+		o, ok := other.(*Expression)
+		return ok && e.equal(o)
+	case *If:
+		o, ok := other.(*If)
+		return ok && e.equal(o)
+	case *Function:
+		o, ok := other.(*Function)
+		return ok && e.equal(o)
+	case *Return:
+		o, ok := other.(*Return)
+		return ok && e.equal(o)
+	case *Print:
+		o, ok := other.(*Print)
+		return ok && e.equal(o)
+	case *Var:
+		o, ok := other.(*Var)
+		return ok && e.equal(o)
+	case *While:
+		o, ok := other.(*While)
+		return ok && e.equal(o)
+	case *Break:
+		o, ok := other.(*Break)
+		return ok && e.equal(o)
+	case *Continue:
+		o, ok := other.(*Continue)
+		return ok && e.equal(o)
+	case *Import:
+		o, ok := other.(*Import)
+		return ok && e.equal(o)
+	case *Export:
+		o, ok := other.(*Export)
+		return ok && e.equal(o)
+	case *Throw:
+		o, ok := other.(*Throw)
+		return ok && e.equal(o)
+	case *Try:
+		o, ok := other.(*Try)
+		return ok && e.equal(o)
+	case *Block:
+		o, ok := other.(*Block)
+		return ok && e.equal(o)
+	case *Class:
+		o, ok := other.(*Class)
+		return ok && e.equal(o)
+	}
+	return false
+}
+
+func (e *Expression) equal(o *Expression) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return Equal(e.Expression, o.Expression)
+}
+
+func (e *If) equal(o *If) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Condition, o.Condition) &&
+		Equal(e.Then, o.Then) &&
+		Equal(e.Else, o.Else)
+}
+
+func (e *Function) equal(o *Function) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Name, o.Name) &&
+		equalList(e.Params, o.Params, equalToken) &&
+		equalList(e.Body, o.Body, equalNode[Stmt])
+}
+
+func (e *Return) equal(o *Return) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Value, o.Value)
+}
+
+func (e *Print) equal(o *Print) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Expression, o.Expression)
+}
+
+func (e *Var) equal(o *Var) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Name, o.Name) &&
+		Equal(e.Initializer, o.Initializer)
+}
+
+func (e *While) equal(o *While) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Condition, o.Condition) &&
+		Equal(e.Body, o.Body) &&
+		Equal(e.Increment, o.Increment)
+}
+
+func (e *Break) equal(o *Break) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword)
+}
+
+func (e *Continue) equal(o *Continue) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword)
+}
+
+func (e *Import) equal(o *Import) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		equalToken(e.Path, o.Path) &&
+		equalToken(e.Alias, o.Alias) &&
+		equalList(e.Names, o.Names, equalToken)
+}
+
+func (e *Export) equal(o *Export) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Declaration, o.Declaration)
+}
+
+func (e *Throw) equal(o *Throw) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		Equal(e.Value, o.Value)
+}
+
+func (e *Try) equal(o *Try) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Keyword, o.Keyword) &&
+		equalList(e.Body, o.Body, equalNode[Stmt]) &&
+		equalToken(e.CatchName, o.CatchName) &&
+		equalList(e.Catch, o.Catch, equalNode[Stmt]) &&
+		equalList(e.Finally, o.Finally, equalNode[Stmt])
+}
+
+func (e *Block) equal(o *Block) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Brace, o.Brace) &&
+		equalList(e.Statements, o.Statements, equalNode[Stmt])
+}
+
+func (e *Class) equal(o *Class) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+	return equalToken(e.Name, o.Name) &&
+		Equal(e.SuperClass, o.SuperClass) &&
+		equalList(e.Methods, o.Methods, equalNode[*Function])
+}
+
+// walkStmt walks the children of stmt, in the order of their fields.
+func walkStmt(v Visitor, stmt Stmt) {
+	switch e := stmt.(type) {
+	case *Expression:
+		if e.Expression != nil {
+			Walk(v, e.Expression)
+		}
+	case *If:
+		if e.Condition != nil {
+			Walk(v, e.Condition)
+		}
+		if e.Then != nil {
+			Walk(v, e.Then)
+		}
+		if e.Else != nil {
+			Walk(v, e.Else)
+		}
+	case *Function:
+		walkList(v, e.Body)
+	case *Return:
+		if e.Value != nil {
+			Walk(v, e.Value)
+		}
+	case *Print:
+		if e.Expression != nil {
+			Walk(v, e.Expression)
+		}
+	case *Var:
+		if e.Initializer != nil {
+			Walk(v, e.Initializer)
+		}
+	case *While:
+		if e.Condition != nil {
+			Walk(v, e.Condition)
+		}
+		if e.Body != nil {
+			Walk(v, e.Body)
+		}
+		if e.Increment != nil {
+			Walk(v, e.Increment)
+		}
+	case *Export:
+		if e.Declaration != nil {
+			Walk(v, e.Declaration)
+		}
+	case *Throw:
+		if e.Value != nil {
+			Walk(v, e.Value)
+		}
+	case *Try:
+		walkList(v, e.Body)
+		walkList(v, e.Catch)
+		walkList(v, e.Finally)
+	case *Block:
+		walkList(v, e.Statements)
+	case *Class:
+		if e.SuperClass != nil {
+			Walk(v, e.SuperClass)
+		}
+		walkList(v, e.Methods)
+	}
+}
+
+func (e *Expression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Expression", map[string]any{
+		"expression": e.Expression,
+	}))
+}
+
+func (e *If) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "If", map[string]any{
+		"keyword":   tokenJSON(e.Keyword),
+		"condition": e.Condition,
+		"then":      e.Then,
+		"else":      e.Else,
+	}))
+}
+
+func (e *Function) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Function", map[string]any{
+		"name":   tokenJSON(e.Name),
+		"params": tokensJSON(e.Params),
+		"body":   e.Body,
+	}))
+}
+
+func (e *Return) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Return", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+		"value":   e.Value,
+	}))
+}
+
+func (e *Print) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Print", map[string]any{
+		"keyword":    tokenJSON(e.Keyword),
+		"expression": e.Expression,
+	}))
+}
+
+func (e *Var) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Var", map[string]any{
+		"name":        tokenJSON(e.Name),
+		"initializer": e.Initializer,
+	}))
+}
+
+func (e *While) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "While", map[string]any{
+		"keyword":   tokenJSON(e.Keyword),
+		"condition": e.Condition,
+		"body":      e.Body,
+		"increment": e.Increment,
+	}))
+}
+
+func (e *Break) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Break", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+	}))
+}
+
+func (e *Continue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Continue", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+	}))
+}
+
+func (e *Import) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Import", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+		"path":    tokenJSON(e.Path),
+		"alias":   tokenJSON(e.Alias),
+		"names":   tokensJSON(e.Names),
+	}))
+}
+
+func (e *Export) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Export", map[string]any{
+		"keyword":     tokenJSON(e.Keyword),
+		"declaration": e.Declaration,
+	}))
+}
+
+func (e *Throw) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Throw", map[string]any{
+		"keyword": tokenJSON(e.Keyword),
+		"value":   e.Value,
+	}))
+}
+
+func (e *Try) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Try", map[string]any{
+		"keyword":   tokenJSON(e.Keyword),
+		"body":      e.Body,
+		"catchName": tokenJSON(e.CatchName),
+		"catch":     e.Catch,
+		"finally":   e.Finally,
+	}))
+}
+
+func (e *Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Block", map[string]any{
+		"brace":      tokenJSON(e.Brace),
+		"statements": e.Statements,
+	}))
+}
+
+func (e *Class) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeJSON(e, "Class", map[string]any{
+		"name":       tokenJSON(e.Name),
+		"superClass": e.SuperClass,
+		"methods":    e.Methods,
+	}))
+}
@@ -0,0 +1,87 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// LoxString gives strings their built-in methods and indexing. Strings are
+// plain Go strings at runtime and are only wrapped while a method or index is
+// looked up. Lengths and indexes count runes, not bytes.
+type LoxString string
+
+var (
+	_ Builtin   = LoxString("")
+	_ Indexable = LoxString("")
+)
+
+// asBuiltin returns v as a Builtin if it has built-in methods.
+func asBuiltin(v any) (Builtin, bool) {
+	if s, ok := v.(string); ok {
+		return LoxString(s), true
+	}
+	b, ok := v.(Builtin)
+	return b, ok
+}
+
+// asIndexable returns v as an Indexable if it supports `v[key]`.
+func asIndexable(v any) (Indexable, bool) {
+	if s, ok := v.(string); ok {
+		return LoxString(s), true
+	}
+	i, ok := v.(Indexable)
+	return i, ok
+}
+
+func (s LoxString) Get(token *Token, index any) any {
+	runes := []rune(string(s))
+	return string(runes[s.index(token, index, len(runes))])
+}
+
+func (s LoxString) Set(token *Token, index any, value any) {
+	panic(NewRuntimeError(token, "Strings are immutable."))
+}
+
+// index converts a Lox number into a rune position in [0, limit).
+func (s LoxString) index(token *Token, index any, limit int) int {
+	f, ok := index.(float64)
+	if !ok || f != math.Trunc(f) {
+		panic(NewRuntimeError(token, "String index must be an integer."))
+	}
+	if f < 0 || f >= float64(limit) {
+		panic(NewRuntimeError(token, fmt.Sprintf("String index %v out of range.", f)))
+	}
+	return int(f)
+}
+
+// Method returns the built-in method called name bound to the string. Errors
+// raised by the method are reported at token.
+func (s LoxString) Method(name *Token) *NativeFunction {
+	switch name.lexeme {
+	case "len":
+		return NewNativeFunction(0, func(arguments []any) any {
+			return float64(len([]rune(string(s))))
+		})
+	case "upper":
+		return NewNativeFunction(0, func(arguments []any) any {
+			return strings.ToUpper(string(s))
+		})
+	case "lower":
+		return NewNativeFunction(0, func(arguments []any) any {
+			return strings.ToLower(string(s))
+		})
+	case "slice":
+		return NewNativeFunction(2, func(arguments []any) any {
+			runes := []rune(string(s))
+			start := s.index(name, arguments[0], len(runes)+1)
+			end := s.index(name, arguments[1], len(runes)+1)
+			if start > end {
+				panic(NewRuntimeError(name, "Slice start must not be after end."))
+			}
+			return string(runes[start:end])
+		})
+	}
+
+	panic(NewRuntimeError(name, "Undefined property '"+name.lexeme+"'."))
+}
@@ -0,0 +1,183 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Value is a handle to a Lox value held by the host, such as a function to
+// call back or an instance returned by a script. It stays valid for as long
+// as the host keeps it, across calls to Run.
+type Value struct {
+	lox   *Lox
+	value any
+}
+
+// Global returns the value of a global variable defined by the script.
+func (l *Lox) Global(name string) (Value, error) {
+	value, ok := l.globalValues()[name]
+	if !ok {
+		return Value{}, NewRuntimeError(nil, "Undefined variable '"+name+"'.")
+	}
+	return Value{l, value}, nil
+}
+
+// globalValues returns the global variables of the script.
+func (l *Lox) globalValues() map[string]any {
+	if l.vm != nil {
+		return l.vm.globals
+	}
+	return l.interpreter.globals.bindings()
+}
+
+// ValueOf converts a Go value to a Lox value as NewGoFunction converts
+// results, wrapping funcs as natives. A Value is returned as it is.
+func (l *Lox) ValueOf(v any) (Value, error) {
+	switch v := v.(type) {
+	case Value:
+		return v, nil
+	case *Value:
+		return *v, nil
+	}
+
+	if reflect.ValueOf(v).Kind() == reflect.Func {
+		native, err := NewGoFunction(v)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{l, native}, nil
+	}
+	return Value{l, toLox(reflect.ValueOf(v))}, nil
+}
+
+// Interface returns the underlying Lox value: nil, bool, float64, string or
+// one of the package's pointer types.
+func (v Value) Interface() any {
+	return v.value
+}
+
+func (v Value) IsNil() bool {
+	return v.value == nil
+}
+
+// String formats the value the way `print` does.
+func (v Value) String() string {
+	return stringify(v.value)
+}
+
+// Call calls the value, which must be a Lox function, class or native, with
+// arguments converted by ValueOf. Runtime errors raised by the call are
+// returned as a *RuntimeError.
+func (v Value) Call(arguments ...any) (Value, error) {
+	return v.CallContext(context.Background(), arguments...)
+}
+
+// CallContext is like Call but stops the call with a runtime error wrapping
+// ctx.Err() once ctx is done. A call made while Lox code is running, from a
+// native, runs under the context of the code that called the native.
+func (v Value) CallContext(ctx context.Context, arguments ...any) (Value, error) {
+	defer v.lox.begin(ctx)()
+
+	values := make([]any, len(arguments))
+	for i, argument := range arguments {
+		value, err := v.lox.ValueOf(argument)
+		if err != nil {
+			return Value{}, err
+		}
+		values[i] = value.value
+	}
+
+	var result any
+	var err error
+	if v.lox.vm != nil {
+		result, err = v.lox.vm.callFromHost(v.value, values)
+	} else {
+		result, err = v.lox.interpreter.call(v.value, values)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{v.lox, result}, nil
+}
+
+// Get reads a field or bound method of an instance, or an export of a
+// module.
+func (v Value) Get(name string) (Value, error) {
+	var result any
+	switch object := v.value.(type) {
+	case *LoxInstance:
+		field, err := catchRuntimeError(func() any {
+			return object.Get(&Token{t: TokenType_IDENTIFIER, lexeme: name})
+		})
+		if err != nil {
+			return Value{}, err
+		}
+		result = field
+	case *ObjInstance:
+		if field, ok := object.fields[name]; ok {
+			result = field
+		} else if method, ok := object.class.methods[name]; ok {
+			result = &ObjBoundMethod{receiver: object, method: method}
+		} else {
+			return Value{}, NewRuntimeError(nil, "Undefined property '"+name+"'.")
+		}
+	case *LoxModule:
+		export, err := catchRuntimeError(func() any {
+			return object.Get(&Token{t: TokenType_IDENTIFIER, lexeme: name})
+		})
+		if err != nil {
+			return Value{}, err
+		}
+		result = export
+	default:
+		return Value{}, NewRuntimeError(nil, "Only instances have properties.")
+	}
+	return Value{v.lox, result}, nil
+}
+
+// Set assigns a field of an instance, converting value by ValueOf.
+func (v Value) Set(name string, value any) error {
+	converted, err := v.lox.ValueOf(value)
+	if err != nil {
+		return err
+	}
+
+	switch object := v.value.(type) {
+	case *LoxInstance:
+		object.fields[name] = converted.value
+	case *ObjInstance:
+		object.fields[name] = converted.value
+	default:
+		return NewRuntimeError(nil, "Only instances have fields.")
+	}
+	return nil
+}
+
+// As converts v to the Go type T, the way arguments are converted for a
+// func registered with NewGoFunction.
+func As[T any](v Value) (T, error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+
+	converted, ok := fromLox(v.lox, v.value, t)
+	if !ok {
+		return zero, fmt.Errorf("lox: %s is not %s", v, describeType(t))
+	}
+	return converted.Interface().(T), nil
+}
+
+// catchRuntimeError runs fn, returning a runtime error it raises as an
+// error.
+func catchRuntimeError(fn func() any) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = re
+		}
+	}()
+	return fn(), nil
+}
@@ -0,0 +1,769 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+const framesMax = 1024
+
+// handler is an active try statement: where to resume and how much of the
+// frame and value stacks to keep when an error is caught.
+type handler struct {
+	frames  int
+	stack   int
+	ip      int
+	finally bool
+}
+
+type CallFrame struct {
+	closure *ObjClosure
+	ip      int
+	// index of the frame's first stack slot
+	slots int
+	// module is set on the frame running the top level of an imported
+	// module, which returns the module rather than nil.
+	module *LoxModule
+}
+
+// VM executes bytecode produced by the Compiler. Globals persist across calls
+// to interpret so that the REPL can build on previous lines.
+type VM struct {
+	lox          *Lox
+	frames       []CallFrame
+	stack        []any
+	globals      map[string]any
+	handlers     []handler
+	openUpvalues *ObjUpvalue
+	// base is the number of frames below the code being run. It is nonzero
+	// while the host calls into Lox from a native called by the VM; run
+	// returns once the frames above base have returned.
+	base int
+}
+
+func NewVM(lox *Lox) *VM {
+	vm := &VM{
+		lox:    lox,
+		frames: make([]CallFrame, 0, framesMax),
+		stack:  make([]any, 0, framesMax),
+	}
+	vm.globals = vm.newGlobals()
+	return vm
+}
+
+// newGlobals creates the globals of a module, holding the natives and the
+// globals defined by the host.
+func (vm *VM) newGlobals() map[string]any {
+	globals := map[string]any{}
+
+	for name, value := range vm.lox.globals {
+		globals[name] = value
+	}
+
+	return globals
+}
+
+// interpret runs function as a script and returns the value it returns.
+func (vm *VM) interpret(function *ObjFunction) (any, error) {
+	closure := NewObjClosure(function, vm.globals)
+	vm.push(closure)
+	if err := vm.call(closure, 0); err != nil {
+		vm.resetStack()
+		return nil, err
+	}
+	if err := vm.run(); err != nil {
+		vm.resetStack()
+		return nil, err
+	}
+	return vm.pop(), nil
+}
+
+// callFromHost calls callee on behalf of the host and runs it to completion.
+// The host may itself have been called from Lox through a native, in which
+// case the VM is left as it was found.
+func (vm *VM) callFromHost(callee any, arguments []any) (result any, err error) {
+	base, stack, handlers := vm.base, len(vm.stack), len(vm.handlers)
+	vm.base = len(vm.frames)
+	defer func() {
+		// A native called directly raises its errors as panics, as it does
+		// on the interpreter.
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				err = vm.lox.internalError(r)
+			} else {
+				if re.trace == nil {
+					re.trace = vm.traceback()
+				}
+				err = re
+			}
+		}
+		if err != nil {
+			vm.popFrames(vm.base)
+			vm.closeUpvalues(stack)
+			vm.stack = vm.stack[:stack]
+			vm.handlers = vm.handlers[:handlers]
+			result = nil
+		}
+		vm.base = base
+	}()
+
+	vm.push(callee)
+	for _, argument := range arguments {
+		vm.push(argument)
+	}
+
+	err = vm.callValue(callee, len(arguments))
+	if err == nil && len(vm.frames) > vm.base {
+		err = vm.run()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vm.pop(), nil
+}
+
+func (vm *VM) resetStack() {
+	vm.stack = vm.stack[:0]
+	vm.popFrames(0)
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+}
+
+// runtimeError creates an error raised at the current instruction. It is
+// reported by run if no handler catches it.
+func (vm *VM) runtimeError(format string, args ...any) error {
+	err := NewRuntimeError(nil, fmt.Sprintf(format, args...))
+	err.trace = vm.traceback()
+	return err
+}
+
+func (vm *VM) traceback() []StackFrame {
+	trace := []StackFrame{}
+	for i := len(vm.frames) - 1; i >= 0; i-- {
+		frame := &vm.frames[i]
+		function := frame.closure.function
+		trace = append(trace, StackFrame{
+			function: function.name,
+			file:     function.file,
+			line:     function.chunk.lines[frame.ip-1],
+		})
+	}
+	return trace
+}
+
+// catch unwinds to the innermost handler and resumes execution there. It
+// reports false if there is no handler left.
+func (vm *VM) catch(err *RuntimeError) bool {
+	if err.fatal {
+		return false
+	}
+	// Handlers below base belong to Lox code that called out to the host;
+	// the error is returned to the host first.
+	if len(vm.handlers) == 0 || vm.handlers[len(vm.handlers)-1].frames <= vm.base {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.popFrames(h.frames)
+	vm.closeUpvalues(h.stack)
+	vm.stack = vm.stack[:h.stack]
+	if h.finally {
+		// Rethrown unchanged once the finally block has run.
+		vm.push(err)
+	} else {
+		vm.push(vm.errorValue(err))
+	}
+	vm.frames[h.frames-1].ip = h.ip
+	return true
+}
+
+// popFrames discards the frames above n, abandoning the imports of any
+// modules they were running.
+func (vm *VM) popFrames(n int) {
+	for i := len(vm.frames) - 1; i >= n; i-- {
+		if vm.frames[i].module != nil {
+			vm.lox.endModule(nil)
+		}
+	}
+	vm.frames = vm.frames[:n]
+}
+
+// errorValue is the Lox value bound by `catch` for err.
+func (vm *VM) errorValue(err *RuntimeError) any {
+	if err.thrown {
+		return err.value
+	}
+
+	instance := NewObjInstance(objErrorClass)
+	instance.fields["message"] = err.message
+	instance.fields["line"] = float64(err.line())
+	return instance
+}
+
+func (vm *VM) push(v any) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() any {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+func (vm *VM) call(closure *ObjClosure, argCount int) error {
+	if argCount != closure.function.arity {
+		return vm.runtimeError("Expected %d arguments but got %d.", closure.function.arity, argCount)
+	}
+
+	if len(vm.frames) >= vm.lox.maxCallDepth {
+		err := limitError(nil, ErrStackOverflow)
+		err.trace = vm.traceback()
+		return err
+	}
+
+	vm.frames = append(vm.frames, CallFrame{
+		closure: closure,
+		ip:      0,
+		slots:   len(vm.stack) - argCount - 1,
+	})
+	return nil
+}
+
+func (vm *VM) callValue(callee any, argCount int) error {
+	switch callee := callee.(type) {
+	case *ObjBoundMethod:
+		vm.stack[len(vm.stack)-argCount-1] = callee.receiver
+		return vm.call(callee.method, argCount)
+	case *ObjClass:
+		vm.stack[len(vm.stack)-argCount-1] = NewObjInstance(callee)
+		if initializer, ok := callee.methods["init"]; ok {
+			return vm.call(initializer, argCount)
+		} else if argCount != 0 {
+			return vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+		}
+		return nil
+	case *ObjClosure:
+		return vm.call(callee, argCount)
+	case *NativeFunction:
+		if message := arityMessage(callee.arity, callee.variadic, argCount); message != "" {
+			return vm.runtimeError("%s", message)
+		}
+		result := callee.fn(vm.lox, vm.stack[len(vm.stack)-argCount:])
+		vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+		vm.push(result)
+		return nil
+	}
+	return vm.runtimeError("Can only call functions and classes.")
+}
+
+func (vm *VM) invokeFromClass(class *ObjClass, name string, argCount int) error {
+	method, ok := class.methods[name]
+	if !ok {
+		return vm.runtimeError("Undefined property '%s'.", name)
+	}
+	return vm.call(method, argCount)
+}
+
+func (vm *VM) invoke(name string, argCount int) error {
+	receiver := vm.peek(argCount)
+
+	if builtin, ok := asBuiltin(receiver); ok {
+		method := builtin.Method(&Token{t: TokenType_IDENTIFIER, lexeme: name})
+		vm.stack[len(vm.stack)-argCount-1] = method
+		return vm.callValue(method, argCount)
+	}
+
+	if module, ok := receiver.(*LoxModule); ok {
+		value := module.Get(&Token{t: TokenType_IDENTIFIER, lexeme: name})
+		vm.stack[len(vm.stack)-argCount-1] = value
+		return vm.callValue(value, argCount)
+	}
+
+	instance, ok := receiver.(*ObjInstance)
+	if !ok {
+		return vm.runtimeError("Only instances have properties.")
+	}
+
+	if value, ok := instance.fields[name]; ok {
+		vm.stack[len(vm.stack)-argCount-1] = value
+		return vm.callValue(value, argCount)
+	}
+
+	return vm.invokeFromClass(instance.class, name, argCount)
+}
+
+// importModule calls the top level of the module at path. Its frame returns
+// the module once it has run.
+func (vm *VM) importModule(path string) error {
+	statements := vm.lox.parseModule(nil, path)
+	compiler := NewCompiler(vm.lox)
+	compiler.function.file = path
+	function := compiler.compile(statements)
+	if vm.lox.hadError {
+		return vm.runtimeError("Could not compile module '%s'.", displayPath(path))
+	}
+
+	closure := NewObjClosure(function, vm.newGlobals())
+	vm.push(closure)
+	if err := vm.call(closure, 0); err != nil {
+		return err
+	}
+
+	vm.frames[len(vm.frames)-1].module = &LoxModule{
+		path:    path,
+		exports: exportedNames(statements),
+		globals: closure.globals,
+	}
+	vm.lox.beginModule(path)
+	return nil
+}
+
+func (vm *VM) bindMethod(class *ObjClass, name string) error {
+	method, ok := class.methods[name]
+	if !ok {
+		return vm.runtimeError("Undefined property '%s'.", name)
+	}
+
+	bound := &ObjBoundMethod{receiver: vm.peek(0), method: method}
+	vm.pop()
+	vm.push(bound)
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *ObjUpvalue {
+	var prev *ObjUpvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		prev = upvalue
+		upvalue = upvalue.next
+	}
+
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &ObjUpvalue{slot: slot, open: true, next: upvalue}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) getUpvalue(upvalue *ObjUpvalue) any {
+	if upvalue.open {
+		return vm.stack[upvalue.slot]
+	}
+	return upvalue.closed
+}
+
+func (vm *VM) setUpvalue(upvalue *ObjUpvalue, v any) {
+	if upvalue.open {
+		vm.stack[upvalue.slot] = v
+		return
+	}
+	upvalue.closed = v
+}
+
+func (vm *VM) numberOperands() (float64, float64, error) {
+	b, bok := vm.peek(0).(float64)
+	a, aok := vm.peek(1).(float64)
+	if !aok || !bok {
+		return 0, 0, vm.runtimeError("Operands must be numbers.")
+	}
+	vm.stack = vm.stack[:len(vm.stack)-2]
+	return a, b, nil
+}
+
+func (vm *VM) run() error {
+	for {
+		err := vm.execute()
+		re, ok := err.(*RuntimeError)
+		if !ok || !vm.catch(re) {
+			return err
+		}
+	}
+}
+
+// execute runs bytecode until the frames above base return or an error is
+// raised.
+func (vm *VM) execute() (err error) {
+	// Runtime errors raised by values shared with the interpreter, such as
+	// lists, arrive as panics.
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				err = vm.lox.internalError(r)
+				return
+			}
+			if re.trace == nil {
+				re.trace = vm.traceback()
+			}
+			err = re
+		}
+	}()
+
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := frame.closure.function.chunk
+
+	readByte := func() byte {
+		b := chunk.code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		v := chunk.readShort(frame.ip)
+		frame.ip += 2
+		return v
+	}
+	readString := func() string {
+		return chunk.constants[readShort()].(string)
+	}
+	// Calls and returns change the active frame.
+	loadFrame := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		chunk = frame.closure.function.chunk
+	}
+
+	for {
+		op := OpCode(readByte())
+		if err := vm.lox.step(); err != nil {
+			err.trace = vm.traceback()
+			return err
+		}
+		switch op {
+		case OpCode_CONSTANT:
+			vm.push(chunk.constants[readShort()])
+		case OpCode_NIL:
+			vm.push(nil)
+		case OpCode_TRUE:
+			vm.push(true)
+		case OpCode_FALSE:
+			vm.push(false)
+		case OpCode_POP:
+			vm.pop()
+		case OpCode_GET_LOCAL:
+			slot := readByte()
+			vm.push(vm.stack[frame.slots+int(slot)])
+		case OpCode_SET_LOCAL:
+			slot := readByte()
+			vm.stack[frame.slots+int(slot)] = vm.peek(0)
+		case OpCode_GET_GLOBAL:
+			name := readString()
+			value, ok := frame.closure.globals[name]
+			if !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.push(value)
+		case OpCode_DEFINE_GLOBAL:
+			name := readString()
+			frame.closure.globals[name] = vm.peek(0)
+			vm.pop()
+		case OpCode_SET_GLOBAL:
+			name := readString()
+			if _, ok := frame.closure.globals[name]; !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			frame.closure.globals[name] = vm.peek(0)
+		case OpCode_GET_UPVALUE:
+			slot := readByte()
+			vm.push(vm.getUpvalue(frame.closure.upvalues[slot]))
+		case OpCode_SET_UPVALUE:
+			slot := readByte()
+			vm.setUpvalue(frame.closure.upvalues[slot], vm.peek(0))
+		case OpCode_GET_PROPERTY:
+			if builtin, ok := asBuiltin(vm.peek(0)); ok {
+				name := readString()
+				vm.pop() // Receiver.
+				vm.push(builtin.Method(&Token{t: TokenType_IDENTIFIER, lexeme: name}))
+				break
+			}
+
+			if module, ok := vm.peek(0).(*LoxModule); ok {
+				name := readString()
+				vm.pop() // Module.
+				vm.push(module.Get(&Token{t: TokenType_IDENTIFIER, lexeme: name}))
+				break
+			}
+
+			instance, ok := vm.peek(0).(*ObjInstance)
+			if !ok {
+				return vm.runtimeError("Only instances have properties.")
+			}
+
+			name := readString()
+			if value, ok := instance.fields[name]; ok {
+				vm.pop() // Instance.
+				vm.push(value)
+				break
+			}
+
+			if err := vm.bindMethod(instance.class, name); err != nil {
+				return err
+			}
+		case OpCode_SET_PROPERTY:
+			instance, ok := vm.peek(1).(*ObjInstance)
+			if !ok {
+				return vm.runtimeError("Only instances have fields.")
+			}
+
+			instance.fields[readString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop() // Instance.
+			vm.push(value)
+		case OpCode_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().(*ObjClass)
+
+			if err := vm.bindMethod(superclass, name); err != nil {
+				return err
+			}
+		case OpCode_EQUAL:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(isEqual(a, b))
+		case OpCode_GREATER:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a > b)
+		case OpCode_GREATER_EQUAL:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a >= b)
+		case OpCode_LESS:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a < b)
+		case OpCode_LESS_EQUAL:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a <= b)
+		case OpCode_ADD:
+			switch b := vm.peek(0).(type) {
+			case float64:
+				if a, ok := vm.peek(1).(float64); ok {
+					vm.stack = vm.stack[:len(vm.stack)-2]
+					vm.push(a + b)
+					continue
+				}
+			case string:
+				if a, ok := vm.peek(1).(string); ok {
+					vm.stack = vm.stack[:len(vm.stack)-2]
+					vm.push(a + b)
+					continue
+				}
+			}
+			return vm.runtimeError("Operands must be two numbers or two strings.")
+		case OpCode_SUBTRACT:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a - b)
+		case OpCode_MULTIPLY:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a * b)
+		case OpCode_DIVIDE:
+			a, b, err := vm.numberOperands()
+			if err != nil {
+				return err
+			}
+			vm.push(a / b)
+		case OpCode_NOT:
+			vm.push(!isTruthy(vm.pop()))
+		case OpCode_NEGATE:
+			f, ok := vm.peek(0).(float64)
+			if !ok {
+				return vm.runtimeError("Operand must be a number.")
+			}
+			vm.pop()
+			vm.push(-f)
+		case OpCode_PRINT:
+			fmt.Fprintln(vm.lox.stdout, stringify(vm.pop()))
+		case OpCode_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case OpCode_JUMP_IF_FALSE:
+			offset := readShort()
+			if !isTruthy(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OpCode_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+		case OpCode_CALL:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OpCode_INVOKE:
+			method := readString()
+			argCount := int(readByte())
+			if err := vm.invoke(method, argCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OpCode_SUPER_INVOKE:
+			method := readString()
+			argCount := int(readByte())
+			superclass := vm.pop().(*ObjClass)
+			if err := vm.invokeFromClass(superclass, method, argCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OpCode_CLOSURE:
+			function := chunk.constants[readShort()].(*ObjFunction)
+			closure := NewObjClosure(function, frame.closure.globals)
+			vm.push(closure)
+			for i := range closure.upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+		case OpCode_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OpCode_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.slots)
+			if frame.module != nil {
+				result = frame.module
+				vm.lox.endModule(frame.module)
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:frame.slots]
+			vm.push(result)
+			if len(vm.frames) == vm.base {
+				return nil
+			}
+			loadFrame()
+		case OpCode_CLASS:
+			vm.push(NewObjClass(readString()))
+		case OpCode_INHERIT:
+			superclass, ok := vm.peek(1).(*ObjClass)
+			if !ok {
+				return vm.runtimeError("Superclass must be a class.")
+			}
+
+			subclass := vm.peek(0).(*ObjClass)
+			for name, method := range superclass.methods {
+				subclass.methods[name] = method
+			}
+			vm.pop() // Subclass.
+		case OpCode_METHOD:
+			method := vm.peek(0).(*ObjClosure)
+			class := vm.peek(1).(*ObjClass)
+			class.methods[readString()] = method
+			vm.pop()
+		case OpCode_BUILD_LIST:
+			count := readShort()
+			elements := make([]any, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(NewLoxList(elements))
+		case OpCode_BUILD_MAP:
+			count := readShort()
+			m := NewLoxMap()
+			entries := vm.stack[len(vm.stack)-2*count:]
+			for i := 0; i < len(entries); i += 2 {
+				m.Set(nil, entries[i], entries[i+1])
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(m)
+		case OpCode_INTERPOLATE:
+			count := readShort()
+			var sb strings.Builder
+			for _, part := range vm.stack[len(vm.stack)-count:] {
+				sb.WriteString(stringify(part))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(sb.String())
+		case OpCode_GET_INDEX:
+			indexable, ok := asIndexable(vm.peek(1))
+			if !ok {
+				return vm.runtimeError("Only lists, maps and strings can be indexed.")
+			}
+			value := indexable.Get(nil, vm.peek(0))
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(value)
+		case OpCode_SET_INDEX:
+			indexable, ok := asIndexable(vm.peek(2))
+			if !ok {
+				return vm.runtimeError("Only lists, maps and strings can be indexed.")
+			}
+			value := vm.peek(0)
+			indexable.Set(nil, vm.peek(1), value)
+			vm.stack = vm.stack[:len(vm.stack)-3]
+			vm.push(value)
+		case OpCode_THROW:
+			value := vm.pop()
+			if err, ok := value.(*RuntimeError); ok {
+				return err
+			}
+			err := NewThrow(nil, value)
+			err.trace = vm.traceback()
+			return err
+		case OpCode_PUSH_CATCH, OpCode_PUSH_FINALLY:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frames:  len(vm.frames),
+				stack:   len(vm.stack),
+				ip:      frame.ip + offset,
+				finally: op == OpCode_PUSH_FINALLY,
+			})
+		case OpCode_POP_HANDLER:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OpCode_IMPORT:
+			path, module := vm.lox.findModule(nil, readString())
+			if module != nil {
+				vm.push(module)
+				break
+			}
+
+			if err := vm.importModule(path); err != nil {
+				return err
+			}
+			loadFrame()
+		case OpCode_IMPORT_NAME:
+			name := readString()
+			module := vm.peek(0).(*LoxModule)
+			vm.push(module.Get(&Token{t: TokenType_IDENTIFIER, lexeme: name}))
+		default:
+			return vm.runtimeError("Unknown opcode.")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package loxtest runs Lox programs against expectations written in their
+// comments, in the format of the Crafting Interpreters test suite:
+//
+//	print 1 + 2; // expect: 3
+//	print nil.x; // expect runtime error: Only instances have properties.
+//	var = 1;     // Error at '=': Expect variable name.
+//	// [line 7] Error at end: Expect '}' after block.
+//
+// Each `expect:` comment is a line the program must print, in order. A
+// compile error is expected at the line of its comment unless the comment
+// gives the line; a runtime error must be raised at the line of its comment.
+package loxtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectError        = regexp.MustCompile(`// (Error.*)`)
+	expectErrorLine    = regexp.MustCompile(`// \[line (\d+)\] (Error.*)`)
+)
+
+// Result is the outcome of running one file.
+type Result struct {
+	Path string
+	// Skipped is set for files with no expectations, such as benchmarks
+	// whose output changes from run to run.
+	Skipped bool
+	// Diff is a unified diff from the expected results to the actual ones,
+	// or empty if they match.
+	Diff string
+}
+
+// Passed reports whether the file ran and matched its expectations.
+func (r *Result) Passed() bool {
+	return !r.Skipped && r.Diff == ""
+}
+
+// RunFile runs the Lox file at path on a Lox configured by opts and
+// compares what it prints and the errors it raises with the expectations in
+// its comments. Output and errors are captured, overriding WithStdout and
+// WithStderr.
+func RunFile(path string, opts ...lox.Option) (*Result, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := expectations(string(b))
+	if expected == nil {
+		return &Result{Path: path, Skipped: true}, nil
+	}
+
+	var stdout strings.Builder
+	opts = append(opts, lox.WithStdout(&stdout), lox.WithStderr(io.Discard))
+	err = lox.New(opts...).RunFile(path)
+	actual := strings.SplitAfter(stdout.String(), "\n")
+	if actual[len(actual)-1] == "" {
+		actual = actual[:len(actual)-1]
+	}
+	actual = append(actual, errorLines(err)...)
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        expected,
+		B:        actual,
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Path: path, Diff: diff}, nil
+}
+
+// RunDir runs every .lox file in the tree rooted at dir, in lexical order.
+func RunDir(dir string, opts ...lox.Option) ([]*Result, error) {
+	var results []*Result
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+		result, err := RunFile(path, opts...)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// expectations returns the lines a program is expected to print followed by
+// the errors it is expected to raise, each ending in a newline, or nil if the
+// program has no expectations.
+func expectations(source string) []string {
+	var output, errs []string
+	for i, line := range strings.Split(source, "\n") {
+		n := i + 1
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			output = append(output, m[1]+"\n")
+		} else if m := expectRuntimeError.FindStringSubmatch(line); m != nil {
+			errs = append(errs, runtimeErrorLine(n, m[1]))
+		} else if m := expectErrorLine.FindStringSubmatch(line); m != nil {
+			n, _ = strconv.Atoi(m[1])
+			errs = append(errs, fmt.Sprintf("[line %d] %s\n", n, m[2]))
+		} else if m := expectError.FindStringSubmatch(line); m != nil {
+			errs = append(errs, fmt.Sprintf("[line %d] %s\n", n, m[1]))
+		}
+	}
+	if output == nil && errs == nil {
+		return nil
+	}
+	return append(output, errs...)
+}
+
+// errorLines formats the error returned by a run like its expectations.
+func errorLines(err error) []string {
+	var compileErr *lox.CompileError
+	var runtimeErr *lox.RuntimeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &compileErr):
+		lines := make([]string, len(compileErr.Errors))
+		for i, e := range compileErr.Errors {
+			lines[i] = e.Error() + "\n"
+		}
+		return lines
+	case errors.As(err, &runtimeErr):
+		return []string{runtimeErrorLine(runtimeErr.Line(), runtimeErr.Message())}
+	default:
+		return []string{err.Error() + "\n"}
+	}
+}
+
+func runtimeErrorLine(line int, message string) string {
+	return fmt.Sprintf("[line %d] Runtime error: %s\n", line, message)
+}
@@ -0,0 +1,65 @@
+package loxtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorpus(t *testing.T) {
+	for _, opts := range [][]lox.Option{nil, {lox.WithVM()}} {
+		results, err := RunDir("../lox", opts...)
+		require.Nil(t, err)
+		require.NotEmpty(t, results)
+
+		for _, result := range results {
+			require.Empty(t, result.Diff, result.Path)
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pass.lox": `print 1; // expect: 1
+print "a" + "b"; // expect: ab
+print nil.x; // expect runtime error: Only instances have properties.`,
+		"fail.lox": `print 1; // expect: 1
+print 2; // expect: 3
+var = 1; // Error at '=': Expect variable name.`,
+		"syntax.lox": `print 1; // expect: 1
+{
+// [line 4] Error at end: Expect '}' after block.
+`,
+		"none.lox": `print clock();`,
+	}
+	for name, source := range files {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644))
+	}
+
+	results, err := RunDir(dir)
+	require.Nil(t, err)
+	require.Len(t, results, 4)
+
+	fail, none, pass, syntax := results[0], results[1], results[2], results[3]
+	require.True(t, pass.Passed())
+	require.True(t, none.Skipped)
+	require.False(t, none.Passed())
+	require.False(t, syntax.Passed())
+	require.Equal(t, `--- expected
++++ actual
+@@ -1,2 +1 @@
+-1
+ [line 4] Error at end: Expect '}' after block.
+`, syntax.Diff)
+	require.Equal(t, `--- expected
++++ actual
+@@ -1,3 +1 @@
+-1
+-3
+ [line 3] Error at '=': Expect variable name.
+`, fail.Diff)
+}
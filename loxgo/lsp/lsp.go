@@ -0,0 +1,402 @@
+// Package lsp is a Language Server Protocol server for Lox. It publishes
+// the errors in open documents and answers requests for definitions,
+// references, hovers, document symbols and completions, using lox.Analyze.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+)
+
+// Server serves one client over a pair of streams, such as stdin and stdout.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+	// docs are the open documents by URI.
+	docs     map[string]*document
+	shutdown bool
+}
+
+type document struct {
+	text     string
+	analysis *lox.Analysis
+	// lines are the offsets at which the lines of text start.
+	lines []int
+}
+
+func newDocument(text string) *document {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &document{text: text, analysis: lox.Analyze(text), lines: lines}
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Run serves requests until the client sends `exit` or closes the input.
+func (s *Server) Run() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			// Unparsable; the error has been sent.
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("lsp: exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads the next message. It returns a nil message if the body isn't
+// valid JSON, after responding with an error.
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: bad Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		null := json.RawMessage("null")
+		return nil, s.write(&message{ID: &null, Error: &responseError{Code: codeParseError, Message: err.Error()}})
+	}
+	return msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) notify(method string, params any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{Method: method, Params: b})
+}
+
+// handle dispatches a request or notification. Requests, which have an ID,
+// are always answered.
+func (s *Server) handle(msg *message) error {
+	result, rerr := s.dispatch(msg)
+	if msg.ID == nil {
+		return nil
+	}
+
+	if rerr != nil {
+		return s.write(&message{ID: msg.ID, Error: rerr})
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(b)
+	return s.write(&message{ID: msg.ID, Result: &raw})
+}
+
+func (s *Server) dispatch(msg *message) (any, *responseError) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		result := InitializeResult{Capabilities: ServerCapabilities{
+			TextDocumentSync:       textDocumentSyncFull,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			HoverProvider:          true,
+			DocumentSymbolProvider: true,
+			CompletionProvider:     &struct{}{},
+		}}
+		result.ServerInfo.Name = "loxgo"
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publishDiagnostics(params.TextDocument.URI, nil)
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.definition(params), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.references(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.hover(params), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.documentSymbols(params), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.completion(params), nil
+	}
+
+	if msg.ID == nil {
+		// Notifications the server doesn't handle, such as `initialized`,
+		// are ignored.
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// update analyzes the new text of a document and publishes its errors.
+func (s *Server) update(uri, text string) *responseError {
+	doc := newDocument(text)
+	s.docs[uri] = doc
+
+	diagnostics := []Diagnostic{}
+	for _, d := range doc.analysis.Diagnostics {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.rangeOf(d.Span),
+			Severity: severityError,
+			Source:   "loxgo",
+			Message:  d.Message,
+		})
+	}
+	return s.publishDiagnostics(uri, diagnostics)
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) *responseError {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return &responseError{Code: codeInvalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+// symbolAt returns the document and the symbol at a position in it.
+func (s *Server) symbolAt(params TextDocumentPositionParams) (*document, *lox.Symbol) {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	return doc, doc.analysis.SymbolAt(doc.offset(params.Position))
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	doc, symbol := s.symbolAt(params)
+	if symbol == nil || symbol.Span == (lox.Span{}) {
+		return nil
+	}
+	return &Location{URI: params.TextDocument.URI, Range: doc.rangeOf(symbol.Span)}
+}
+
+func (s *Server) references(params ReferenceParams) []Location {
+	locations := []Location{}
+	doc, symbol := s.symbolAt(params.TextDocumentPositionParams)
+	if symbol == nil {
+		return locations
+	}
+
+	if params.Context.IncludeDeclaration {
+		locations = append(locations, Location{URI: params.TextDocument.URI, Range: doc.rangeOf(symbol.Span)})
+	}
+	for _, span := range doc.analysis.ReferencesTo(symbol) {
+		locations = append(locations, Location{URI: params.TextDocument.URI, Range: doc.rangeOf(span)})
+	}
+	return locations
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	doc, symbol := s.symbolAt(params)
+	if symbol == nil {
+		return nil
+	}
+
+	offset := doc.offset(params.Position)
+	span := symbol.Span
+	for _, reference := range doc.analysis.References {
+		if reference.Symbol == symbol && reference.Span.Contains(offset) {
+			span = reference.Span
+		}
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```lox\n" + symbol.Detail + "\n```"},
+		Range:    doc.rangeOf(span),
+	}
+}
+
+func (s *Server) documentSymbols(params DocumentSymbolParams) []DocumentSymbol {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return []DocumentSymbol{}
+	}
+	return doc.documentSymbols(doc.analysis.Symbols)
+}
+
+func (doc *document) documentSymbols(symbols []*lox.Symbol) []DocumentSymbol {
+	result := []DocumentSymbol{}
+	for _, symbol := range symbols {
+		kind := symbolKindVariable
+		switch symbol.Kind {
+		case lox.SymbolKind_CLASS:
+			kind = symbolKindClass
+		case lox.SymbolKind_METHOD:
+			kind = symbolKindMethod
+		case lox.SymbolKind_FUNCTION:
+			kind = symbolKindFunction
+		}
+		// The AST doesn't record where declarations end, so a symbol's
+		// range is its name.
+		r := doc.rangeOf(symbol.Span)
+		result = append(result, DocumentSymbol{
+			Name:           symbol.Name,
+			Detail:         symbol.Detail,
+			Kind:           kind,
+			Range:          r,
+			SelectionRange: r,
+			Children:       doc.documentSymbols(symbol.Children),
+		})
+	}
+	return result
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+
+	for _, symbol := range doc.analysis.Completions(doc.offset(params.Position)) {
+		kind := completionKindVariable
+		switch symbol.Kind {
+		case lox.SymbolKind_CLASS:
+			kind = completionKindClass
+		case lox.SymbolKind_FUNCTION:
+			kind = completionKindFunction
+		case lox.SymbolKind_KEYWORD:
+			kind = completionKindKeyword
+		}
+		items = append(items, CompletionItem{Label: symbol.Name, Kind: kind, Detail: symbol.Detail})
+	}
+	return items
+}
+
+// offset converts a position in the document to a byte offset.
+func (doc *document) offset(pos Position) int {
+	if pos.Line >= len(doc.lines) {
+		return len(doc.text)
+	}
+	i := doc.lines[pos.Line]
+
+	units := 0
+	for j, r := range doc.text[i:] {
+		if units >= pos.Character || r == '\n' {
+			return i + j
+		}
+		units += utf16Len(r)
+	}
+	return len(doc.text)
+}
+
+// position converts a byte offset in the document to a position.
+func (doc *document) position(offset int) Position {
+	line := sort.SearchInts(doc.lines, offset+1) - 1
+	units := 0
+	for _, r := range doc.text[doc.lines[line]:offset] {
+		units += utf16Len(r)
+	}
+	return Position{Line: line, Character: units}
+}
+
+func (doc *document) rangeOf(span lox.Span) Range {
+	return Range{Start: doc.position(span.Offset), End: doc.position(span.Offset + span.Length)}
+}
+
+// utf16Len is the number of UTF-16 code units that encode r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
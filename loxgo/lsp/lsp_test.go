@@ -0,0 +1,264 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// client talks to a Server over pipes, as an editor would over stdio.
+type client struct {
+	t    *testing.T
+	in   io.WriteCloser
+	out  *bufio.Reader
+	id   int
+	done chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, in: clientOut, out: bufio.NewReader(clientIn), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+	return c
+}
+
+func (c *client) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	require.Nil(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.Nil(c.t, err)
+}
+
+func (c *client) receive() *message {
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	require.Nil(c.t, err)
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	require.Nil(c.t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.out, body)
+	require.Nil(c.t, err)
+
+	msg := &message{}
+	require.Nil(c.t, json.Unmarshal(body, msg))
+	return msg
+}
+
+// request sends a request and decodes the result of its response into
+// result.
+func (c *client) request(method string, params any, result any) {
+	c.id++
+	c.send(map[string]any{"id": c.id, "method": method, "params": params})
+
+	msg := c.receive()
+	require.Nil(c.t, msg.Error, method)
+	require.Equal(c.t, strconv.Itoa(c.id), string(*msg.ID))
+	if result != nil {
+		require.Nil(c.t, json.Unmarshal(*msg.Result, result))
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.send(map[string]any{"method": method, "params": params})
+}
+
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	msg := c.receive()
+	require.Equal(c.t, "textDocument/publishDiagnostics", msg.Method)
+	var params PublishDiagnosticsParams
+	require.Nil(c.t, json.Unmarshal(msg.Params, &params))
+	return params
+}
+
+func at(uri string, line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func span(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestServer(t *testing.T) {
+	const uri = "file:///shapes.lox"
+	source := `class Shape {
+  area() { return 0; }
+}
+class Square < Shape {
+  init(side) { this.side = side; }
+  area() { return this.side * this.side; }
+}
+fun describe(shape) {
+  var name = "🟥";
+  print name + " " + shape.area();
+}
+describe(Square(2));
+`
+
+	c := newClient(t)
+
+	var initialized InitializeResult
+	c.request("initialize", map[string]any{"capabilities": map[string]any{}}, &initialized)
+	require.True(t, initialized.Capabilities.DefinitionProvider)
+	require.Equal(t, textDocumentSyncFull, initialized.Capabilities.TextDocumentSync)
+	c.notify("initialized", map[string]any{})
+
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": source},
+	})
+	require.Equal(t, PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}}, c.diagnostics())
+
+	// describe( on the last line goes to the function declaration.
+	var definition Location
+	c.request("textDocument/definition", at(uri, 11, 3), &definition)
+	require.Equal(t, Location{URI: uri, Range: span(7, 4, 12)}, definition)
+
+	var references []Location
+	c.request("textDocument/references", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 7, "character": 15},
+		"context":      map[string]any{"includeDeclaration": true},
+	}, &references)
+	require.Equal(t, []Location{
+		{URI: uri, Range: span(7, 13, 18)},
+		{URI: uri, Range: span(9, 21, 26)},
+	}, references)
+
+	// Columns after the emoji count UTF-16 code units.
+	var hover Hover
+	c.request("textDocument/hover", at(uri, 9, 9), &hover)
+	require.Equal(t, "```lox\nvar name\n```", hover.Contents.Value)
+	require.Equal(t, span(9, 8, 12), hover.Range)
+	c.request("textDocument/hover", at(uri, 3, 16), &hover)
+	require.Equal(t, "```lox\nclass Shape\n```", hover.Contents.Value)
+
+	var symbols []DocumentSymbol
+	c.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}}, &symbols)
+	require.Len(t, symbols, 3)
+	require.Equal(t, "class Square < Shape", symbols[1].Detail)
+	require.Equal(t, symbolKindClass, symbols[1].Kind)
+	require.Len(t, symbols[1].Children, 2)
+	require.Equal(t, "Square.init(side)", symbols[1].Children[0].Detail)
+	require.Equal(t, symbolKindFunction, symbols[2].Kind)
+
+	var completions []CompletionItem
+	c.request("textDocument/completion", at(uri, 12, 0), &completions)
+	labels := map[string]int{}
+	for _, item := range completions {
+		labels[item.Label] = item.Kind
+	}
+	require.Equal(t, completionKindClass, labels["Square"])
+	require.Equal(t, completionKindFunction, labels["describe"])
+	require.Equal(t, completionKindFunction, labels["clock"])
+	require.Equal(t, completionKindKeyword, labels["while"])
+	require.NotContains(t, labels, "area")
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": "var a = 1;\nprint a +;\nreturn 1;\n"}},
+	})
+	require.Equal(t, []Diagnostic{
+		{Range: span(1, 9, 10), Severity: severityError, Source: "loxgo", Message: "Expect expression."},
+		{Range: span(2, 0, 6), Severity: severityError, Source: "loxgo", Message: "Can't return from top-level code."},
+	}, c.diagnostics().Diagnostics)
+
+	c.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	require.Empty(t, c.diagnostics().Diagnostics)
+
+	c.id++
+	c.send(map[string]any{"id": c.id, "method": "workspace/symbol", "params": map[string]any{}})
+	require.Equal(t, codeMethodNotFound, c.receive().Error.Code)
+
+	c.request("shutdown", nil, nil)
+	c.notify("exit", nil)
+	require.Nil(t, <-c.done)
+}
+
+func TestCompletionScope(t *testing.T) {
+	uri := "file:///scope.lox"
+	source := `fun f(shape) {
+  var area = 1;
+  print area;
+}
+fun g(side) {
+  print side;
+}
+`
+
+	c := newClient(t)
+	c.request("initialize", map[string]any{"capabilities": map[string]any{}}, nil)
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": source},
+	})
+	c.diagnostics()
+
+	labels := func(line, character int) map[string]bool {
+		var completions []CompletionItem
+		c.request("textDocument/completion", at(uri, line, character), &completions)
+		labels := map[string]bool{}
+		for _, item := range completions {
+			labels[item.Label] = true
+		}
+		return labels
+	}
+
+	// Inside f, its parameter and locals are offered, but those declared
+	// later aren't.
+	inF := labels(1, 2)
+	require.True(t, inF["shape"])
+	require.False(t, inF["area"])
+	require.True(t, labels(2, 8)["area"])
+
+	// Inside g, the parameter of f isn't.
+	inG := labels(5, 8)
+	require.True(t, inG["side"])
+	require.True(t, inG["f"])
+	require.False(t, inG["shape"])
+	require.False(t, inG["area"])
+
+	// At the top level, neither's are.
+	top := labels(7, 0)
+	require.True(t, top["g"])
+	require.False(t, top["shape"])
+	require.False(t, top["side"])
+
+	c.request("shutdown", nil, nil)
+	c.notify("exit", nil)
+	require.Nil(t, <-c.done)
+}
+
+func TestServerExitBeforeShutdown(t *testing.T) {
+	c := newClient(t)
+	c.notify("exit", nil)
+	require.Error(t, <-c.done)
+}
+
+func TestDocumentPositions(t *testing.T) {
+	doc := newDocument("a\n🟥 b\n\nc")
+	for offset, pos := range map[int]Position{
+		0:  {Line: 0, Character: 0},
+		2:  {Line: 1, Character: 0},
+		7:  {Line: 1, Character: 3},
+		9:  {Line: 2, Character: 0},
+		10: {Line: 3, Character: 0},
+		11: {Line: 3, Character: 1},
+	} {
+		require.Equal(t, pos, doc.position(offset), offset)
+		require.Equal(t, offset, doc.offset(pos), offset)
+	}
+	require.Equal(t, 11, doc.offset(Position{Line: 9, Character: 0}))
+	require.Equal(t, 1, doc.offset(Position{Line: 0, Character: 5}))
+}
@@ -1,32 +1,143 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+
+	"github.com/adamlouis/exp/loxgo/dap"
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/adamlouis/exp/loxgo/lsp"
 )
 
 func main() {
-	// took shortcuts to get java patterns into go
-	// take a pass at end to write idomatic go
-	l := &Lox{
-		interpreter: NewInterpreter(nil),
+	useVM := flag.Bool("vm", false, "run scripts on the bytecode VM instead of the tree-walking interpreter")
+	disassemble := flag.Bool("disassemble", false, "print compiled bytecode before running it (with -vm)")
+	var dump dumps
+	flag.BoolVar(&dump.tokens, "dump-tokens", false, "print the script's tokens instead of running it")
+	flag.BoolVar(&dump.ast, "dump-ast", false, "print the script's syntax tree instead of running it")
+	flag.BoolVar(&dump.resolved, "dump-resolved", false, "print the syntax tree with the scope depth of each local variable")
+	flag.StringVar((*string)(&dump.format), "dump-format", string(lox.DumpFormat_SEXPR), "format of the dumps: sexpr or json")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: loxgo [flags] [script]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo [flags] test <dir>...")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo fmt [-w] [-d] [path ...]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo lint [-json] [path ...]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo debug <script>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo dap")
+		fmt.Fprintln(flag.CommandLine.Output(), "       loxgo lsp")
+		fmt.Fprintln(flag.CommandLine.Output(), "A script named like a subcommand is run if the file exists.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := []lox.Option{lox.WithStderr(os.Stderr)}
+	if *useVM {
+		opts = append(opts, lox.WithVM())
+	}
+	if *disassemble {
+		opts = append(opts, lox.WithDisassemble())
+	}
+
+	if dump.tokens || dump.ast || dump.resolved {
+		if flag.NArg() > 1 {
+			flag.Usage()
+			os.Exit(64)
+		}
+		os.Exit(runDump(flag.Arg(0), dump))
 	}
-	l.interpreter.lox = l
 
-	switch len(os.Args) {
-	case 2:
-		if err := l.runFile(os.Args[1]); err != nil {
-			fmt.Println(err.Error())
+	switch subcommand(flag.Arg(0)) {
+	case "test":
+		os.Exit(runTests(flag.Args()[1:], opts))
+	case "fmt":
+		os.Exit(runFormat(flag.Args()[1:]))
+	case "lint":
+		os.Exit(runLint(flag.Args()[1:]))
+	case "debug":
+		if flag.NArg() != 2 || *useVM {
+			fmt.Fprintln(os.Stderr, "usage: loxgo debug <script> (the VM can't be debugged)")
+			os.Exit(64)
+		}
+		console := lox.NewDebugConsole(os.Stdin, os.Stdout)
+		os.Exit(runFile(lox.New(append(opts, lox.WithDebugger(console.Debugger()))...), flag.Arg(1)))
+	case "dap":
+		if err := dap.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "lsp":
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 		os.Exit(0)
+	}
+
+	l := lox.New(opts...)
+
+	switch flag.NArg() {
 	case 1:
-		if err := l.runPrompt(); err != nil {
-			fmt.Println(err.Error())
+		os.Exit(runFile(l, flag.Arg(0)))
+	case 0:
+		if err := runPrompt(l); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 		os.Exit(0)
 	default:
-		panic("usage: loxgo [script]")
+		flag.Usage()
+		os.Exit(64)
+	}
+}
+
+// subcommand returns arg if it names a subcommand rather than a script. A
+// script whose name is that of a subcommand, such as ./test, is still run,
+// but a directory of that name doesn't hide the subcommand.
+func subcommand(arg string) string {
+	if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+		return ""
+	}
+	return arg
+}
+
+// runFile runs the script at path and returns the exit code, using the codes
+// of the reference implementation: 65 for a syntax error, 70 for a runtime
+// error and 74 if the script can't be read. Errors in the script have
+// already been reported to stderr.
+func runFile(l *lox.Lox, path string) int {
+	err := l.RunFile(path)
+
+	var compileErr *lox.CompileError
+	var runtimeErr *lox.RuntimeError
+	var pathErr *fs.PathError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &compileErr):
+		return 65
+	case errors.As(err, &runtimeErr):
+		return 70
+	case errors.As(err, &pathErr):
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 74
+	default:
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+}
+
+// runPrompt runs the REPL on stdin, keeping its history in ~/.loxgo_history.
+func runPrompt(l *lox.Lox) error {
+	repl := lox.NewREPL(l, os.Stdin, os.Stdout)
+	if home, err := os.UserHomeDir(); err == nil {
+		if err := repl.LoadHistory(filepath.Join(home, ".loxgo_history")); err != nil {
+			return err
+		}
 	}
+	return repl.Run()
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunFileExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]int{
+		"print 1;":     0,
+		"print 1 +;":   65,
+		"print nil.x;": 70,
+	}
+	for source, code := range scripts {
+		path := filepath.Join(dir, "script.lox")
+		require.Nil(t, os.WriteFile(path, []byte(source), 0o644))
+		require.Equal(t, code, runFile(lox.New(lox.WithStdout(io.Discard)), path), source)
+	}
+
+	require.Equal(t, 74, runFile(lox.New(), filepath.Join(dir, "missing.lox")))
+}
+
+func TestSubcommand(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	require.Equal(t, "test", subcommand("test"))
+	require.Equal(t, "fmt", subcommand("fmt"))
+
+	// A directory named like a subcommand, such as a checkout's test/,
+	// doesn't hide it.
+	require.Nil(t, os.Mkdir("fmt", 0o755))
+	require.Equal(t, "fmt", subcommand("fmt"))
+
+	// A script named like a subcommand runs as it did before there were
+	// subcommands.
+	require.Nil(t, os.WriteFile("test", []byte("print 1;"), 0o644))
+	require.Equal(t, "", subcommand("test"))
+	require.Equal(t, "fmt", subcommand("fmt"))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+)
+
+// dumps selects what runDump prints.
+type dumps struct {
+	tokens   bool
+	ast      bool
+	resolved bool
+	format   lox.DumpFormat
+}
+
+// runDump prints the tokens and syntax trees of the script at path, or of
+// stdin if path is empty, instead of running it. It returns the exit code,
+// using the codes of runFile.
+func runDump(path string, d dumps) int {
+	if d.format != lox.DumpFormat_SEXPR && d.format != lox.DumpFormat_JSON {
+		fmt.Fprintf(os.Stderr, "unknown dump format '%s'\n", d.format)
+		return 64
+	}
+
+	var b []byte
+	var err error
+	if path == "" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 74
+	}
+	source := string(b)
+
+	if d.tokens {
+		err = lox.DumpTokens(os.Stdout, source, d.format)
+	}
+	if err == nil && d.ast {
+		err = lox.DumpAST(os.Stdout, source, d.format, false)
+	}
+	if err == nil && d.resolved {
+		err = lox.DumpAST(os.Stdout, source, d.format, true)
+	}
+
+	var compileErr *lox.CompileError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &compileErr):
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 65
+	default:
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// runFormat formats the .lox files named by args, or those under the
+// directories among them, as gofmt does Go files. Without files it formats
+// stdin. It returns the exit code: 2 if a file couldn't be formatted.
+func runFormat(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the result")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: loxgo fmt [-w] [-d] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "loxgo fmt: can't use -w on stdin")
+			return 2
+		}
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		return formatSource("<stdin>", string(b), false, *diff)
+	}
+
+	code := 0
+	for _, arg := range flags.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			// Files named explicitly are formatted whatever their extension.
+			if err != nil || d.IsDir() || (path != arg && filepath.Ext(path) != ".lox") {
+				return err
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if c := formatSource(path, string(b), *write, *diff); c != 0 {
+				code = c
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			code = 2
+		}
+	}
+	return code
+}
+
+// formatSource formats the source of the file at path and writes it back,
+// prints a diff, or prints the result.
+func formatSource(path string, source string, write bool, diff bool) int {
+	formatted, err := lox.Format(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:\n%s\n", path, err.Error())
+		return 2
+	}
+
+	switch {
+	case diff:
+		if formatted == source {
+			return 0
+		}
+		d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(source),
+			B:        difflib.SplitLines(formatted),
+			FromFile: path + ".orig",
+			ToFile:   path,
+			Context:  3,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		fmt.Printf("diff %s %s.orig\n%s", path, path, d)
+	case write:
+		if formatted == source {
+			return 0
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		if err := os.WriteFile(path, []byte(formatted), info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+	default:
+		fmt.Print(formatted)
+	}
+	return 0
+}
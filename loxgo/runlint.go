@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+)
+
+// lintWarning is a warning as -json prints it.
+type lintWarning struct {
+	File    string       `json:"file"`
+	Line    int          `json:"line"`
+	Column  int          `json:"column"`
+	Code    lox.LintCode `json:"code"`
+	Message string       `json:"message"`
+}
+
+// runLint lints the .lox files named by args, or those under the
+// directories among them, or stdin if there are none. It returns the exit
+// code: 1 if there were warnings and 2 if a file couldn't be linted.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the warnings as a JSON array")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: loxgo lint [-json] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	warnings := []lintWarning{}
+	code := 0
+	lint := func(path string, source []byte) {
+		ws, err := lox.Lint(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:\n%s\n", path, err.Error())
+			code = 2
+			return
+		}
+		for _, w := range ws {
+			warnings = append(warnings, lintWarning{File: path, Line: w.Span.Line, Column: w.Span.Column, Code: w.Code, Message: w.Message})
+		}
+	}
+
+	if flags.NArg() == 0 {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		lint("<stdin>", b)
+	}
+	for _, arg := range flags.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			// Files named explicitly are linted whatever their extension.
+			if err != nil || d.IsDir() || (path != arg && filepath.Ext(path) != ".lox") {
+				return err
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			lint(path, b)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			code = 2
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(warnings); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+	} else {
+		for _, w := range warnings {
+			fmt.Printf("%s:%d:%d: %s %s\n", w.File, w.Line, w.Column, w.Code, w.Message)
+		}
+	}
+
+	if code == 0 && len(warnings) > 0 {
+		code = 1
+	}
+	return code
+}
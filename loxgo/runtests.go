@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamlouis/exp/loxgo/lox"
+	"github.com/adamlouis/exp/loxgo/loxtest"
+)
+
+// runTests runs the .lox files under each of dirs against the expectations
+// in their comments, printing a line per file and a diff for each failure.
+// It returns the exit code.
+func runTests(dirs []string, opts []lox.Option) int {
+	if len(dirs) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: loxgo [flags] test <dir>...")
+		return 64
+	}
+
+	passed, failed, skipped := 0, 0, 0
+	for _, dir := range dirs {
+		results, err := loxtest.RunDir(dir, opts...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		for _, result := range results {
+			switch {
+			case result.Skipped:
+				skipped++
+				fmt.Printf("SKIP %s (no expectations)\n", result.Path)
+			case result.Passed():
+				passed++
+				fmt.Printf("ok   %s\n", result.Path)
+			default:
+				failed++
+				fmt.Printf("FAIL %s\n%s", result.Path, result.Diff)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}